@@ -10,10 +10,12 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/hashicorp/go-msgpack v1.1.5
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/lib/pq v1.10.9
 	github.com/outbrain/golib v0.0.0-20180830062331-ab954725f502
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/rcrowley/go-metrics v0.0.0-20190826022208-cac0b30c2563
 	golang.org/x/sys v0.1.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 	vitess.io/vitess v2.1.1+incompatible
 )
 
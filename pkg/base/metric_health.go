@@ -9,6 +9,7 @@ import (
 type MetricHealth struct {
 	LastHealthyAt           time.Time
 	SecondsSinceLastHealthy int64
+	SourceDomain            string `json:"-"` // when collected from a shared domain service, the domain it was collected from
 }
 
 func NewMetricHealth(lastHealthyAt time.Time) *MetricHealth {
@@ -7,13 +7,19 @@ package base
 
 import (
 	"errors"
-	"strings"
+	"net"
 )
 
 type MetricResult interface {
 	Get() (float64, error)
 }
 
+// HostMetricResult is a MetricResult known to originate in a specific host
+type HostMetricResult interface {
+	MetricResult
+	GetHost() string
+}
+
 type MetricResultFunc func() (metricResult MetricResult, threshold float64)
 
 var ThresholdExceededError = errors.New("Threshold exceeded")
@@ -21,11 +27,14 @@ var noHostsError = errors.New("No hosts found")
 var noResultYetError = errors.New("Metric not collected yet")
 var NoSuchMetricError = errors.New("No such metric")
 
+// IsDialTcpError returns true when given error (or any error it wraps) is a failure to dial a host. Errors of a
+// host which was reached, such as query timeouts, are not dial errors.
 func IsDialTcpError(e error) bool {
 	if e == nil {
 		return false
 	}
-	return strings.HasPrefix(e.Error(), "dial tcp")
+	var opError *net.OpError
+	return errors.As(e, &opError) && opError.Op == "dial"
 }
 
 type noHostsMetricResult struct{}
@@ -63,3 +72,35 @@ func NewSimpleMetricResult(value float64) MetricResult {
 func (metricResult *simpleMetricResult) Get() (float64, error) {
 	return metricResult.Value, nil
 }
+
+type hostMetricResult struct {
+	simpleMetricResult
+	Host string
+}
+
+// NewHostMetricResult returns a simple result, which also indicates the host the value was read from
+func NewHostMetricResult(value float64, host string) HostMetricResult {
+	return &hostMetricResult{simpleMetricResult: simpleMetricResult{Value: value}, Host: host}
+}
+
+func (metricResult *hostMetricResult) GetHost() string {
+	return metricResult.Host
+}
+
+type hostErrorMetricResult struct {
+	Err  error
+	Host string
+}
+
+// NewHostErrorMetricResult returns an erroneous result, which also indicates the host the error was read from
+func NewHostErrorMetricResult(err error, host string) HostMetricResult {
+	return &hostErrorMetricResult{Err: err, Host: host}
+}
+
+func (metricResult *hostErrorMetricResult) Get() (float64, error) {
+	return 0, metricResult.Err
+}
+
+func (metricResult *hostErrorMetricResult) GetHost() string {
+	return metricResult.Host
+}
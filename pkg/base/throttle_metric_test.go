@@ -0,0 +1,28 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestIsDialTcpError(t *testing.T) {
+	dialError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	test.S(t).ExpectFalse(IsDialTcpError(nil))
+	test.S(t).ExpectTrue(IsDialTcpError(dialError))
+	test.S(t).ExpectTrue(IsDialTcpError(fmt.Errorf("wrapped: %w", dialError)))
+	// only actual dial errors count, not errors which merely read like one
+	test.S(t).ExpectFalse(IsDialTcpError(errors.New("dial tcp 10.0.0.1:3306: connect: connection refused")))
+	test.S(t).ExpectFalse(IsDialTcpError(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}))
+	test.S(t).ExpectFalse(IsDialTcpError(context.DeadlineExceeded))
+	test.S(t).ExpectFalse(IsDialTcpError(errors.New("replication not running")))
+}
@@ -0,0 +1,75 @@
+package base
+
+import (
+	"sync"
+	"time"
+)
+
+// WatcherIdleTimeout is the time after which a watcher no longer read from stops, e.g. once its cluster is removed
+const WatcherIdleTimeout = 5 * time.Minute
+
+// Watcher keeps some state up to date in the background
+type Watcher interface {
+	// Watch runs until given isIdle returns true
+	Watch(isIdle func() bool)
+}
+
+type watcherEntry struct {
+	watcher Watcher
+	lastGet time.Time
+}
+
+// WatcherRegistry shares a background watcher between all readers of the same key. A watcher stops, and leaves
+// the registry, once no longer read from for WatcherIdleTimeout.
+type WatcherRegistry struct {
+	entries     map[string](*watcherEntry)
+	idleTimeout time.Duration
+	mutex       sync.Mutex
+}
+
+func NewWatcherRegistry() *WatcherRegistry {
+	return &WatcherRegistry{
+		entries:     make(map[string](*watcherEntry)),
+		idleTimeout: WatcherIdleTimeout,
+	}
+}
+
+// Get returns the watcher of given key. If there is none, one is created via newWatcher, typically with an initial
+// read, and then watches in the background. newWatcher is called outside the registry's lock, so that a slow initial
+// read does not hold up other keys; should concurrent readers both create a watcher, only one is kept.
+func (registry *WatcherRegistry) Get(key string, newWatcher func() Watcher) Watcher {
+	registry.mutex.Lock()
+	if entry, found := registry.entries[key]; found {
+		entry.lastGet = time.Now()
+		registry.mutex.Unlock()
+		return entry.watcher
+	}
+	registry.mutex.Unlock()
+
+	watcher := newWatcher()
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if entry, found := registry.entries[key]; found {
+		entry.lastGet = time.Now()
+		return entry.watcher
+	}
+	entry := &watcherEntry{watcher: watcher, lastGet: time.Now()}
+	registry.entries[key] = entry
+	go func() {
+		watcher.Watch(func() bool { return registry.isIdle(entry) })
+
+		registry.mutex.Lock()
+		defer registry.mutex.Unlock()
+		delete(registry.entries, key)
+	}()
+	return watcher
+}
+
+func (registry *WatcherRegistry) isIdle(entry *watcherEntry) bool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	return time.Since(entry.lastGet) > registry.idleTimeout
+}
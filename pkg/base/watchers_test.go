@@ -0,0 +1,68 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+type testWatcher struct {
+	stopped chan bool
+}
+
+func (w *testWatcher) Watch(isIdle func() bool) {
+	for !isIdle() {
+		time.Sleep(time.Millisecond)
+	}
+	close(w.stopped)
+}
+
+func TestWatcherRegistry(t *testing.T) {
+	registry := NewWatcherRegistry()
+	registry.idleTimeout = 50 * time.Millisecond
+
+	var created int64
+	newWatcher := func() Watcher {
+		atomic.AddInt64(&created, 1)
+		return &testWatcher{stopped: make(chan bool)}
+	}
+	w := registry.Get("a", newWatcher)
+	test.S(t).ExpectTrue(registry.Get("a", newWatcher) == w)
+	test.S(t).ExpectTrue(registry.Get("b", newWatcher) != w)
+	test.S(t).ExpectEquals(atomic.LoadInt64(&created), int64(2))
+
+	// an idle watcher stops, and leaves the registry
+	select {
+	case <-w.(*testWatcher).stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected idle watcher to stop")
+	}
+	for registry.Get("a", newWatcher) == w {
+		time.Sleep(time.Millisecond)
+	}
+	test.S(t).ExpectTrue(atomic.LoadInt64(&created) >= 3)
+}
+
+func TestWatcherRegistryConcurrentCreate(t *testing.T) {
+	registry := NewWatcherRegistry()
+
+	// a watcher created while another reader was creating one is dropped in favor of the registered one
+	release := make(chan bool)
+	created := make(chan Watcher, 1)
+	go func() {
+		created <- registry.Get("a", func() Watcher {
+			<-release
+			return &testWatcher{stopped: make(chan bool)}
+		})
+	}()
+	registered := registry.Get("a", func() Watcher { return &testWatcher{stopped: make(chan bool)} })
+	close(release)
+	test.S(t).ExpectTrue(<-created == registered)
+}
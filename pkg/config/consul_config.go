@@ -0,0 +1,49 @@
+package config
+
+//
+// Consul catalog hosts configuration
+//
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const DefaultConsulTimeoutMillis = 1000
+const DefaultConsulWaitSeconds = 60
+
+type ConsulConfigurationSettings struct {
+	Address         string   // Consul HTTP API, e.g. "http://127.0.0.1:8500". Leave empty to inherit MySQLConfigurationSettings's ConsulAddress
+	Token           string   // ACL token. Leave empty to inherit MySQLConfigurationSettings's ConsulToken
+	Service         string   // name of the service the servers are registered as
+	Tags            []string // if non empty, only servers having all these tags are listed, e.g. the cluster name
+	Datacenter      string   // optional, defaults to the datacenter of the agent
+	TimeoutMillis   int      // request timeout. Default: 1000
+	BlockingQueries bool     // if true, watch the service with blocking queries, and refresh as soon as its healthy servers change
+	WaitSeconds     int      // maximum duration of a blocking query. Default: 60
+}
+
+func (settings *ConsulConfigurationSettings) IsEmpty() bool {
+	return settings.Service == ""
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *ConsulConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if settings.Address == "" {
+		return fmt.Errorf("Consul service %s: Address must be provided", settings.Service)
+	}
+	if parsed, err := url.Parse(settings.Address); err != nil || parsed.Host == "" {
+		return fmt.Errorf("Consul service %s: invalid Address: %s", settings.Service, settings.Address)
+	}
+	settings.Token = resolveCredential(settings.Token)
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultConsulTimeoutMillis
+	}
+	if settings.WaitSeconds <= 0 {
+		settings.WaitSeconds = DefaultConsulWaitSeconds
+	}
+	return nil
+}
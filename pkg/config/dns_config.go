@@ -0,0 +1,66 @@
+package config
+
+//
+// DNS hosts configuration
+//
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const DefaultDNSTimeoutMillis = 1000
+
+const (
+	DNSRecordTypeSRV = "SRV" // hosts and ports are the targets and ports of SRV records
+	DNSRecordTypeA   = "A"   // hosts are the addresses of A records
+)
+
+const (
+	DNSOnFailureKeep = "keep" // on NXDOMAIN or SERVFAIL, keep the previously discovered hosts
+	DNSOnFailureFail = "fail" // on NXDOMAIN or SERVFAIL, the cluster has no hosts, and checks fail
+)
+
+type DNSConfigurationSettings struct {
+	Name          string // e.g. "_mysql._tcp.shard1.example.com" for SRV records, or "shard1.example.com" for A records
+	RecordType    string // "SRV" (default) or "A"
+	Resolver      string // optional, "host" or "host:port" of the DNS server to query. Default: nameservers of /etc/resolv.conf
+	TimeoutMillis int    // query timeout. Default: 1000
+	OnFailure     string // "keep" (default) or "fail": what to do when the name does not resolve, or the server fails
+}
+
+func (settings *DNSConfigurationSettings) IsEmpty() bool {
+	return settings.Name == ""
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *DNSConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	settings.RecordType = strings.ToUpper(settings.RecordType)
+	switch settings.RecordType {
+	case "":
+		settings.RecordType = DNSRecordTypeSRV
+	case DNSRecordTypeSRV, DNSRecordTypeA:
+	default:
+		return fmt.Errorf("DNS name %s: unsupported RecordType: %s", settings.Name, settings.RecordType)
+	}
+	switch settings.OnFailure {
+	case "":
+		settings.OnFailure = DNSOnFailureKeep
+	case DNSOnFailureKeep, DNSOnFailureFail:
+	default:
+		return fmt.Errorf("DNS name %s: unsupported OnFailure: %s", settings.Name, settings.OnFailure)
+	}
+	if settings.Resolver != "" {
+		if _, _, err := net.SplitHostPort(settings.Resolver); err != nil {
+			settings.Resolver = net.JoinHostPort(settings.Resolver, "53")
+		}
+	}
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultDNSTimeoutMillis
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+//
+// File hosts configuration: hosts listed in a file, maintained e.g. by configuration management
+//
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FileHostsFormatLines = "lines" // one host per line; empty lines and lines starting with "#" are ignored
+	FileHostsFormatJSON  = "json"  // a list of hosts, or an object with a "Hosts" list
+	FileHostsFormatYAML  = "yaml"  // a list of hosts, or a mapping with a "Hosts" list
+)
+
+type FileHostsConfigurationSettings struct {
+	Path   string // file listing hosts; a host can be "hostname" or "hostname:port"
+	Format string // "lines", "json" or "yaml". Default: by the file's extension; "lines" unless ".json", ".yaml" or ".yml"
+}
+
+func (settings *FileHostsConfigurationSettings) IsEmpty() bool {
+	return settings.Path == ""
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *FileHostsConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if settings.Format == "" {
+		switch strings.ToLower(filepath.Ext(settings.Path)) {
+		case ".json":
+			settings.Format = FileHostsFormatJSON
+		case ".yaml", ".yml":
+			settings.Format = FileHostsFormatYAML
+		default:
+			settings.Format = FileHostsFormatLines
+		}
+	}
+	switch settings.Format {
+	case FileHostsFormatLines, FileHostsFormatJSON, FileHostsFormatYAML:
+	default:
+		return fmt.Errorf("FileHostsSettings %s: unsupported Format: %s", settings.Path, settings.Format)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package config
+
+//
+// Group Replication (InnoDB Cluster) hosts configuration
+//
+
+type GroupReplicationConfigurationSettings struct {
+	SeedHosts       []string // group members to read group membership from; a host can be "hostname" or "hostname:port"
+	OnlySecondaries bool     // if true, only probe SECONDARY members. Default: all ONLINE members
+}
+
+func (settings *GroupReplicationConfigurationSettings) IsEmpty() bool {
+	return len(settings.SeedHosts) == 0
+}
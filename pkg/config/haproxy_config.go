@@ -48,14 +48,39 @@ func ParseHostPort(address string) (hostPort *HostPort, err error) {
 	return hostPort, nil
 }
 
+const (
+	HAProxyStatsFormatCSV   = "csv"   // "show stat"
+	HAProxyStatsFormatTyped = "typed" // "show stat typed"
+	HAProxyStatsFormatJSON  = "json"  // "show stat json"
+)
+
 type HAProxyConfigurationSettings struct {
-	Host      string
-	Port      int
-	Addresses string
-	PoolName  string
+	Host        string
+	Port        int
+	Addresses   string // comma separated stats page URLs, or runtime API addresses: "unix:///path/to/socket" or "tcp://host:port"
+	PoolName    string
+	PoolNames   []string // several pools (backends) whose hosts are combined; alternative or addition to PoolName
+	User        string   // stats page HTTP basic auth user. Can be given as "${ENV_VARIABLE}" or "${file:/path/to/secret}"
+	Password    string   // stats page HTTP basic auth password. Can be given as "${ENV_VARIABLE}" or "${file:/path/to/secret}"
+	StatsFormat string   // runtime API "show stat" output format: "csv" (default), "typed" or "json"
+}
+
+// IsRuntimeAPIAddress returns true for addresses of the HAProxy runtime API (a.k.a. stats socket), as opposed
+// to stats page URLs
+func IsRuntimeAPIAddress(u *url.URL) bool {
+	return u.Scheme == "unix" || u.Scheme == "tcp"
 }
 
 func parseAddress(address string) (u *url.URL, err error) {
+	if strings.HasPrefix(address, "unix://") {
+		if u, err = url.Parse(address); err != nil {
+			return u, err
+		}
+		if u.Host != "" || u.Path == "" {
+			return u, fmt.Errorf("Invalid unix socket address: %s. Expected format is unix:///path/to/socket", address)
+		}
+		return u, nil
+	}
 	if hostPort, err := ParseHostPort(address); err == nil {
 		// covers the case for e.g. "my.host.name:1234", which has no scheme
 		return hostPort.URL(), nil
@@ -68,6 +93,9 @@ func parseAddress(address string) (u *url.URL, err error) {
 	if _, err := ParseHostPort(u.Host); err != nil {
 		return u, err
 	}
+	if IsRuntimeAPIAddress(u) && u.Port() == "" {
+		return u, fmt.Errorf("Invalid runtime API address: %s. Expected format is tcp://host:port", address)
+	}
 	return u, nil
 }
 
@@ -93,15 +121,48 @@ func (settings *HAProxyConfigurationSettings) GetProxyAddresses() (addresses [](
 	return settings.parseAddresses()
 }
 
+// GetPoolNames returns PoolName along with PoolNames
+func (settings *HAProxyConfigurationSettings) GetPoolNames() (poolNames []string) {
+	if settings.PoolName != "" {
+		poolNames = append(poolNames, settings.PoolName)
+	}
+	for _, poolName := range settings.PoolNames {
+		if poolName != "" && poolName != settings.PoolName {
+			poolNames = append(poolNames, poolName)
+		}
+	}
+	return poolNames
+}
+
 func (settings *HAProxyConfigurationSettings) IsEmpty() bool {
-	if settings.PoolName == "" {
+	if len(settings.GetPoolNames()) == 0 {
 		return true
 	}
 	addresses, _ := settings.GetProxyAddresses()
 	return len(addresses) == 0
 }
 
+// Credentials returns the stats page's current user & password, resolving secret file references
+func (settings *HAProxyConfigurationSettings) Credentials() (user string, password string, err error) {
+	if user, err = ResolveSecret(settings.User); err != nil {
+		return user, password, err
+	}
+	if password, err = ResolveSecret(settings.Password); err != nil {
+		return user, password, err
+	}
+	return user, password, nil
+}
+
 func (settings *HAProxyConfigurationSettings) postReadAdjustments() error {
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+	switch settings.StatsFormat {
+	case "":
+		settings.StatsFormat = HAProxyStatsFormatCSV
+	case HAProxyStatsFormatCSV, HAProxyStatsFormatTyped, HAProxyStatsFormatJSON:
+	default:
+		return fmt.Errorf("HAProxySettings: unsupported StatsFormat: %s", settings.StatsFormat)
+	}
 	for {
 		submatch := envVariableRegexp.FindStringSubmatch(settings.Addresses)
 		if len(submatch) == 0 {
@@ -6,6 +6,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/outbrain/golib/log"
@@ -88,6 +89,43 @@ func TestHAProxyParseAddresses(t *testing.T) {
 		_, err := c.parseAddresses()
 		test.S(t).ExpectNotNil(err)
 	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "unix:///var/run/haproxy.sock,tcp://localhost:9999"}
+		addresses, err := c.parseAddresses()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(addresses), 2)
+		test.S(t).ExpectEquals(addresses[0].Path, "/var/run/haproxy.sock")
+		test.S(t).ExpectTrue(IsRuntimeAPIAddress(addresses[0]))
+		test.S(t).ExpectEquals(addresses[1].Host, "localhost:9999")
+		test.S(t).ExpectTrue(IsRuntimeAPIAddress(addresses[1]))
+	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "unix://haproxy.sock"}
+		_, err := c.parseAddresses()
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "tcp://localhost"}
+		_, err := c.parseAddresses()
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestHAProxyStatsFormat(t *testing.T) {
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "unix:///var/run/haproxy.sock", PoolName: "p"}
+		test.S(t).ExpectNil(c.postReadAdjustments())
+		test.S(t).ExpectEquals(c.StatsFormat, HAProxyStatsFormatCSV)
+	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "unix:///var/run/haproxy.sock", PoolName: "p", StatsFormat: HAProxyStatsFormatTyped}
+		test.S(t).ExpectNil(c.postReadAdjustments())
+		test.S(t).ExpectEquals(c.StatsFormat, HAProxyStatsFormatTyped)
+	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "unix:///var/run/haproxy.sock", PoolName: "p", StatsFormat: "xml"}
+		test.S(t).ExpectNotNil(c.postReadAdjustments())
+	}
 }
 
 func TestHAProxyGetProxyAddresses(t *testing.T) {
@@ -153,4 +191,20 @@ func TestHAProxyIsEmpty(t *testing.T) {
 		isEmpty := c.IsEmpty()
 		test.S(t).ExpectFalse(isEmpty)
 	}
+	{
+		c := &HAProxyConfigurationSettings{Addresses: "localhost:1234", PoolNames: []string{"p_ro_a", "p_ro_b"}}
+		isEmpty := c.IsEmpty()
+		test.S(t).ExpectFalse(isEmpty)
+	}
+}
+
+func TestHAProxyGetPoolNames(t *testing.T) {
+	{
+		c := &HAProxyConfigurationSettings{}
+		test.S(t).ExpectEquals(len(c.GetPoolNames()), 0)
+	}
+	{
+		c := &HAProxyConfigurationSettings{PoolName: "p_ro_a", PoolNames: []string{"p_ro_b", "p_ro_a", ""}}
+		test.S(t).ExpectEquals(strings.Join(c.GetPoolNames(), ","), "p_ro_a,p_ro_b")
+	}
 }
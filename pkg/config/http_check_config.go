@@ -0,0 +1,90 @@
+package config
+
+//
+// HTTP check configuration: how probed hosts' HTTP check endpoints are queried, and how outcomes apply
+//
+
+import (
+	"fmt"
+)
+
+const (
+	HttpCheckActionInclude = "include" // include the host in aggregation
+	HttpCheckActionExclude = "exclude" // exclude the host from aggregation
+	HttpCheckActionError   = "error"   // treat the host as erroneous
+)
+
+const DefaultHttpCheckTimeoutMillis = 1000
+
+type HttpCheckConfigurationSettings struct {
+	Scheme           string                 // "http" (default) or "https"
+	SkipVerify       bool                   // with "https", do not verify the host's certificate
+	Headers          map[string]string      // request headers, e.g. authorization
+	TimeoutMillis    int                    // request timeout. Default: 1000
+	ExpectedStatuses []int                  // statuses of a passing check. Default: any status but 404
+	BodyMatch        map[string]interface{} // if non empty, a passing check's JSON body must have these values. Keys may be dotted paths, e.g. "replication.running"
+	OnFail           string                 // action for a failing check (unexpected status or body mismatch): "exclude" (default), "include" or "error"
+	OnError          string                 // action for a check that could not complete (e.g. timeout, connection refused): "include" (default), "exclude" or "error"
+}
+
+func (settings *HttpCheckConfigurationSettings) IsEmpty() bool {
+	if settings.Scheme != "" || settings.SkipVerify || settings.TimeoutMillis != 0 {
+		return false
+	}
+	if len(settings.Headers) > 0 || len(settings.ExpectedStatuses) > 0 || len(settings.BodyMatch) > 0 {
+		return false
+	}
+	return settings.OnFail == "" && settings.OnError == ""
+}
+
+// inherit applies given (global) settings onto these settings, unless these settings are explicitly set.
+// HTTP check settings are inherited as a whole, never field by field.
+func (settings *HttpCheckConfigurationSettings) inherit(other *HttpCheckConfigurationSettings) {
+	if settings.IsEmpty() {
+		*settings = *other
+	}
+}
+
+// IsExpectedStatus returns true when given status is that of a passing check
+func (settings *HttpCheckConfigurationSettings) IsExpectedStatus(status int) bool {
+	if len(settings.ExpectedStatuses) == 0 {
+		return status != 404
+	}
+	for _, expectedStatus := range settings.ExpectedStatuses {
+		if status == expectedStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func validateHttpCheckAction(name string, action string) error {
+	switch action {
+	case HttpCheckActionInclude, HttpCheckActionExclude, HttpCheckActionError:
+		return nil
+	}
+	return fmt.Errorf("HttpCheckSettings: unsupported %s: %s", name, action)
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *HttpCheckConfigurationSettings) postReadAdjustments() error {
+	if settings.Scheme == "" {
+		settings.Scheme = "http"
+	}
+	if settings.Scheme != "http" && settings.Scheme != "https" {
+		return fmt.Errorf("HttpCheckSettings: unsupported Scheme: %s", settings.Scheme)
+	}
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultHttpCheckTimeoutMillis
+	}
+	if settings.OnFail == "" {
+		settings.OnFail = HttpCheckActionExclude
+	}
+	if settings.OnError == "" {
+		settings.OnError = HttpCheckActionInclude
+	}
+	if err := validateHttpCheckAction("OnFail", settings.OnFail); err != nil {
+		return err
+	}
+	return validateHttpCheckAction("OnError", settings.OnError)
+}
@@ -0,0 +1,93 @@
+package config
+
+//
+// HTTP store configuration: metrics read off HTTP endpoints
+//
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+const DefaultHTTPStoreTimeoutMillis = 1000
+
+type HTTPStoreClusterConfigurationSettings struct {
+	URLs                 []string          // endpoints to read, one per host
+	JSONPath             string            // dotted path of the value in a JSON response, e.g. "consumers.0.lag". Mutually exclusive with Regexp
+	Regexp               string            // regular expression matching the value in the response. The first submatch, if any, is the value. Mutually exclusive with JSONPath
+	Headers              map[string]string // override HTTPStoreConfigurationSettings's, or leave empty to inherit those settings
+	TimeoutMillis        int               // override HTTPStoreConfigurationSettings's, or leave empty to inherit those settings
+	CacheMillis          int               // override HTTPStoreConfigurationSettings's, or leave empty to inherit those settings
+	ThrottleThreshold    float64           // override HTTPStoreConfigurationSettings's, or leave empty to inherit those settings
+	IgnoreHostsCount     int               // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64           // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string          // override HTTPStoreConfigurationSettings's, or leave empty to inherit those settings
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *HTTPStoreClusterConfigurationSettings) postReadAdjustments() error {
+	if len(settings.URLs) == 0 {
+		return fmt.Errorf("URLs must be provided")
+	}
+	for _, u := range settings.URLs {
+		if parsed, err := url.Parse(u); err != nil || parsed.Host == "" {
+			return fmt.Errorf("Invalid URL: %s", u)
+		}
+	}
+	if (settings.JSONPath == "") == (settings.Regexp == "") {
+		return fmt.Errorf("Exactly one of JSONPath, Regexp must be provided")
+	}
+	if settings.Regexp != "" {
+		if _, err := regexp.Compile(settings.Regexp); err != nil {
+			return fmt.Errorf("Invalid Regexp: %+v", err)
+		}
+	}
+	return nil
+}
+
+type HTTPStoreConfigurationSettings struct {
+	Headers              map[string]string // request headers, e.g. authorization
+	TimeoutMillis        int               // request timeout. Default: 1000
+	CacheMillis          int               // optional, if defined then probe result will be cached, and future probes may use cached value
+	ThrottleThreshold    float64
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // If non empty, substrings to indicate URLs to be ignored/skipped
+
+	Clusters map[string](*HTTPStoreClusterConfigurationSettings) // cluster name -> cluster config
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *HTTPStoreConfigurationSettings) postReadAdjustments() error {
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultHTTPStoreTimeoutMillis
+	}
+	for clusterName, clusterSettings := range settings.Clusters {
+		if err := clusterSettings.postReadAdjustments(); err != nil {
+			return fmt.Errorf("HTTP cluster %s: %+v", clusterName, err)
+		}
+		if len(clusterSettings.Headers) == 0 {
+			clusterSettings.Headers = settings.Headers
+		}
+		if clusterSettings.TimeoutMillis <= 0 {
+			clusterSettings.TimeoutMillis = settings.TimeoutMillis
+		}
+		if clusterSettings.CacheMillis == 0 {
+			clusterSettings.CacheMillis = settings.CacheMillis
+		}
+		if clusterSettings.ThrottleThreshold == 0 {
+			clusterSettings.ThrottleThreshold = settings.ThrottleThreshold
+		}
+		if clusterSettings.IgnoreHostsCount == 0 {
+			clusterSettings.IgnoreHostsCount = settings.IgnoreHostsCount
+		}
+		if clusterSettings.IgnoreHostsThreshold == 0 {
+			clusterSettings.IgnoreHostsThreshold = settings.IgnoreHostsThreshold
+		}
+		if len(clusterSettings.IgnoreHosts) == 0 {
+			clusterSettings.IgnoreHosts = settings.IgnoreHosts
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestHTTPStoreConfigurationInheritance(t *testing.T) {
+	{
+		settings := &HTTPStoreConfigurationSettings{
+			Headers:           map[string]string{"Authorization": "Bearer token"},
+			ThrottleThreshold: 1000,
+			IgnoreHosts:       []string{"queue-9"},
+			Clusters: map[string](*HTTPStoreClusterConfigurationSettings){
+				"inherits": {URLs: []string{"http://queue-1:8080/stats"}, JSONPath: "queue.depth"},
+				"custom":   {URLs: []string{"https://search:9200/lag"}, Regexp: `lag ([0-9.]+)`, ThrottleThreshold: 5, TimeoutMillis: 300, IgnoreHosts: []string{"search-canary"}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ThrottleThreshold, 1000.0)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].TimeoutMillis, DefaultHTTPStoreTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Headers["Authorization"], "Bearer token")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].ThrottleThreshold, 5.0)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].TimeoutMillis, 300)
+		test.S(t).ExpectEquals(len(settings.Clusters["inherits"].IgnoreHosts), 1)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].IgnoreHosts[0], "queue-9")
+		test.S(t).ExpectEquals(len(settings.Clusters["custom"].IgnoreHosts), 1)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].IgnoreHosts[0], "search-canary")
+	}
+	{
+		settings := &HTTPStoreConfigurationSettings{
+			Clusters: map[string](*HTTPStoreClusterConfigurationSettings){
+				"c0": {URLs: []string{"http://queue-1:8080/stats"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &HTTPStoreConfigurationSettings{
+			Clusters: map[string](*HTTPStoreClusterConfigurationSettings){
+				"c0": {URLs: []string{"http://queue-1:8080/stats"}, JSONPath: "depth", Regexp: "depth ([0-9]+)"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &HTTPStoreConfigurationSettings{
+			Clusters: map[string](*HTTPStoreClusterConfigurationSettings){
+				"c0": {URLs: []string{"http://queue-1:8080/stats"}, Regexp: "depth ("},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &HTTPStoreConfigurationSettings{
+			Clusters: map[string](*HTTPStoreClusterConfigurationSettings){
+				"c0": {URLs: []string{"queue-1"}, JSONPath: "depth"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
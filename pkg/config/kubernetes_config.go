@@ -0,0 +1,61 @@
+package config
+
+//
+// Kubernetes hosts configuration
+//
+
+import (
+	"fmt"
+)
+
+const DefaultKubernetesTimeoutMillis = 1000
+
+const (
+	KubernetesResourceEndpointSlices = "endpointslices" // ready addresses of the EndpointSlices of Service
+	KubernetesResourceEndpoints      = "endpoints"      // ready addresses of the Endpoints of Service
+	KubernetesResourcePods           = "pods"           // IPs of ready pods matching LabelSelector
+)
+
+type KubernetesConfigurationSettings struct {
+	Namespace     string // namespace of Service or pods. Default: the namespace of the kubeconfig context, or freno's own namespace when running in-cluster
+	Service       string // name of the service whose endpoints are listed
+	LabelSelector string // label selector of pods, e.g. "app=mysql,role=replica"; applies when Resource is "pods"
+	Resource      string // "endpointslices" (default), "endpoints" or "pods" (default when LabelSelector is given)
+	Port          int    // port to probe on listed addresses. Default: the cluster's Port
+	Kubeconfig    string // path to a kubeconfig file. Default: in-cluster credentials
+	Context       string // kubeconfig context. Default: the kubeconfig's current context
+	TimeoutMillis int    // request timeout. Default: 1000
+}
+
+func (settings *KubernetesConfigurationSettings) IsEmpty() bool {
+	return settings.Service == "" && settings.LabelSelector == ""
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *KubernetesConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if settings.Resource == "" {
+		settings.Resource = KubernetesResourceEndpointSlices
+		if settings.Service == "" {
+			settings.Resource = KubernetesResourcePods
+		}
+	}
+	switch settings.Resource {
+	case KubernetesResourceEndpointSlices, KubernetesResourceEndpoints:
+		if settings.Service == "" {
+			return fmt.Errorf("Kubernetes: Service must be provided for %s", settings.Resource)
+		}
+	case KubernetesResourcePods:
+		if settings.LabelSelector == "" {
+			return fmt.Errorf("Kubernetes: LabelSelector must be provided for pods")
+		}
+	default:
+		return fmt.Errorf("Kubernetes: unsupported Resource: %s", settings.Resource)
+	}
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultKubernetesTimeoutMillis
+	}
+	return nil
+}
@@ -5,15 +5,40 @@ package config
 //
 
 import (
+	"fmt"
 	"os"
 )
 
 const DefaultMySQLPort = 3306
 
+const (
+	MySQLMetricAggregationNone = ""
+	MySQLMetricAggregationMax  = "max"
+	MySQLMetricAggregationSum  = "sum"
+	MySQLMetricAggregationAvg  = "avg"
+)
+
+const (
+	MySQLMetricTypeReplicationLag         = ""
+	MySQLMetricTypeHeartbeat              = "heartbeat"
+	MySQLMetricTypeReplicationApplierLag  = "replication_applier_lag"
+	MySQLMetricTypeGroupReplicationQueue  = "group_replication_queue"
+	MySQLMetricTypeProxySQLReplicationLag = "proxysql_replication_lag" // as monitored by ProxySQL; requires ProxySQLSettings
+)
+
+const (
+	DiscoveryModeFirst        = "first"        // hosts of the first configured source, by precedence
+	DiscoveryModeUnion        = "union"        // hosts listed by any of the configured sources
+	DiscoveryModeIntersection = "intersection" // hosts listed by all of the configured sources
+)
+
 type MySQLClusterConfigurationSettings struct {
 	User                 string   // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	Password             string   // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	MetricQuery          string   // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	MetricType           string   // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	MetricAggregation    string   // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	MetricRate           bool     // inherited from MySQLConfigurationSettings's along with MetricQuery
 	CacheMillis          int      // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	ThrottleThreshold    float64  // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	Port                 int      // Specify if different than 3306 or if different than specified by MySQLConfigurationSettings
@@ -22,11 +47,24 @@ type MySQLClusterConfigurationSettings struct {
 	HttpCheckPort        int      // Specify if different than specified by MySQLConfigurationSettings. -1 to disable HTTP check
 	HttpCheckPath        string   // Specify if different than specified by MySQLConfigurationSettings
 	IgnoreHosts          []string // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	ReplicationChannels  []string // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	DiscoveryMode        string   // how the hosts of several configured sources combine: "first" (default), "union" or "intersection"
+
+	HAProxySettings          HAProxyConfigurationSettings      // If list of servers is to be acquired via HAProxy, provide this field
+	ProxySQLSettings         ProxySQLConfigurationSettings     // If list of servers is to be acquired via ProxySQL, provide this field
+	VitessSettings           VitessConfigurationSettings       // If list of servers is to be acquired via Vitess, provide this field
+	ConsulSettings           ConsulConfigurationSettings       // If list of servers is to be acquired via Consul catalog, provide this field
+	DNSSettings              DNSConfigurationSettings          // If list of servers is to be acquired via DNS SRV or A records, provide this field
+	OrchestratorSettings     OrchestratorConfigurationSettings // If list of servers is to be acquired via orchestrator, provide this field
+	KubernetesSettings       KubernetesConfigurationSettings   // If list of servers is to be acquired via Kubernetes endpoints or pods, provide this field
+	StaticHostsSettings      StaticHostsConfigurationSettings
+	FileHostsSettings        FileHostsConfigurationSettings        // If list of servers is to be read off a file, provide this field. The file is watched for changes
+	GroupReplicationSettings GroupReplicationConfigurationSettings // If list of servers is to be acquired via Group Replication membership, provide this field
+	TopologySettings         TopologyConfigurationSettings         // If list of servers is to be acquired by crawling replicas from the primary, provide this field
 
-	HAProxySettings     HAProxyConfigurationSettings  // If list of servers is to be acquired via HAProxy, provide this field
-	ProxySQLSettings    ProxySQLConfigurationSettings // If list of servers is to be acquired via ProxySQL, provide this field
-	VitessSettings      VitessConfigurationSettings   // If list of servers is to be acquired via Vitess, provide this field
-	StaticHostsSettings StaticHostsConfigurationSettings
+	HeartbeatSettings MySQLHeartbeatConfigurationSettings // applies when MetricType is "heartbeat"
+	TLSSettings       TLSConfigurationSettings            // override MySQLConfigurationSettings's, or leave empty to inherit those settings
+	HttpCheckSettings HttpCheckConfigurationSettings      // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 }
 
 // Hook to implement adjustments after reading each configuration file.
@@ -34,36 +72,78 @@ func (settings *MySQLClusterConfigurationSettings) postReadAdjustments() error {
 	// Username & password may be given as plaintext in the config file, or can be delivered
 	// via environment variables. We accept user & password in the form "${SOME_ENV_VARIABLE}"
 	// in which case we get the value from this process' invoking environment.
-	if submatch := envVariableRegexp.FindStringSubmatch(settings.User); len(submatch) > 1 {
-		settings.User = os.Getenv(submatch[1])
+	// We also accept user & password in the form "${file:/path/to/secret}", in which case the
+	// value is read from the file upon use, and re-read when the file changes.
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+	if err := settings.HAProxySettings.postReadAdjustments(); err != nil {
+		return err
 	}
-	if submatch := envVariableRegexp.FindStringSubmatch(settings.Password); len(submatch) > 1 {
-		settings.Password = os.Getenv(submatch[1])
+	if err := settings.DNSSettings.postReadAdjustments(); err != nil {
+		return err
 	}
-	if err := settings.HAProxySettings.postReadAdjustments(); err != nil {
+	if err := settings.KubernetesSettings.postReadAdjustments(); err != nil {
+		return err
+	}
+	if err := settings.FileHostsSettings.postReadAdjustments(); err != nil {
 		return err
 	}
+	if err := settings.TopologySettings.postReadAdjustments(); err != nil {
+		return err
+	}
+	switch settings.DiscoveryMode {
+	case "":
+		settings.DiscoveryMode = DiscoveryModeFirst
+	case DiscoveryModeFirst, DiscoveryModeUnion, DiscoveryModeIntersection:
+	default:
+		return fmt.Errorf("Unsupported DiscoveryMode: %s", settings.DiscoveryMode)
+	}
 	return nil
 }
 
+// Credentials returns the cluster's current user & password, resolving secret file references
+func (settings *MySQLClusterConfigurationSettings) Credentials() (user string, password string, err error) {
+	if user, err = ResolveSecret(settings.User); err != nil {
+		return user, password, err
+	}
+	if password, err = ResolveSecret(settings.Password); err != nil {
+		return user, password, err
+	}
+	return user, password, nil
+}
+
 type MySQLConfigurationSettings struct {
 	User                 string
 	Password             string
 	MetricQuery          string
-	CacheMillis          int // optional, if defined then probe result will be cached, and future probes may use cached value
+	MetricType           string // optional, "heartbeat", "replication_applier_lag", "group_replication_queue" or "proxysql_replication_lag" for built in metrics. Mutually exclusive with MetricQuery
+	MetricAggregation    string // optional, "max", "sum" or "avg": aggregate the values of a multi-row MetricQuery. Default: use first row
+	MetricRate           bool   // optional, if true then MetricQuery reads a monotonically increasing counter, and the metric is its per-second rate
+	CacheMillis          int    // optional, if defined then probe result will be cached, and future probes may use cached value
 	ThrottleThreshold    float64
-	Port                 int      // Specify if different than 3306; applies to all clusters
-	IgnoreDialTcpErrors  bool     // Skip hosts where a metric cannot be retrieved due to TCP dial errors
-	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
-	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
-	HttpCheckPort        int      // port for HTTP check. -1 to disable.
-	HttpCheckPath        string   // If non-empty, requires HttpCheckPort
-	IgnoreHosts          []string // If non empty, substrings to indicate hosts to be ignored/skipped
-	ProxySQLAddresses    []string // A list of ProxySQL instances to query for hosts
-	ProxySQLUser         string   // ProxySQL stats username
-	ProxySQLPassword     string   // ProxySQL stats password
-	VitessCells          []string // Name of the Vitess cells for polling tablet hosts
-	Collation            string   // MySQL collation to use for stores, replaces charset if specified
+	Port                 int                      // Specify if different than 3306; applies to all clusters
+	IgnoreDialTcpErrors  bool                     // Skip hosts where a metric cannot be retrieved due to TCP dial errors
+	IgnoreHostsCount     int                      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64                  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	HttpCheckPort        int                      // port for HTTP check. -1 to disable.
+	HttpCheckPath        string                   // If non-empty, requires HttpCheckPort
+	IgnoreHosts          []string                 // If non empty, substrings to indicate hosts to be ignored/skipped
+	ReplicationChannels  []string                 // If non empty, only consider these replication channels when reading replication lag. Empty means all channels
+	ProxySQLAddresses    []string                 // A list of ProxySQL instances to query for hosts
+	ProxySQLUser         string                   // ProxySQL stats username
+	ProxySQLPassword     string                   // ProxySQL stats password
+	ProxySQLTLSSettings  TLSConfigurationSettings // TLS settings for connecting to ProxySQL
+	VitessCells          []string                 // Name of the Vitess cells for polling tablet hosts
+	ConsulAddress        string                   // Consul HTTP API to query for hosts, e.g. "http://127.0.0.1:8500"
+	ConsulToken          string                   // Consul ACL token
+	OrchestratorURL      string                   // orchestrator API to query for hosts, e.g. "http://orchestrator.example.com:3000"
+	OrchestratorUser     string                   // orchestrator basic authentication user
+	OrchestratorPassword string                   // orchestrator basic authentication password
+	Collation            string                   // MySQL collation to use for stores, replaces charset if specified
+
+	HeartbeatSettings MySQLHeartbeatConfigurationSettings // applies when MetricType is "heartbeat"
+	TLSSettings       TLSConfigurationSettings            // TLS settings for connecting to probed servers; applies to all clusters
+	HttpCheckSettings HttpCheckConfigurationSettings      // applies when HttpCheckPort is set
 
 	Clusters map[string](*MySQLClusterConfigurationSettings) // cluster name -> cluster config
 }
@@ -73,14 +153,25 @@ func (settings *MySQLConfigurationSettings) postReadAdjustments() error {
 	if settings.Port == 0 {
 		settings.Port = DefaultMySQLPort
 	}
+	if err := validateMySQLMetricType(settings.MetricType, settings.MetricQuery); err != nil {
+		return err
+	}
+	if err := validateMySQLMetricQuery(settings.MetricQuery, settings.MetricAggregation, settings.MetricRate); err != nil {
+		return err
+	}
 	// Username & password may be given as plaintext in the config file, or can be delivered
 	// via environment variables. We accept user & password in the form "${SOME_ENV_VARIABLE}"
 	// in which case we get the value from this process' invoking environment.
-	if submatch := envVariableRegexp.FindStringSubmatch(settings.User); len(submatch) > 1 {
-		settings.User = os.Getenv(submatch[1])
+	// We also accept user & password in the form "${file:/path/to/secret}", in which case the
+	// value is read from the file upon use, and re-read when the file changes.
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+
+	if err := settings.TLSSettings.postReadAdjustments(); err != nil {
+		return err
 	}
-	if submatch := envVariableRegexp.FindStringSubmatch(settings.Password); len(submatch) > 1 {
-		settings.Password = os.Getenv(submatch[1])
+	if err := settings.ProxySQLTLSSettings.postReadAdjustments(); err != nil {
+		return err
 	}
 
 	for i := 0; i < len(settings.VitessCells); i++ {
@@ -100,8 +191,30 @@ func (settings *MySQLConfigurationSettings) postReadAdjustments() error {
 		if clusterSettings.Password == "" {
 			clusterSettings.Password = settings.Password
 		}
-		if clusterSettings.MetricQuery == "" {
+		if _, _, err := clusterSettings.Credentials(); err != nil {
+			return err
+		}
+		if clusterSettings.MetricQuery == "" && clusterSettings.MetricType == "" {
 			clusterSettings.MetricQuery = settings.MetricQuery
+			clusterSettings.MetricType = settings.MetricType
+			clusterSettings.MetricRate = clusterSettings.MetricRate || settings.MetricRate
+		}
+		if clusterSettings.MetricAggregation == "" {
+			clusterSettings.MetricAggregation = settings.MetricAggregation
+		}
+		if err := validateMySQLMetricType(clusterSettings.MetricType, clusterSettings.MetricQuery); err != nil {
+			return err
+		}
+		if err := validateMySQLMetricQuery(clusterSettings.MetricQuery, clusterSettings.MetricAggregation, clusterSettings.MetricRate); err != nil {
+			return err
+		}
+		clusterSettings.HeartbeatSettings.inherit(&settings.HeartbeatSettings)
+		if err := clusterSettings.HeartbeatSettings.postReadAdjustments(); err != nil {
+			return err
+		}
+		clusterSettings.TLSSettings.inherit(&settings.TLSSettings)
+		if err := clusterSettings.TLSSettings.postReadAdjustments(); err != nil {
+			return err
 		}
 		if clusterSettings.CacheMillis == 0 {
 			clusterSettings.CacheMillis = settings.CacheMillis
@@ -124,9 +237,16 @@ func (settings *MySQLConfigurationSettings) postReadAdjustments() error {
 		if clusterSettings.HttpCheckPath == "" {
 			clusterSettings.HttpCheckPath = settings.HttpCheckPath
 		}
+		clusterSettings.HttpCheckSettings.inherit(&settings.HttpCheckSettings)
+		if err := clusterSettings.HttpCheckSettings.postReadAdjustments(); err != nil {
+			return err
+		}
 		if len(clusterSettings.IgnoreHosts) == 0 {
 			clusterSettings.IgnoreHosts = settings.IgnoreHosts
 		}
+		if len(clusterSettings.ReplicationChannels) == 0 {
+			clusterSettings.ReplicationChannels = settings.ReplicationChannels
+		}
 		if !clusterSettings.ProxySQLSettings.IsEmpty() {
 			if len(clusterSettings.ProxySQLSettings.Addresses) < 1 {
 				clusterSettings.ProxySQLSettings.Addresses = settings.ProxySQLAddresses
@@ -137,10 +257,70 @@ func (settings *MySQLConfigurationSettings) postReadAdjustments() error {
 			if clusterSettings.ProxySQLSettings.Password == "" {
 				clusterSettings.ProxySQLSettings.Password = settings.ProxySQLPassword
 			}
+			clusterSettings.ProxySQLSettings.TLSSettings.inherit(&settings.ProxySQLTLSSettings)
+			if err := clusterSettings.ProxySQLSettings.TLSSettings.postReadAdjustments(); err != nil {
+				return err
+			}
+			if err := clusterSettings.ProxySQLSettings.postReadAdjustments(); err != nil {
+				return err
+			}
+		}
+		if clusterSettings.MetricType == MySQLMetricTypeProxySQLReplicationLag && clusterSettings.ProxySQLSettings.IsEmpty() {
+			return fmt.Errorf("MetricType=%s requires ProxySQLSettings", clusterSettings.MetricType)
 		}
 		if !clusterSettings.VitessSettings.IsEmpty() && len(clusterSettings.VitessSettings.Cells) < 1 {
 			clusterSettings.VitessSettings.Cells = settings.VitessCells
 		}
+		if !clusterSettings.OrchestratorSettings.IsEmpty() {
+			if clusterSettings.OrchestratorSettings.URL == "" {
+				clusterSettings.OrchestratorSettings.URL = settings.OrchestratorURL
+			}
+			if clusterSettings.OrchestratorSettings.User == "" {
+				clusterSettings.OrchestratorSettings.User = settings.OrchestratorUser
+			}
+			if clusterSettings.OrchestratorSettings.Password == "" {
+				clusterSettings.OrchestratorSettings.Password = settings.OrchestratorPassword
+			}
+			if err := clusterSettings.OrchestratorSettings.postReadAdjustments(); err != nil {
+				return err
+			}
+		}
+		if !clusterSettings.ConsulSettings.IsEmpty() {
+			if clusterSettings.ConsulSettings.Address == "" {
+				clusterSettings.ConsulSettings.Address = settings.ConsulAddress
+			}
+			if clusterSettings.ConsulSettings.Token == "" {
+				clusterSettings.ConsulSettings.Token = settings.ConsulToken
+			}
+			if err := clusterSettings.ConsulSettings.postReadAdjustments(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateMySQLMetricType(metricType string, metricQuery string) error {
+	switch metricType {
+	case MySQLMetricTypeReplicationLag:
+		return nil
+	case MySQLMetricTypeHeartbeat, MySQLMetricTypeReplicationApplierLag, MySQLMetricTypeGroupReplicationQueue, MySQLMetricTypeProxySQLReplicationLag:
+		if metricQuery != "" {
+			return fmt.Errorf("MetricQuery and MetricType=%s are mutually exclusive", metricType)
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported MetricType: %s", metricType)
+}
+
+func validateMySQLMetricQuery(metricQuery string, metricAggregation string, metricRate bool) error {
+	switch metricAggregation {
+	case MySQLMetricAggregationNone, MySQLMetricAggregationMax, MySQLMetricAggregationSum, MySQLMetricAggregationAvg:
+	default:
+		return fmt.Errorf("Unsupported MetricAggregation: %s", metricAggregation)
+	}
+	if metricRate && metricQuery == "" {
+		return fmt.Errorf("MetricRate requires MetricQuery")
 	}
 	return nil
 }
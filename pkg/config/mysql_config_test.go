@@ -0,0 +1,337 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestMySQLMetricTypeInheritance(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			MetricType: MySQLMetricTypeHeartbeat,
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {},
+				"custom":   {MetricQuery: "select 1"},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].MetricType, MySQLMetricTypeHeartbeat)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HeartbeatSettings.Schema, DefaultMySQLHeartbeatSchema)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HeartbeatSettings.Table, DefaultMySQLHeartbeatTable)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HeartbeatSettings.IntervalMillis, DefaultMySQLHeartbeatIntervalMillis)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].MetricType, MySQLMetricTypeReplicationLag)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].MetricQuery, "select 1")
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			HeartbeatSettings: MySQLHeartbeatConfigurationSettings{Schema: "percona", ServerId: 3},
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {MetricType: MySQLMetricTypeHeartbeat, HeartbeatSettings: MySQLHeartbeatConfigurationSettings{Table: "hb"}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["c0"].HeartbeatSettings.TableName(), "`percona`.`hb`")
+		test.S(t).ExpectEquals(settings.Clusters["c0"].HeartbeatSettings.ServerId, uint(3))
+	}
+	{
+		write, noWrite := true, false
+		settings := &MySQLConfigurationSettings{
+			MetricType:        MySQLMetricTypeHeartbeat,
+			HeartbeatSettings: MySQLHeartbeatConfigurationSettings{Write: &write},
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {},
+				"opts-out": {HeartbeatSettings: MySQLHeartbeatConfigurationSettings{Write: &noWrite}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectTrue(settings.Clusters["inherits"].HeartbeatSettings.WriteEnabled())
+		test.S(t).ExpectFalse(settings.Clusters["opts-out"].HeartbeatSettings.WriteEnabled())
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {MetricType: MySQLMetricTypeHeartbeat, MetricQuery: "select 1"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &MySQLConfigurationSettings{MetricType: "no-such-type"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLHttpCheckSettingsInheritance(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			HttpCheckSettings: HttpCheckConfigurationSettings{Scheme: "https", Headers: map[string]string{"Authorization": "Bearer token"}},
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {},
+				"custom":   {HttpCheckSettings: HttpCheckConfigurationSettings{ExpectedStatuses: []int{200}, OnFail: HttpCheckActionError}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HttpCheckSettings.Scheme, "https")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HttpCheckSettings.Headers["Authorization"], "Bearer token")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HttpCheckSettings.TimeoutMillis, DefaultHttpCheckTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HttpCheckSettings.OnFail, HttpCheckActionExclude)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].HttpCheckSettings.OnError, HttpCheckActionInclude)
+		test.S(t).ExpectTrue(settings.Clusters["inherits"].HttpCheckSettings.IsExpectedStatus(503))
+		test.S(t).ExpectFalse(settings.Clusters["inherits"].HttpCheckSettings.IsExpectedStatus(404))
+
+		test.S(t).ExpectEquals(settings.Clusters["custom"].HttpCheckSettings.Scheme, "http")
+		test.S(t).ExpectEquals(len(settings.Clusters["custom"].HttpCheckSettings.Headers), 0)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].HttpCheckSettings.OnFail, HttpCheckActionError)
+		test.S(t).ExpectTrue(settings.Clusters["custom"].HttpCheckSettings.IsExpectedStatus(200))
+		test.S(t).ExpectFalse(settings.Clusters["custom"].HttpCheckSettings.IsExpectedStatus(503))
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {HttpCheckSettings: HttpCheckConfigurationSettings{Scheme: "ftp"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {HttpCheckSettings: HttpCheckConfigurationSettings{OnError: "ignore"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLConsulSettingsInheritance(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			ConsulAddress: "http://consul.example.com:8500",
+			ConsulToken:   "s3cr3t",
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {ConsulSettings: ConsulConfigurationSettings{Service: "mysql", Tags: []string{"inherits"}}},
+				"custom":   {ConsulSettings: ConsulConfigurationSettings{Service: "mysql", Address: "http://127.0.0.1:8500", WaitSeconds: 10}},
+				"static":   {StaticHostsSettings: StaticHostsConfigurationSettings{Hosts: []string{"localhost"}}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ConsulSettings.Address, "http://consul.example.com:8500")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ConsulSettings.Token, "s3cr3t")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ConsulSettings.TimeoutMillis, DefaultConsulTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ConsulSettings.WaitSeconds, DefaultConsulWaitSeconds)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].ConsulSettings.Address, "http://127.0.0.1:8500")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].ConsulSettings.WaitSeconds, 10)
+		test.S(t).ExpectTrue(settings.Clusters["static"].ConsulSettings.IsEmpty())
+		test.S(t).ExpectEquals(settings.Clusters["static"].ConsulSettings.Address, "")
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {ConsulSettings: ConsulConfigurationSettings{Service: "mysql"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLDNSSettings(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"srv": {DNSSettings: DNSConfigurationSettings{Name: "_mysql._tcp.shard1.example.com", Resolver: "10.0.0.53"}},
+				"a":   {DNSSettings: DNSConfigurationSettings{Name: "shard2.example.com", RecordType: "a", OnFailure: DNSOnFailureFail}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["srv"].DNSSettings.RecordType, DNSRecordTypeSRV)
+		test.S(t).ExpectEquals(settings.Clusters["srv"].DNSSettings.Resolver, "10.0.0.53:53")
+		test.S(t).ExpectEquals(settings.Clusters["srv"].DNSSettings.OnFailure, DNSOnFailureKeep)
+		test.S(t).ExpectEquals(settings.Clusters["srv"].DNSSettings.TimeoutMillis, DefaultDNSTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["a"].DNSSettings.RecordType, DNSRecordTypeA)
+		test.S(t).ExpectEquals(settings.Clusters["a"].DNSSettings.Resolver, "")
+		test.S(t).ExpectEquals(settings.Clusters["a"].DNSSettings.OnFailure, DNSOnFailureFail)
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {DNSSettings: DNSConfigurationSettings{Name: "shard1.example.com", RecordType: "AAAA"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {DNSSettings: DNSConfigurationSettings{Name: "shard1.example.com", OnFailure: "retry"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLOrchestratorSettingsInheritance(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			OrchestratorURL:      "http://orchestrator.example.com:3000",
+			OrchestratorUser:     "freno",
+			OrchestratorPassword: "s3cr3t",
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {OrchestratorSettings: OrchestratorConfigurationSettings{Alias: "prod4"}},
+				"custom":   {OrchestratorSettings: OrchestratorConfigurationSettings{ClusterName: "db:3306", URL: "http://127.0.0.1:3000", TimeoutMillis: 300}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].OrchestratorSettings.URL, "http://orchestrator.example.com:3000")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].OrchestratorSettings.User, "freno")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].OrchestratorSettings.Password, "s3cr3t")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].OrchestratorSettings.TimeoutMillis, DefaultOrchestratorTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].OrchestratorSettings.URL, "http://127.0.0.1:3000")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].OrchestratorSettings.TimeoutMillis, 300)
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			OrchestratorURL: "http://orchestrator.example.com:3000",
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {OrchestratorSettings: OrchestratorConfigurationSettings{Alias: "prod4", ClusterName: "db:3306"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {OrchestratorSettings: OrchestratorConfigurationSettings{Alias: "prod4"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLKubernetesSettings(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"service": {KubernetesSettings: KubernetesConfigurationSettings{Service: "mysql-replicas"}},
+				"pods":    {KubernetesSettings: KubernetesConfigurationSettings{LabelSelector: "app=mysql"}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["service"].KubernetesSettings.Resource, KubernetesResourceEndpointSlices)
+		test.S(t).ExpectEquals(settings.Clusters["service"].KubernetesSettings.TimeoutMillis, DefaultKubernetesTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["pods"].KubernetesSettings.Resource, KubernetesResourcePods)
+	}
+	for _, kubernetesSettings := range []KubernetesConfigurationSettings{
+		{Service: "mysql-replicas", Resource: KubernetesResourcePods},
+		{LabelSelector: "app=mysql", Resource: KubernetesResourceEndpoints},
+		{Service: "mysql-replicas", Resource: "services"},
+	} {
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {KubernetesSettings: kubernetesSettings},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLFileHostsSettings(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"lines":  {FileHostsSettings: FileHostsConfigurationSettings{Path: "/etc/freno/hosts/lines"}},
+				"json":   {FileHostsSettings: FileHostsConfigurationSettings{Path: "/etc/freno/hosts/c0.JSON"}},
+				"yaml":   {FileHostsSettings: FileHostsConfigurationSettings{Path: "/etc/freno/hosts/c0.yml"}},
+				"forced": {FileHostsSettings: FileHostsConfigurationSettings{Path: "/etc/freno/hosts/c0.txt", Format: FileHostsFormatJSON}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["lines"].FileHostsSettings.Format, FileHostsFormatLines)
+		test.S(t).ExpectEquals(settings.Clusters["json"].FileHostsSettings.Format, FileHostsFormatJSON)
+		test.S(t).ExpectEquals(settings.Clusters["yaml"].FileHostsSettings.Format, FileHostsFormatYAML)
+		test.S(t).ExpectEquals(settings.Clusters["forced"].FileHostsSettings.Format, FileHostsFormatJSON)
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {FileHostsSettings: FileHostsConfigurationSettings{Path: "/etc/freno/hosts/c0", Format: "csv"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLTopologySettings(t *testing.T) {
+	settings := &MySQLConfigurationSettings{
+		Clusters: map[string](*MySQLClusterConfigurationSettings){
+			"default": {TopologySettings: TopologyConfigurationSettings{SeedHosts: []string{"primary"}}},
+			"deep":    {TopologySettings: TopologyConfigurationSettings{SeedHosts: []string{"primary"}, MaxDepth: 5}},
+			"none":    {},
+		},
+	}
+	test.S(t).ExpectNil(settings.postReadAdjustments())
+	test.S(t).ExpectEquals(settings.Clusters["default"].TopologySettings.MaxDepth, DefaultTopologyMaxDepth)
+	test.S(t).ExpectEquals(settings.Clusters["deep"].TopologySettings.MaxDepth, 5)
+	test.S(t).ExpectTrue(settings.Clusters["none"].TopologySettings.IsEmpty())
+	test.S(t).ExpectEquals(settings.Clusters["none"].TopologySettings.MaxDepth, 0)
+}
+
+func TestMySQLDiscoveryMode(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"default": {},
+				"union":   {DiscoveryMode: DiscoveryModeUnion},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["default"].DiscoveryMode, DiscoveryModeFirst)
+		test.S(t).ExpectEquals(settings.Clusters["union"].DiscoveryMode, DiscoveryModeUnion)
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"c0": {DiscoveryMode: "all"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
+
+func TestMySQLProxySQLMetricType(t *testing.T) {
+	{
+		settings := &MySQLConfigurationSettings{
+			MetricType: MySQLMetricTypeProxySQLReplicationLag,
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"proxysql": {ProxySQLSettings: ProxySQLConfigurationSettings{Addresses: []string{"proxysql1:6032"}, User: "admin", Password: "admin", HostgroupID: 20}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["proxysql"].MetricType, MySQLMetricTypeProxySQLReplicationLag)
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"static": {MetricType: MySQLMetricTypeProxySQLReplicationLag, StaticHostsSettings: StaticHostsConfigurationSettings{Hosts: []string{"replica1"}}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"proxysql": {
+					MetricType:       MySQLMetricTypeProxySQLReplicationLag,
+					MetricQuery:      "select 1",
+					ProxySQLSettings: ProxySQLConfigurationSettings{Addresses: []string{"proxysql1:6032"}, User: "admin", Password: "admin", HostgroupID: 20},
+				},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+//
+// MySQL heartbeat configuration, applies when MetricType is "heartbeat"
+//
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultMySQLHeartbeatSchema         = "meta"
+	DefaultMySQLHeartbeatTable          = "heartbeat"
+	DefaultMySQLHeartbeatIntervalMillis = 250
+)
+
+// MySQLHeartbeatConfigurationSettings describes a pt-heartbeat compatible table, i.e.:
+//
+//	CREATE TABLE heartbeat (
+//	  ts                    varchar(26) NOT NULL,
+//	  server_id             int unsigned NOT NULL PRIMARY KEY,
+//	  file                  varchar(255) DEFAULT NULL,
+//	  position              bigint unsigned DEFAULT NULL,
+//	  relay_master_log_file varchar(255) DEFAULT NULL,
+//	  exec_master_log_pos   bigint unsigned DEFAULT NULL
+//	);
+type MySQLHeartbeatConfigurationSettings struct {
+	Schema         string // heartbeat schema. Default: "meta"
+	Table          string // heartbeat table. Default: "heartbeat"
+	ServerId       uint   // if non-zero, only consider heartbeats written by this server_id
+	Write          *bool  // if true, freno writes heartbeats onto the cluster's primary. Leave empty to inherit; a cluster may set false to opt out
+	PrimaryHost    string // primary to write heartbeats onto ("hostname" or "hostname:port"). If empty, the primary is discovered via the replicas' replication status
+	IntervalMillis int    // heartbeat write interval. Default: 250
+}
+
+// TableName returns the escaped, fully qualified heartbeat table name
+func (settings *MySQLHeartbeatConfigurationSettings) TableName() string {
+	return fmt.Sprintf("`%s`.`%s`", settings.Schema, settings.Table)
+}
+
+// WriteEnabled returns true when freno is to write heartbeats onto the cluster's primary
+func (settings *MySQLHeartbeatConfigurationSettings) WriteEnabled() bool {
+	return settings.Write != nil && *settings.Write
+}
+
+// inherit applies values from given (global) settings onto these settings, where not explicitly set
+func (settings *MySQLHeartbeatConfigurationSettings) inherit(other *MySQLHeartbeatConfigurationSettings) {
+	if settings.Schema == "" {
+		settings.Schema = other.Schema
+	}
+	if settings.Table == "" {
+		settings.Table = other.Table
+	}
+	if settings.ServerId == 0 {
+		settings.ServerId = other.ServerId
+	}
+	if settings.Write == nil {
+		settings.Write = other.Write
+	}
+	if settings.IntervalMillis == 0 {
+		settings.IntervalMillis = other.IntervalMillis
+	}
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *MySQLHeartbeatConfigurationSettings) postReadAdjustments() error {
+	if settings.Schema == "" {
+		settings.Schema = DefaultMySQLHeartbeatSchema
+	}
+	if settings.Table == "" {
+		settings.Table = DefaultMySQLHeartbeatTable
+	}
+	if settings.IntervalMillis <= 0 {
+		settings.IntervalMillis = DefaultMySQLHeartbeatIntervalMillis
+	}
+	if strings.Contains(settings.Schema, "`") || strings.Contains(settings.Table, "`") {
+		return fmt.Errorf("HeartbeatSettings: invalid schema/table name: %s.%s", settings.Schema, settings.Table)
+	}
+	return nil
+}
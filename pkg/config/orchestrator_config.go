@@ -0,0 +1,46 @@
+package config
+
+//
+// Orchestrator hosts configuration
+//
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const DefaultOrchestratorTimeoutMillis = 1000
+
+type OrchestratorConfigurationSettings struct {
+	URL                        string // orchestrator API, e.g. "http://orchestrator.example.com:3000". Leave empty to inherit MySQLConfigurationSettings's OrchestratorURL
+	User                       string // optional, basic authentication user. Leave empty to inherit MySQLConfigurationSettings's OrchestratorUser
+	Password                   string // optional, basic authentication password. Leave empty to inherit MySQLConfigurationSettings's OrchestratorPassword
+	Alias                      string // cluster alias, as listed by /api/cluster/alias/<alias>
+	ClusterName                string // cluster name, as listed by /api/cluster/<cluster name>. Mutually exclusive with Alias
+	ExcludeIntermediateMasters bool   // if true, replicas which have replicas of their own are not probed
+	ExcludeDelayedReplicas     bool   // if true, replicas configured with a replication delay (MASTER_DELAY) are not probed
+	TimeoutMillis              int    // request timeout. Default: 1000
+}
+
+func (settings *OrchestratorConfigurationSettings) IsEmpty() bool {
+	return settings.Alias == "" && settings.ClusterName == ""
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *OrchestratorConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if settings.Alias != "" && settings.ClusterName != "" {
+		return fmt.Errorf("Orchestrator: Alias and ClusterName are mutually exclusive")
+	}
+	if parsed, err := url.Parse(settings.URL); err != nil || parsed.Host == "" {
+		return fmt.Errorf("Orchestrator: invalid URL: %s", settings.URL)
+	}
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultOrchestratorTimeoutMillis
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package config
+
+//
+// PostgreSQL-specific configuration
+//
+
+import (
+	"fmt"
+	"strings"
+)
+
+const DefaultPostgreSQLPort = 5432
+const DefaultPostgreSQLDatabase = "postgres"
+const DefaultPostgreSQLSSLMode = "prefer"
+
+const (
+	PostgreSQLMetricTypeReplayLag       = ""                 // on replicas: time since last replayed transaction, per pg_last_xact_replay_timestamp()
+	PostgreSQLMetricTypeStatReplication = "stat_replication" // on primaries: worst replay_lag of pg_stat_replication
+)
+
+type PostgreSQLClusterConfigurationSettings struct {
+	User                 string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	Password             string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	Database             string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	SSLMode              string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	MetricQuery          string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	MetricType           string   // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	CacheMillis          int      // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	ThrottleThreshold    float64  // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+	Port                 int      // Specify if different than 5432 or if different than specified by PostgreSQLConfigurationSettings
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // override PostgreSQLConfigurationSettings's, or leave empty to inherit those settings
+
+	HAProxySettings     HAProxyConfigurationSettings // If list of servers is to be acquired via HAProxy, provide this field
+	StaticHostsSettings StaticHostsConfigurationSettings
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *PostgreSQLClusterConfigurationSettings) postReadAdjustments() error {
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+	if err := settings.HAProxySettings.postReadAdjustments(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Credentials returns the cluster's current user & password, resolving secret file references
+func (settings *PostgreSQLClusterConfigurationSettings) Credentials() (user string, password string, err error) {
+	if user, err = ResolveSecret(settings.User); err != nil {
+		return user, password, err
+	}
+	if password, err = ResolveSecret(settings.Password); err != nil {
+		return user, password, err
+	}
+	return user, password, nil
+}
+
+type PostgreSQLConfigurationSettings struct {
+	User                 string
+	Password             string
+	Database             string // database to connect to. Default: "postgres"
+	SSLMode              string // libpq sslmode: "disable", "require", "verify-ca" or "verify-full". Default: "prefer"
+	MetricQuery          string
+	MetricType           string // optional, "stat_replication" to read replication lag on primaries. Mutually exclusive with MetricQuery
+	CacheMillis          int    // optional, if defined then probe result will be cached, and future probes may use cached value
+	ThrottleThreshold    float64
+	Port                 int      // Specify if different than 5432; applies to all clusters
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // If non empty, substrings to indicate hosts to be ignored/skipped
+
+	Clusters map[string](*PostgreSQLClusterConfigurationSettings) // cluster name -> cluster config
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *PostgreSQLConfigurationSettings) postReadAdjustments() error {
+	if settings.Port == 0 {
+		settings.Port = DefaultPostgreSQLPort
+	}
+	if settings.Database == "" {
+		settings.Database = DefaultPostgreSQLDatabase
+	}
+	if settings.SSLMode == "" {
+		settings.SSLMode = DefaultPostgreSQLSSLMode
+	}
+	if err := validatePostgreSQLMetric(settings.MetricType, settings.MetricQuery); err != nil {
+		return err
+	}
+	// Username & password may be given as plaintext, as "${SOME_ENV_VARIABLE}" or as "${file:/path/to/secret}",
+	// as with MySQL
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+
+	for _, clusterSettings := range settings.Clusters {
+		if err := clusterSettings.postReadAdjustments(); err != nil {
+			return err
+		}
+		if clusterSettings.User == "" {
+			clusterSettings.User = settings.User
+		}
+		if clusterSettings.Password == "" {
+			clusterSettings.Password = settings.Password
+		}
+		if _, _, err := clusterSettings.Credentials(); err != nil {
+			return err
+		}
+		if clusterSettings.Database == "" {
+			clusterSettings.Database = settings.Database
+		}
+		if clusterSettings.SSLMode == "" {
+			clusterSettings.SSLMode = settings.SSLMode
+		}
+		if clusterSettings.MetricQuery == "" && clusterSettings.MetricType == "" {
+			clusterSettings.MetricQuery = settings.MetricQuery
+			clusterSettings.MetricType = settings.MetricType
+		}
+		if err := validatePostgreSQLMetric(clusterSettings.MetricType, clusterSettings.MetricQuery); err != nil {
+			return err
+		}
+		if clusterSettings.CacheMillis == 0 {
+			clusterSettings.CacheMillis = settings.CacheMillis
+		}
+		if clusterSettings.ThrottleThreshold == 0 {
+			clusterSettings.ThrottleThreshold = settings.ThrottleThreshold
+		}
+		if clusterSettings.Port == 0 {
+			clusterSettings.Port = settings.Port
+		}
+		if clusterSettings.IgnoreHostsCount == 0 {
+			clusterSettings.IgnoreHostsCount = settings.IgnoreHostsCount
+		}
+		if clusterSettings.IgnoreHostsThreshold == 0 {
+			clusterSettings.IgnoreHostsThreshold = settings.IgnoreHostsThreshold
+		}
+		if len(clusterSettings.IgnoreHosts) == 0 {
+			clusterSettings.IgnoreHosts = settings.IgnoreHosts
+		}
+	}
+	return nil
+}
+
+func validatePostgreSQLMetric(metricType string, metricQuery string) error {
+	switch metricType {
+	case PostgreSQLMetricTypeReplayLag:
+	case PostgreSQLMetricTypeStatReplication:
+		if metricQuery != "" {
+			return fmt.Errorf("MetricQuery and MetricType=%s are mutually exclusive", metricType)
+		}
+	default:
+		return fmt.Errorf("Unsupported PostgreSQL MetricType: %s", metricType)
+	}
+	if metricQuery != "" && !strings.HasPrefix(strings.ToLower(strings.TrimSpace(metricQuery)), "select") {
+		return fmt.Errorf("Unsupported PostgreSQL MetricQuery, expecting a SELECT query: %s", metricQuery)
+	}
+	return nil
+}
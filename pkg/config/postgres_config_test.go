@@ -0,0 +1,52 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestPostgreSQLConfigurationInheritance(t *testing.T) {
+	{
+		settings := &PostgreSQLConfigurationSettings{
+			User:              "freno",
+			ThrottleThreshold: 1.0,
+			Clusters: map[string](*PostgreSQLClusterConfigurationSettings){
+				"inherits": {},
+				"custom":   {MetricType: PostgreSQLMetricTypeStatReplication, Port: 6432, Database: "meta", SSLMode: "require"},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].User, "freno")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Port, DefaultPostgreSQLPort)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Database, DefaultPostgreSQLDatabase)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].SSLMode, DefaultPostgreSQLSSLMode)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].MetricType, PostgreSQLMetricTypeReplayLag)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ThrottleThreshold, 1.0)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Port, 6432)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Database, "meta")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].SSLMode, "require")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].MetricType, PostgreSQLMetricTypeStatReplication)
+	}
+	{
+		settings := &PostgreSQLConfigurationSettings{
+			Clusters: map[string](*PostgreSQLClusterConfigurationSettings){
+				"c0": {MetricType: PostgreSQLMetricTypeStatReplication, MetricQuery: "select 1"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &PostgreSQLConfigurationSettings{MetricQuery: "delete from t"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &PostgreSQLConfigurationSettings{MetricType: "no-such-type"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
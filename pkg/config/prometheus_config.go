@@ -0,0 +1,100 @@
+package config
+
+//
+// Prometheus store configuration: metrics scraped off Prometheus text exposition endpoints
+//
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	PrometheusAggregationMax = "max"
+	PrometheusAggregationMin = "min"
+	PrometheusAggregationSum = "sum"
+	PrometheusAggregationAvg = "avg"
+)
+
+type PrometheusClusterConfigurationSettings struct {
+	URLs                 []string          // /metrics endpoints to scrape, one per host
+	Metric               string            // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	Aggregation          string            // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	Headers              map[string]string // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	TimeoutMillis        int               // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	CacheMillis          int               // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	ThrottleThreshold    float64           // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+	IgnoreHostsCount     int               // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64           // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string          // override PrometheusConfigurationSettings's, or leave empty to inherit those settings
+}
+
+type PrometheusConfigurationSettings struct {
+	Metric               string            // selector of the metric, e.g. `mysql_slave_status_seconds_behind_master{channel_name=""}`
+	Aggregation          string            // aggregation of multiple samples selected on a host: "max" (default), "min", "sum" or "avg"
+	Headers              map[string]string // request headers, e.g. authorization
+	TimeoutMillis        int               // request timeout. Default: 1000
+	CacheMillis          int               // optional, if defined then probe result will be cached, and future probes may use cached value
+	ThrottleThreshold    float64
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // If non empty, substrings to indicate URLs to be ignored/skipped
+
+	Clusters map[string](*PrometheusClusterConfigurationSettings) // cluster name -> cluster config
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *PrometheusConfigurationSettings) postReadAdjustments() error {
+	if settings.TimeoutMillis <= 0 {
+		settings.TimeoutMillis = DefaultHTTPStoreTimeoutMillis
+	}
+	if settings.Aggregation == "" {
+		settings.Aggregation = PrometheusAggregationMax
+	}
+	for clusterName, clusterSettings := range settings.Clusters {
+		if len(clusterSettings.URLs) == 0 {
+			return fmt.Errorf("Prometheus cluster %s: URLs must be provided", clusterName)
+		}
+		for _, u := range clusterSettings.URLs {
+			if parsed, err := url.Parse(u); err != nil || parsed.Host == "" {
+				return fmt.Errorf("Prometheus cluster %s: invalid URL: %s", clusterName, u)
+			}
+		}
+		if clusterSettings.Metric == "" {
+			clusterSettings.Metric = settings.Metric
+		}
+		if clusterSettings.Metric == "" {
+			return fmt.Errorf("Prometheus cluster %s: Metric must be provided", clusterName)
+		}
+		if clusterSettings.Aggregation == "" {
+			clusterSettings.Aggregation = settings.Aggregation
+		}
+		switch clusterSettings.Aggregation {
+		case PrometheusAggregationMax, PrometheusAggregationMin, PrometheusAggregationSum, PrometheusAggregationAvg:
+		default:
+			return fmt.Errorf("Prometheus cluster %s: unsupported Aggregation: %s", clusterName, clusterSettings.Aggregation)
+		}
+		if len(clusterSettings.Headers) == 0 {
+			clusterSettings.Headers = settings.Headers
+		}
+		if clusterSettings.TimeoutMillis <= 0 {
+			clusterSettings.TimeoutMillis = settings.TimeoutMillis
+		}
+		if clusterSettings.CacheMillis == 0 {
+			clusterSettings.CacheMillis = settings.CacheMillis
+		}
+		if clusterSettings.ThrottleThreshold == 0 {
+			clusterSettings.ThrottleThreshold = settings.ThrottleThreshold
+		}
+		if clusterSettings.IgnoreHostsCount == 0 {
+			clusterSettings.IgnoreHostsCount = settings.IgnoreHostsCount
+		}
+		if clusterSettings.IgnoreHostsThreshold == 0 {
+			clusterSettings.IgnoreHostsThreshold = settings.IgnoreHostsThreshold
+		}
+		if len(clusterSettings.IgnoreHosts) == 0 {
+			clusterSettings.IgnoreHosts = settings.IgnoreHosts
+		}
+	}
+	return nil
+}
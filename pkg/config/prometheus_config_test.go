@@ -0,0 +1,62 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestPrometheusConfigurationInheritance(t *testing.T) {
+	{
+		settings := &PrometheusConfigurationSettings{
+			Metric:            `mysql_slave_status_seconds_behind_master{channel_name=""}`,
+			ThrottleThreshold: 1.0,
+			IgnoreHosts:       []string{"db-0009"},
+			Clusters: map[string](*PrometheusClusterConfigurationSettings){
+				"inherits": {URLs: []string{"http://db-0002:9104/metrics"}},
+				"custom":   {URLs: []string{"http://db-0003:9100/metrics"}, Metric: "node_load1", Aggregation: PrometheusAggregationAvg, ThrottleThreshold: 8, IgnoreHosts: []string{"db-0004"}},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Metric, `mysql_slave_status_seconds_behind_master{channel_name=""}`)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Aggregation, PrometheusAggregationMax)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ThrottleThreshold, 1.0)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].TimeoutMillis, DefaultHTTPStoreTimeoutMillis)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Metric, "node_load1")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Aggregation, PrometheusAggregationAvg)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].ThrottleThreshold, 8.0)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].IgnoreHosts[0], "db-0009")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].IgnoreHosts[0], "db-0004")
+	}
+	{
+		settings := &PrometheusConfigurationSettings{
+			Clusters: map[string](*PrometheusClusterConfigurationSettings){
+				"c0": {URLs: []string{"http://db-0002:9104/metrics"}},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &PrometheusConfigurationSettings{
+			Metric: "node_load1",
+			Clusters: map[string](*PrometheusClusterConfigurationSettings){
+				"c0": {URLs: []string{"http://db-0002:9100/metrics"}, Aggregation: "median"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &PrometheusConfigurationSettings{
+			Metric: "node_load1",
+			Clusters: map[string](*PrometheusClusterConfigurationSettings){
+				"c0": {},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
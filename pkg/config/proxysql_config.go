@@ -1,6 +1,10 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 //
 // ProxySQL-specific configuration
@@ -8,12 +12,29 @@ import "fmt"
 
 const ProxySQLDefaultDatabase = "stats"
 
+const (
+	ProxySQLServersTableConnectionPool = "stats_mysql_connection_pool" // servers as seen by the connection pool, including SHUNNED_REPLICATION_LAG
+	ProxySQLServersTableRuntime        = "runtime_mysql_servers"       // servers as configured at runtime; requires admin (rather than stats) credentials
+)
+
+// ProxySQLDefaultReplicationLagMaxAgeSecs is the age beyond which ProxySQL's latest replication lag check of a
+// server is considered stale, e.g. as ProxySQL's monitor stopped checking it
+const ProxySQLDefaultReplicationLagMaxAgeSecs = 60
+
+// ProxySQLDefaultStatuses are the statuses of servers probed unless otherwise configured
+var ProxySQLDefaultStatuses = []string{"ONLINE", "SHUNNED_REPLICATION_LAG"}
+
 type ProxySQLConfigurationSettings struct {
-	Addresses           []string
+	Addresses           []string // ProxySQL admin addresses, by order of preference. Addresses which fail are avoided for a while
 	User                string
 	Password            string
 	HostgroupID         uint
 	IgnoreServerTTLSecs uint
+	ServersTable        string                   // "stats_mysql_connection_pool" (default) or "runtime_mysql_servers"
+	Statuses            []string                 // statuses of servers to probe, e.g. add "OFFLINE_SOFT". Default: "ONLINE", "SHUNNED_REPLICATION_LAG"
+	TLSSettings         TLSConfigurationSettings // leave empty to inherit MySQLConfigurationSettings's ProxySQLTLSSettings
+
+	ReplicationLagMaxAgeSecs uint // with MetricType "proxysql_replication_lag": age beyond which a lag check is stale. Default: 60
 }
 
 func (settings ProxySQLConfigurationSettings) AddressToDSN(address string) string {
@@ -32,3 +53,45 @@ func (settings *ProxySQLConfigurationSettings) IsEmpty() bool {
 	}
 	return false
 }
+
+// GetServersTable returns the table servers are read from
+func (settings *ProxySQLConfigurationSettings) GetServersTable() string {
+	if settings.ServersTable == "" {
+		return ProxySQLServersTableConnectionPool
+	}
+	return settings.ServersTable
+}
+
+// GetStatuses returns the statuses of servers to probe
+func (settings *ProxySQLConfigurationSettings) GetStatuses() []string {
+	if len(settings.Statuses) == 0 {
+		return ProxySQLDefaultStatuses
+	}
+	return settings.Statuses
+}
+
+// GetReplicationLagMaxAge returns the age beyond which ProxySQL's latest replication lag check of a server is stale
+func (settings *ProxySQLConfigurationSettings) GetReplicationLagMaxAge() time.Duration {
+	if settings.ReplicationLagMaxAgeSecs == 0 {
+		return ProxySQLDefaultReplicationLagMaxAgeSecs * time.Second
+	}
+	return time.Duration(settings.ReplicationLagMaxAgeSecs) * time.Second
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *ProxySQLConfigurationSettings) postReadAdjustments() error {
+	switch settings.GetServersTable() {
+	case ProxySQLServersTableConnectionPool, ProxySQLServersTableRuntime:
+	default:
+		return fmt.Errorf("ProxySQLSettings: unsupported ServersTable: %s", settings.ServersTable)
+	}
+	for i, status := range settings.Statuses {
+		switch status = strings.ToUpper(strings.TrimSpace(status)); status {
+		case "ONLINE", "SHUNNED", "SHUNNED_REPLICATION_LAG", "OFFLINE_SOFT", "OFFLINE_HARD":
+			settings.Statuses[i] = status
+		default:
+			return fmt.Errorf("ProxySQLSettings: unsupported status: %s", status)
+		}
+	}
+	return nil
+}
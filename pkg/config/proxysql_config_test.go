@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	test "github.com/outbrain/golib/tests"
 )
@@ -40,3 +41,34 @@ func TestProxySQLIsEmpty(t *testing.T) {
 		test.S(t).ExpectFalse(isEmpty)
 	}
 }
+
+func TestProxySQLPostReadAdjustments(t *testing.T) {
+	{
+		c := &ProxySQLConfigurationSettings{}
+		test.S(t).ExpectNil(c.postReadAdjustments())
+		test.S(t).ExpectEquals(c.GetServersTable(), ProxySQLServersTableConnectionPool)
+		test.S(t).ExpectEquals(len(c.GetStatuses()), 2)
+		test.S(t).ExpectEquals(c.GetReplicationLagMaxAge(), time.Minute)
+	}
+	{
+		c := &ProxySQLConfigurationSettings{ReplicationLagMaxAgeSecs: 15}
+		test.S(t).ExpectNil(c.postReadAdjustments())
+		test.S(t).ExpectEquals(c.GetReplicationLagMaxAge(), 15*time.Second)
+	}
+	{
+		c := &ProxySQLConfigurationSettings{ServersTable: ProxySQLServersTableRuntime, Statuses: []string{"online", " OFFLINE_SOFT"}}
+		test.S(t).ExpectNil(c.postReadAdjustments())
+		test.S(t).ExpectEquals(c.GetServersTable(), ProxySQLServersTableRuntime)
+		test.S(t).ExpectEquals(len(c.GetStatuses()), 2)
+		test.S(t).ExpectEquals(c.GetStatuses()[0], "ONLINE")
+		test.S(t).ExpectEquals(c.GetStatuses()[1], "OFFLINE_SOFT")
+	}
+	{
+		c := &ProxySQLConfigurationSettings{ServersTable: "mysql_servers"}
+		test.S(t).ExpectNotNil(c.postReadAdjustments())
+	}
+	{
+		c := &ProxySQLConfigurationSettings{Statuses: []string{"DRAINING"}}
+		test.S(t).ExpectNotNil(c.postReadAdjustments())
+	}
+}
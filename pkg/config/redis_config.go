@@ -0,0 +1,126 @@
+package config
+
+//
+// Redis-specific configuration
+//
+
+import (
+	"fmt"
+)
+
+const DefaultRedisPort = 6379
+
+const (
+	RedisMetricTypeLagSeconds = ""          // replication lag in seconds, as per the primary's replication offset history
+	RedisMetricTypeLagBytes   = "lag_bytes" // replication lag in bytes: the primary's master_repl_offset minus the replica's offset
+)
+
+type RedisClusterConfigurationSettings struct {
+	User                 string   // override RedisConfigurationSettings's, or leave empty to inherit those settings
+	Password             string   // override RedisConfigurationSettings's, or leave empty to inherit those settings
+	MetricType           string   // override RedisConfigurationSettings's, or leave empty to inherit those settings
+	CacheMillis          int      // override RedisConfigurationSettings's, or leave empty to inherit those settings
+	ThrottleThreshold    float64  // override RedisConfigurationSettings's, or leave empty to inherit those settings
+	Port                 int      // Specify if different than 6379 or if different than specified by RedisConfigurationSettings
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // override RedisConfigurationSettings's, or leave empty to inherit those settings
+
+	HAProxySettings     HAProxyConfigurationSettings // If list of servers is to be acquired via HAProxy, provide this field
+	StaticHostsSettings StaticHostsConfigurationSettings
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *RedisClusterConfigurationSettings) postReadAdjustments() error {
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+	if err := settings.HAProxySettings.postReadAdjustments(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Credentials returns the cluster's current user & password, resolving secret file references
+func (settings *RedisClusterConfigurationSettings) Credentials() (user string, password string, err error) {
+	if user, err = ResolveSecret(settings.User); err != nil {
+		return user, password, err
+	}
+	if password, err = ResolveSecret(settings.Password); err != nil {
+		return user, password, err
+	}
+	return user, password, nil
+}
+
+type RedisConfigurationSettings struct {
+	User                 string // optional, ACL user (Redis 6 and above)
+	Password             string // optional, AUTH password
+	MetricType           string // optional, "lag_bytes" for replication lag in bytes. Default: replication lag in seconds
+	CacheMillis          int    // optional, if defined then probe result will be cached, and future probes may use cached value
+	ThrottleThreshold    float64
+	Port                 int      // Specify if different than 6379; applies to all clusters
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies (default: 0)
+	IgnoreHosts          []string // If non empty, substrings to indicate hosts to be ignored/skipped
+
+	Clusters map[string](*RedisClusterConfigurationSettings) // cluster name -> cluster config
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *RedisConfigurationSettings) postReadAdjustments() error {
+	if settings.Port == 0 {
+		settings.Port = DefaultRedisPort
+	}
+	if err := validateRedisMetricType(settings.MetricType); err != nil {
+		return err
+	}
+	settings.User = resolveCredential(settings.User)
+	settings.Password = resolveCredential(settings.Password)
+
+	for _, clusterSettings := range settings.Clusters {
+		if err := clusterSettings.postReadAdjustments(); err != nil {
+			return err
+		}
+		if clusterSettings.User == "" {
+			clusterSettings.User = settings.User
+		}
+		if clusterSettings.Password == "" {
+			clusterSettings.Password = settings.Password
+		}
+		if _, _, err := clusterSettings.Credentials(); err != nil {
+			return err
+		}
+		if clusterSettings.MetricType == "" {
+			clusterSettings.MetricType = settings.MetricType
+		}
+		if err := validateRedisMetricType(clusterSettings.MetricType); err != nil {
+			return err
+		}
+		if clusterSettings.CacheMillis == 0 {
+			clusterSettings.CacheMillis = settings.CacheMillis
+		}
+		if clusterSettings.ThrottleThreshold == 0 {
+			clusterSettings.ThrottleThreshold = settings.ThrottleThreshold
+		}
+		if clusterSettings.Port == 0 {
+			clusterSettings.Port = settings.Port
+		}
+		if clusterSettings.IgnoreHostsCount == 0 {
+			clusterSettings.IgnoreHostsCount = settings.IgnoreHostsCount
+		}
+		if clusterSettings.IgnoreHostsThreshold == 0 {
+			clusterSettings.IgnoreHostsThreshold = settings.IgnoreHostsThreshold
+		}
+		if len(clusterSettings.IgnoreHosts) == 0 {
+			clusterSettings.IgnoreHosts = settings.IgnoreHosts
+		}
+	}
+	return nil
+}
+
+func validateRedisMetricType(metricType string) error {
+	switch metricType {
+	case RedisMetricTypeLagSeconds, RedisMetricTypeLagBytes:
+		return nil
+	}
+	return fmt.Errorf("Unsupported Redis MetricType: %s", metricType)
+}
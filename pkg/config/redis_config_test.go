@@ -0,0 +1,46 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestRedisConfigurationInheritance(t *testing.T) {
+	{
+		settings := &RedisConfigurationSettings{
+			Password:          "secret",
+			ThrottleThreshold: 1.0,
+			Clusters: map[string](*RedisClusterConfigurationSettings){
+				"inherits": {},
+				"custom":   {MetricType: RedisMetricTypeLagBytes, Port: 6380, Password: "other", ThrottleThreshold: 1048576},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Password, "secret")
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].Port, DefaultRedisPort)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].MetricType, RedisMetricTypeLagSeconds)
+		test.S(t).ExpectEquals(settings.Clusters["inherits"].ThrottleThreshold, 1.0)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Password, "other")
+		test.S(t).ExpectEquals(settings.Clusters["custom"].Port, 6380)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].MetricType, RedisMetricTypeLagBytes)
+		test.S(t).ExpectEquals(settings.Clusters["custom"].ThrottleThreshold, 1048576.0)
+	}
+	{
+		settings := &RedisConfigurationSettings{MetricType: "no-such-type"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &RedisConfigurationSettings{
+			Clusters: map[string](*RedisClusterConfigurationSettings){
+				"c0": {MetricType: "lag_seconds"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
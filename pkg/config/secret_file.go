@@ -0,0 +1,110 @@
+package config
+
+//
+// Secret files: credentials given as "${file:/path/to/secret}" are read off the file system,
+// and are re-read when the file changes, so that credentials can be rotated without a restart.
+//
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/outbrain/golib/log"
+)
+
+// secretFileCheckInterval is the minimal interval between two checks of a secret file for changes
+const secretFileCheckInterval = time.Second
+
+var secretFileRegexp = regexp.MustCompile(`^[$][{]file:(.+?)[}]$`)
+
+type secretFile struct {
+	value     string
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+var secretFiles = make(map[string]*secretFile)
+var secretFilesMutex sync.Mutex
+
+// getSecretFilePath returns the path referenced by given value, or empty string if the value is not a secret file reference
+func getSecretFilePath(value string) string {
+	if submatch := secretFileRegexp.FindStringSubmatch(value); len(submatch) > 1 {
+		return submatch[1]
+	}
+	return ""
+}
+
+// resolveCredential resolves a "${SOME_ENV_VARIABLE}" value from this process' environment. Secret file
+// references are kept as they are, to be resolved on use via ResolveSecret.
+func resolveCredential(value string) string {
+	if getSecretFilePath(value) != "" {
+		return value
+	}
+	if submatch := envVariableRegexp.FindStringSubmatch(value); len(submatch) > 1 {
+		return os.Getenv(submatch[1])
+	}
+	return value
+}
+
+// readSecretFile returns the trimmed content of given file. The file is checked for changes at most once
+// per secretFileCheckInterval. If a changed file cannot be read, the last known content applies.
+func readSecretFile(path string) (string, error) {
+	secretFilesMutex.Lock()
+	defer secretFilesMutex.Unlock()
+
+	secret, found := secretFiles[path]
+	if found && time.Since(secret.checkedAt) < secretFileCheckInterval {
+		return secret.value, nil
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		if found {
+			log.Errorf("secret file %s: %+v; using last known secret", path, err)
+			secret.checkedAt = time.Now()
+			return secret.value, nil
+		}
+		return "", err
+	}
+	if found && fileInfo.ModTime().Equal(secret.modTime) && fileInfo.Size() == secret.size {
+		secret.checkedAt = time.Now()
+		return secret.value, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if found {
+			log.Errorf("secret file %s: %+v; using last known secret", path, err)
+			secret.checkedAt = time.Now()
+			return secret.value, nil
+		}
+		return "", err
+	}
+	if found {
+		log.Infof("secret file %s changed; reloading", path)
+	}
+	secretFiles[path] = &secretFile{
+		value:     strings.TrimSpace(string(content)),
+		modTime:   fileInfo.ModTime(),
+		size:      fileInfo.Size(),
+		checkedAt: time.Now(),
+	}
+	return secretFiles[path].value, nil
+}
+
+// ResolveSecret returns the current value of given secret: the content of the referenced file for
+// "${file:/path/to/secret}" values, or otherwise the value itself
+func ResolveSecret(value string) (string, error) {
+	path := getSecretFilePath(value)
+	if path == "" {
+		return value, nil
+	}
+	secret, err := readSecretFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read secret file %s: %+v", path, err)
+	}
+	return secret, nil
+}
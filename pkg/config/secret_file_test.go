@@ -0,0 +1,98 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestResolveCredential(t *testing.T) {
+	os.Setenv("FRENO_TEST_SECRET", "penguin")
+	defer os.Unsetenv("FRENO_TEST_SECRET")
+
+	test.S(t).ExpectEquals(resolveCredential("gromit"), "gromit")
+	test.S(t).ExpectEquals(resolveCredential("${FRENO_TEST_SECRET}"), "penguin")
+	test.S(t).ExpectEquals(resolveCredential("${file:/etc/freno/secret}"), "${file:/etc/freno/secret}")
+	test.S(t).ExpectEquals(getSecretFilePath("${file:/etc/freno/secret}"), "/etc/freno/secret")
+	test.S(t).ExpectEquals(getSecretFilePath("${FRENO_TEST_SECRET}"), "")
+}
+
+func TestResolveSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	test.S(t).ExpectNil(os.WriteFile(path, []byte("penguin\n"), 0600))
+
+	reference := "${file:" + path + "}"
+	secret, err := ResolveSecret(reference)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(secret, "penguin")
+
+	// rotate
+	test.S(t).ExpectNil(os.WriteFile(path, []byte("wallace\n"), 0600))
+	modTime := time.Now().Add(time.Minute)
+	test.S(t).ExpectNil(os.Chtimes(path, modTime, modTime))
+
+	// file is not checked again within secretFileCheckInterval
+	secret, err = ResolveSecret(reference)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(secret, "penguin")
+
+	secretFilesMutex.Lock()
+	secretFiles[path].checkedAt = time.Time{}
+	secretFilesMutex.Unlock()
+	secret, err = ResolveSecret(reference)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(secret, "wallace")
+
+	// a file gone missing retains the last known secret
+	test.S(t).ExpectNil(os.Remove(path))
+	secretFilesMutex.Lock()
+	secretFiles[path].checkedAt = time.Time{}
+	secretFilesMutex.Unlock()
+	secret, err = ResolveSecret(reference)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(secret, "wallace")
+
+	_, err = ResolveSecret("${file:/path/does/not/exist}")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestMySQLClusterCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	test.S(t).ExpectNil(os.WriteFile(path, []byte("penguin"), 0600))
+	{
+		settings := &MySQLConfigurationSettings{
+			User:     "gromit",
+			Password: "${file:" + path + "}",
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"inherits": {},
+				"custom":   {User: "wallace", Password: "cheese"},
+			},
+		}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		user, password, err := settings.Clusters["inherits"].Credentials()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(user, "gromit")
+		test.S(t).ExpectEquals(password, "penguin")
+
+		user, password, err = settings.Clusters["custom"].Credentials()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(user, "wallace")
+		test.S(t).ExpectEquals(password, "cheese")
+	}
+	{
+		settings := &MySQLConfigurationSettings{
+			Clusters: map[string](*MySQLClusterConfigurationSettings){
+				"missing": {Password: "${file:/path/does/not/exist}"},
+			},
+		}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+}
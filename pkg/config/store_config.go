@@ -4,10 +4,101 @@ package config
 // General-store configuration
 //
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
 type StoresSettings struct {
-	MySQL MySQLConfigurationSettings // Any and all MySQL setups go here
+	MySQL      MySQLConfigurationSettings      // Any and all MySQL setups go here
+	PostgreSQL PostgreSQLConfigurationSettings // Any and all PostgreSQL setups go here
+	Redis      RedisConfigurationSettings      // Any and all Redis setups go here
+	HTTP       HTTPStoreConfigurationSettings  // Metrics read off HTTP endpoints
+	Prometheus PrometheusConfigurationSettings // Metrics scraped off Prometheus exporters
+
+	// Futuristic stores register their own section via RegisterStoreSettings, rather than adding a field here.
+	registered map[string]StoreSettings
+}
+
+// StoreSettings is the config section of a store registered via RegisterStoreSettings
+type StoreSettings interface {
+	// PostReadAdjustments validates the section and applies defaults, e.g. clusters inheriting the store's settings
+	PostReadAdjustments() error
+}
+
+var storeSettingsFactories = make(map[string](func() StoreSettings))
+var storeSettingsFactoriesMutex sync.Mutex
+
+// RegisterStoreSettings makes a store's config section, as read off Stores.<name>, available via
+// StoresSettings.Registered. It panics if called twice with the same name, or with the name of a built in
+// section. Drivers register their section in their package's init(), ahead of reading the configuration.
+func RegisterStoreSettings(name string, newSettings func() StoreSettings) {
+	storeSettingsFactoriesMutex.Lock()
+	defer storeSettingsFactoriesMutex.Unlock()
+
+	for _, field := range []string{"MySQL", "PostgreSQL", "Redis", "HTTP", "Prometheus"} {
+		if strings.EqualFold(name, field) {
+			panic(fmt.Sprintf("config: RegisterStoreSettings called with built in section %s", name))
+		}
+	}
+	for registeredName := range storeSettingsFactories {
+		if strings.EqualFold(name, registeredName) {
+			panic(fmt.Sprintf("config: RegisterStoreSettings called twice for section %s", name))
+		}
+	}
+	storeSettingsFactories[name] = newSettings
+}
+
+// registeredStoreSettingsName returns the registered section matching a JSON key, which, as with struct fields,
+// is case insensitive
+func registeredStoreSettingsName(key string) (name string, newSettings func() StoreSettings, found bool) {
+	storeSettingsFactoriesMutex.Lock()
+	defer storeSettingsFactoriesMutex.Unlock()
+
+	for name, newSettings := range storeSettingsFactories {
+		if strings.EqualFold(key, name) {
+			return name, newSettings, true
+		}
+	}
+	return "", nil, false
+}
+
+// UnmarshalJSON reads the built in sections, then registered ones. As with built in sections, a registered section
+// read off several configuration files is merged.
+func (settings *StoresSettings) UnmarshalJSON(data []byte) error {
+	type builtInStoresSettings StoresSettings
+	if err := json.Unmarshal(data, (*builtInStoresSettings)(settings)); err != nil {
+		return err
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return err
+	}
+	for key, section := range sections {
+		name, newSettings, found := registeredStoreSettingsName(key)
+		if !found {
+			continue
+		}
+		if settings.registered == nil {
+			settings.registered = make(map[string]StoreSettings)
+		}
+		if _, found := settings.registered[name]; !found {
+			settings.registered[name] = newSettings()
+		}
+		if err := json.Unmarshal(section, settings.registered[name]); err != nil {
+			return fmt.Errorf("Cannot read Stores.%s: %+v", name, err)
+		}
+	}
+	return nil
+}
 
-	// Futuristic stores can come here.
+// Registered returns the section of given name, as registered via RegisterStoreSettings, or nil if there is no
+// such registered section. A registered section absent from the configuration is returned with its defaults.
+func (settings *StoresSettings) Registered(name string) StoreSettings {
+	return settings.registered[name]
 }
 
 // Hook to implement adjustments after reading each configuration file.
@@ -15,5 +106,37 @@ func (settings *StoresSettings) postReadAdjustments() error {
 	if err := settings.MySQL.postReadAdjustments(); err != nil {
 		return err
 	}
+	if err := settings.PostgreSQL.postReadAdjustments(); err != nil {
+		return err
+	}
+	if err := settings.Redis.postReadAdjustments(); err != nil {
+		return err
+	}
+	if err := settings.HTTP.postReadAdjustments(); err != nil {
+		return err
+	}
+	if err := settings.Prometheus.postReadAdjustments(); err != nil {
+		return err
+	}
+
+	storeSettingsFactoriesMutex.Lock()
+	defer storeSettingsFactoriesMutex.Unlock()
+
+	if settings.registered == nil {
+		settings.registered = make(map[string]StoreSettings)
+	}
+	names := []string{}
+	for name, newSettings := range storeSettingsFactories {
+		if _, found := settings.registered[name]; !found {
+			settings.registered[name] = newSettings()
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := settings.registered[name].PostReadAdjustments(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,78 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+type exampleClusterSettings struct {
+	ThrottleThreshold float64
+}
+
+type exampleStoreSettings struct {
+	ThrottleThreshold float64
+	Clusters          map[string](*exampleClusterSettings)
+}
+
+func (settings *exampleStoreSettings) PostReadAdjustments() error {
+	for clusterName, clusterSettings := range settings.Clusters {
+		if clusterSettings.ThrottleThreshold < 0 {
+			return fmt.Errorf("Invalid ThrottleThreshold for example cluster %s", clusterName)
+		}
+		if clusterSettings.ThrottleThreshold == 0 {
+			clusterSettings.ThrottleThreshold = settings.ThrottleThreshold
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterStoreSettings("Example", func() StoreSettings { return &exampleStoreSettings{} })
+}
+
+func TestRegisteredStoreSettings(t *testing.T) {
+	ioutil.WriteFile("/tmp/TestRegisteredStoreSettings1.json", []byte(`{"RaftDataDir": "/tmp", "Stores": {"example": {"ThrottleThreshold": 1, "Clusters": {"c0": {}}}}}`), 0644)
+	ioutil.WriteFile("/tmp/TestRegisteredStoreSettings2.json", []byte(`{"Stores": {"Example": {"Clusters": {"c1": {"ThrottleThreshold": 2}}}}}`), 0644)
+	ioutil.WriteFile("/tmp/TestRegisteredStoreSettings3.json", []byte(`{"Stores": {"Example": {"Clusters": {"c2": {"ThrottleThreshold": -1}}}}}`), 0644)
+	ioutil.WriteFile("/tmp/TestRegisteredStoreSettings4.json", []byte(`{"RaftDataDir": "/tmp"}`), 0644)
+	{
+		// sections are merged across files, then adjusted
+		config := createConfiguration()
+		test.S(t).ExpectNil(config.Read("/tmp/TestRegisteredStoreSettings1.json", "/tmp/TestRegisteredStoreSettings2.json"))
+		settings, ok := config.settings.Stores.Registered("Example").(*exampleStoreSettings)
+		test.S(t).ExpectTrue(ok)
+		test.S(t).ExpectEquals(len(settings.Clusters), 2)
+		test.S(t).ExpectEquals(settings.Clusters["c0"].ThrottleThreshold, 1.0)
+		test.S(t).ExpectEquals(settings.Clusters["c1"].ThrottleThreshold, 2.0)
+		test.S(t).ExpectTrue(config.settings.Stores.Registered("no-such-store") == nil)
+	}
+	{
+		config := createConfiguration()
+		test.S(t).ExpectNotNil(config.Read("/tmp/TestRegisteredStoreSettings1.json", "/tmp/TestRegisteredStoreSettings3.json"))
+	}
+	{
+		// an absent section has its defaults
+		config := createConfiguration()
+		test.S(t).ExpectNil(config.Read("/tmp/TestRegisteredStoreSettings4.json"))
+		settings, ok := config.settings.Stores.Registered("Example").(*exampleStoreSettings)
+		test.S(t).ExpectTrue(ok)
+		test.S(t).ExpectEquals(len(settings.Clusters), 0)
+	}
+}
+
+func TestRegisterStoreSettingsTwice(t *testing.T) {
+	for _, name := range []string{"example", "redis"} {
+		func() {
+			defer func() { test.S(t).ExpectNotNil(recover()) }()
+			RegisterStoreSettings(name, func() StoreSettings { return &exampleStoreSettings{} })
+		}()
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+//
+// TLS configuration for MySQL connections: probes, backend and ProxySQL
+//
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type TLSConfigurationSettings struct {
+	Enabled    bool   // connect via TLS, verifying the server against the system CA pool. Implied by any of the below
+	CAFile     string // PEM encoded CA bundle to verify the server against
+	CertFile   string // PEM encoded client certificate. Requires KeyFile
+	KeyFile    string // PEM encoded client private key. Requires CertFile
+	ServerName string // expected server name. Default: the hostname connected to
+	SkipVerify bool   // do not verify the server's certificate
+}
+
+func (settings *TLSConfigurationSettings) IsEmpty() bool {
+	if settings == nil {
+		return true
+	}
+	return *settings == TLSConfigurationSettings{}
+}
+
+// inherit applies given (global) settings onto these settings, unless these settings are explicitly set.
+// TLS settings are inherited as a whole, never field by field.
+func (settings *TLSConfigurationSettings) inherit(other *TLSConfigurationSettings) {
+	if settings.IsEmpty() {
+		*settings = *other
+	}
+}
+
+// ConfigName returns the name under which these settings are registered with the MySQL driver.
+// The name is derived from the settings, so that identical settings share a registration.
+func (settings *TLSConfigurationSettings) ConfigName() string {
+	if settings.IsEmpty() {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%+v", *settings)))
+	return fmt.Sprintf("freno-%s", hex.EncodeToString(hash[:])[:16])
+}
+
+// DSNParams returns the DSN parameters, if any, by which a connection uses these settings
+func (settings *TLSConfigurationSettings) DSNParams() string {
+	if settings.IsEmpty() {
+		return ""
+	}
+	return fmt.Sprintf("&tls=%s", settings.ConfigName())
+}
+
+// TLSConfig builds the tls.Config described by these settings
+func (settings *TLSConfigurationSettings) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         settings.ServerName,
+		InsecureSkipVerify: settings.SkipVerify,
+	}
+	if settings.CAFile != "" {
+		caBundle, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("TLSSettings: no certificates found in %s", settings.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if settings.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// Hook to implement adjustments after reading each configuration file. Validates the settings, loads
+// certificates and registers the resulting TLS config with the MySQL driver.
+func (settings *TLSConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if (settings.CertFile == "") != (settings.KeyFile == "") {
+		return fmt.Errorf("TLSSettings: CertFile and KeyFile must be provided together")
+	}
+	tlsConfig, err := settings.TLSConfig()
+	if err != nil {
+		return err
+	}
+	return mysql.RegisterTLSConfig(settings.ConfigName(), tlsConfig)
+}
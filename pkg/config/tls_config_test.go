@@ -0,0 +1,110 @@
+/*
+   Copyright 2019 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// writeTestCertificate writes a self signed certificate and its key into given directory
+func writeTestCertificate(t *testing.T, dir string) (certFile string, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.S(t).ExpectNil(err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "freno-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	test.S(t).ExpectNil(err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	test.S(t).ExpectNil(err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	test.S(t).ExpectNil(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	test.S(t).ExpectNil(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestTLSConfigurationSettings(t *testing.T) {
+	{
+		settings := &TLSConfigurationSettings{}
+		test.S(t).ExpectTrue(settings.IsEmpty())
+		test.S(t).ExpectEquals(settings.ConfigName(), "")
+		test.S(t).ExpectEquals(settings.DSNParams(), "")
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+	}
+	{
+		var settings *TLSConfigurationSettings
+		test.S(t).ExpectTrue(settings.IsEmpty())
+		test.S(t).ExpectEquals(settings.DSNParams(), "")
+	}
+	{
+		settings := &TLSConfigurationSettings{SkipVerify: true}
+		test.S(t).ExpectFalse(settings.IsEmpty())
+		test.S(t).ExpectTrue(strings.HasPrefix(settings.ConfigName(), "freno-"))
+		test.S(t).ExpectEquals(settings.DSNParams(), "&tls="+settings.ConfigName())
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+
+		other := &TLSConfigurationSettings{SkipVerify: true, ServerName: "mysql.example.com"}
+		test.S(t).ExpectNotEquals(settings.ConfigName(), other.ConfigName())
+	}
+	{
+		settings := &TLSConfigurationSettings{CertFile: "/tmp/cert.pem"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		settings := &TLSConfigurationSettings{CAFile: "/path/does/not/exist.pem"}
+		test.S(t).ExpectNotNil(settings.postReadAdjustments())
+	}
+	{
+		certFile, keyFile := writeTestCertificate(t, t.TempDir())
+		settings := &TLSConfigurationSettings{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "mysql.example.com"}
+		test.S(t).ExpectNil(settings.postReadAdjustments())
+		tlsConfig, err := settings.TLSConfig()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectNotNil(tlsConfig.RootCAs)
+		test.S(t).ExpectEquals(len(tlsConfig.Certificates), 1)
+		test.S(t).ExpectEquals(tlsConfig.ServerName, "mysql.example.com")
+		test.S(t).ExpectFalse(tlsConfig.InsecureSkipVerify)
+	}
+}
+
+func TestTLSConfigurationSettingsInheritance(t *testing.T) {
+	settings := &MySQLConfigurationSettings{
+		TLSSettings:         TLSConfigurationSettings{Enabled: true},
+		ProxySQLTLSSettings: TLSConfigurationSettings{SkipVerify: true},
+		Clusters: map[string](*MySQLClusterConfigurationSettings){
+			"inherits": {},
+			"custom":   {TLSSettings: TLSConfigurationSettings{SkipVerify: true, ServerName: "custom.example.com"}},
+			"proxysql": {ProxySQLSettings: ProxySQLConfigurationSettings{Addresses: []string{"proxysql:6032"}, User: "u", Password: "p", HostgroupID: 10}},
+		},
+	}
+	test.S(t).ExpectNil(settings.postReadAdjustments())
+	test.S(t).ExpectTrue(settings.Clusters["inherits"].TLSSettings.Enabled)
+	test.S(t).ExpectFalse(settings.Clusters["custom"].TLSSettings.Enabled)
+	test.S(t).ExpectEquals(settings.Clusters["custom"].TLSSettings.ServerName, "custom.example.com")
+	test.S(t).ExpectTrue(settings.Clusters["proxysql"].ProxySQLSettings.TLSSettings.SkipVerify)
+	test.S(t).ExpectTrue(settings.Clusters["inherits"].ProxySQLSettings.TLSSettings.IsEmpty())
+}
@@ -0,0 +1,29 @@
+package config
+
+//
+// Topology crawl hosts configuration: replicas found by walking replication downwards from the primary
+//
+
+const DefaultTopologyMaxDepth = 3
+
+type TopologyConfigurationSettings struct {
+	SeedHosts          []string // primary (or any server) to crawl replicas from; the first responsive one is used. A host can be "hostname" or "hostname:port"
+	MaxDepth           int      // levels of replicas to crawl below the seed host. Default: 3
+	IncludeSeed        bool     // if true, probe the seed host along with its replicas. Default: only replicas
+	ProcesslistThreads bool     // if true, also discover replicas by their binlog dump threads, for replicas not setting report_host. These are probed on the cluster's Port
+}
+
+func (settings *TopologyConfigurationSettings) IsEmpty() bool {
+	return len(settings.SeedHosts) == 0
+}
+
+// Hook to implement adjustments after reading each configuration file.
+func (settings *TopologyConfigurationSettings) postReadAdjustments() error {
+	if settings.IsEmpty() {
+		return nil
+	}
+	if settings.MaxDepth <= 0 {
+		settings.MaxDepth = DefaultTopologyMaxDepth
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+)
+
+// watchErrorBackoff is the pause between a failed blocking query and the next one
+const watchErrorBackoff = 1 * time.Second
+
+// httpClient is shared by all reads, so that connections to Consul are reused across blocking queries.
+// Each request has its own deadline, which depends on whether it blocks.
+var httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+
+// ServiceEntry is a healthy instance of a service, as listed by /v1/health/service/<service>
+type ServiceEntry struct {
+	Node struct {
+		Node    string
+		Address string
+	}
+	Service struct {
+		ID      string
+		Service string
+		Tags    []string
+		Address string
+		Port    int
+	}
+}
+
+// HostPort returns the address of the service instance, falling back to its node's address
+func (entry *ServiceEntry) HostPort() config.HostPort {
+	hostPort := config.HostPort{Host: entry.Service.Address, Port: entry.Service.Port}
+	if hostPort.Host == "" {
+		hostPort.Host = entry.Node.Address
+	}
+	return hostPort
+}
+
+func constructHealthURL(settings *config.ConsulConfigurationSettings, index uint64) string {
+	queryParams := url.Values{}
+	queryParams.Add("passing", "true")
+	for _, tag := range settings.Tags {
+		queryParams.Add("tag", tag)
+	}
+	if settings.Datacenter != "" {
+		queryParams.Add("dc", settings.Datacenter)
+	}
+	if index > 0 {
+		queryParams.Add("index", strconv.FormatUint(index, 10))
+		queryParams.Add("wait", fmt.Sprintf("%ds", settings.WaitSeconds))
+	}
+	return fmt.Sprintf("%s/v1/health/service/%s?%s", strings.TrimRight(settings.Address, "/"), url.PathEscape(settings.Service), queryParams.Encode())
+}
+
+// readHealthyHosts lists the hosts of a service's instances which pass their health checks. With a non-zero
+// index, this is a blocking query, which returns once the service changes or WaitSeconds elapse.
+func readHealthyHosts(settings *config.ConsulConfigurationSettings, index uint64) (hosts []config.HostPort, lastIndex uint64, err error) {
+	timeout := time.Duration(settings.TimeoutMillis) * time.Millisecond
+	if index > 0 {
+		// Consul adds up to wait/16 of jitter to blocking queries
+		timeout += time.Duration(settings.WaitSeconds) * time.Second * 17 / 16
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, constructHealthURL(settings, index), nil)
+	if err != nil {
+		return hosts, lastIndex, err
+	}
+	token, err := config.ResolveSecret(settings.Token)
+	if err != nil {
+		return hosts, lastIndex, err
+	}
+	if token != "" {
+		request.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return hosts, lastIndex, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return hosts, lastIndex, fmt.Errorf("%v", resp.Status)
+	}
+	var entries []ServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return hosts, lastIndex, err
+	}
+	for _, entry := range entries {
+		hosts = append(hosts, entry.HostPort())
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].String() < hosts[j].String() })
+	if lastIndex, err = strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64); err != nil {
+		// Not a blocking-query capable endpoint. Never mind, we just won't block.
+		lastIndex = 0
+	}
+	return hosts, lastIndex, nil
+}
+
+// watcher keeps the latest hosts of a service by way of blocking queries
+type watcher struct {
+	settings config.ConsulConfigurationSettings
+	onChange func()
+	hosts    []config.HostPort
+	err      error
+	index    uint64
+	mutex    sync.Mutex
+}
+
+var watchers = base.NewWatcherRegistry()
+
+// WatcherKey identifies the watcher of given settings: clusters of the same key share a watcher
+func WatcherKey(settings *config.ConsulConfigurationSettings) string {
+	return fmt.Sprintf("%s|%s|%d", constructHealthURL(settings, 0), settings.Token, settings.WaitSeconds)
+}
+
+// getWatcher returns the watcher of given service, starting one with an initial read if there is none
+func getWatcher(settings *config.ConsulConfigurationSettings, onChange func()) *watcher {
+	return watchers.Get(WatcherKey(settings), func() base.Watcher {
+		w := &watcher{settings: *settings, onChange: onChange}
+		w.hosts, w.index, w.err = readHealthyHosts(&w.settings, 0)
+		return w
+	}).(*watcher)
+}
+
+func (w *watcher) read() ([]config.HostPort, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.hosts, w.err
+}
+
+// Watch runs blocking queries until the watcher is idle
+func (w *watcher) Watch(isIdle func() bool) {
+	for !isIdle() {
+		w.mutex.Lock()
+		index := w.index
+		w.mutex.Unlock()
+
+		hosts, lastIndex, err := readHealthyHosts(&w.settings, index)
+		if err != nil {
+			log.Errorf("Consul blocking query on service %s failed: %+v", w.settings.Service, err)
+		}
+		w.mutex.Lock()
+		changed := err == nil && !reflect.DeepEqual(hosts, w.hosts)
+		if err == nil {
+			w.hosts = hosts
+		}
+		w.err = err
+		if lastIndex < w.index {
+			// The index went backwards, e.g. on a restored snapshot. Consul's advice is to start over.
+			lastIndex = 0
+		}
+		w.index = lastIndex
+		w.mutex.Unlock()
+
+		if changed && w.onChange != nil {
+			w.onChange()
+		}
+		if err != nil || lastIndex == 0 {
+			time.Sleep(watchErrorBackoff)
+		}
+	}
+}
+
+// ReadHosts lists the hosts of a service's healthy instances. Instances which do not register a port have a
+// zero Port. With BlockingQueries, the service is watched in the background, and onChange is called whenever
+// its hosts change.
+func ReadHosts(settings *config.ConsulConfigurationSettings, onChange func()) (hosts []config.HostPort, err error) {
+	if !settings.BlockingQueries {
+		hosts, _, err = readHealthyHosts(settings, 0)
+		return hosts, err
+	}
+	return getWatcher(settings, onChange).read()
+}
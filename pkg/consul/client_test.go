@@ -0,0 +1,170 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func newServiceEntry(nodeAddress string, serviceAddress string, port int) ServiceEntry {
+	var entry ServiceEntry
+	entry.Node.Node = nodeAddress
+	entry.Node.Address = nodeAddress
+	entry.Service.Service = "mysql"
+	entry.Service.Address = serviceAddress
+	entry.Service.Port = port
+	return entry
+}
+
+func TestConstructHealthURL(t *testing.T) {
+	settings := &config.ConsulConfigurationSettings{
+		Address:     "http://consul.example.com:8500/",
+		Service:     "mysql",
+		Tags:        []string{"prod4", "replica"},
+		Datacenter:  "dc1",
+		WaitSeconds: 30,
+	}
+	test.S(t).ExpectEquals(constructHealthURL(settings, 0), "http://consul.example.com:8500/v1/health/service/mysql?dc=dc1&passing=true&tag=prod4&tag=replica")
+	test.S(t).ExpectEquals(constructHealthURL(settings, 7), "http://consul.example.com:8500/v1/health/service/mysql?dc=dc1&index=7&passing=true&tag=prod4&tag=replica&wait=30s")
+}
+
+func TestReadHosts(t *testing.T) {
+	consulAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/mysql" || r.URL.Query().Get("tag") != "prod4" || r.URL.Query().Get("passing") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Consul-Index", "12")
+		json.NewEncoder(w).Encode([]ServiceEntry{
+			newServiceEntry("10.0.0.2", "", 3307),
+			newServiceEntry("10.0.0.1", "replica1.example.com", 0),
+		})
+	}))
+	defer consulAPI.Close()
+
+	settings := &config.ConsulConfigurationSettings{
+		Address:       consulAPI.URL,
+		Token:         "s3cr3t",
+		Service:       "mysql",
+		Tags:          []string{"prod4"},
+		TimeoutMillis: 1000,
+		WaitSeconds:   1,
+	}
+	{
+		hosts, err := ReadHosts(settings, nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 2)
+		test.S(t).ExpectEquals(hosts[0], config.HostPort{Host: "10.0.0.2", Port: 3307})
+		test.S(t).ExpectEquals(hosts[1], config.HostPort{Host: "replica1.example.com", Port: 0})
+	}
+	{
+		settings.Token = ""
+		_, err := ReadHosts(settings, nil)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		settings.Token = "s3cr3t"
+		settings.Service = "postgres"
+		_, err := ReadHosts(settings, nil)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadHostsBlockingQueries(t *testing.T) {
+	var index int64 = 1
+	changeHosts := make(chan bool)
+	consulAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") != "" {
+			select {
+			case <-changeHosts:
+				atomic.StoreInt64(&index, 2)
+			case <-time.After(time.Second):
+			}
+		}
+		w.Header().Set("X-Consul-Index", fmt.Sprintf("%d", atomic.LoadInt64(&index)))
+		entries := []ServiceEntry{newServiceEntry("10.0.0.1", "", 3306)}
+		if atomic.LoadInt64(&index) > 1 {
+			entries = append(entries, newServiceEntry("10.0.0.2", "", 3306))
+		}
+		json.NewEncoder(w).Encode(entries)
+	}))
+	defer consulAPI.Close()
+
+	settings := &config.ConsulConfigurationSettings{
+		Address:         consulAPI.URL,
+		Service:         "mysql",
+		TimeoutMillis:   1000,
+		WaitSeconds:     1,
+		BlockingQueries: true,
+	}
+	changed := make(chan bool, 1)
+	onChange := func() { changed <- true }
+	{
+		hosts, err := ReadHosts(settings, onChange)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 1)
+	}
+	changeHosts <- true
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a change notification")
+	}
+	{
+		hosts, err := ReadHosts(settings, onChange)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 2)
+	}
+}
+
+func TestReadHostsBlockingQueriesSlowInitialRead(t *testing.T) {
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	consulAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") == "" && r.URL.Path == "/v1/health/service/slow" {
+			started <- true
+			<-release
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		json.NewEncoder(w).Encode([]ServiceEntry{newServiceEntry("10.0.0.1", "", 3306)})
+	}))
+	defer consulAPI.Close()
+
+	newSettings := func(service string) *config.ConsulConfigurationSettings {
+		return &config.ConsulConfigurationSettings{
+			Address:         consulAPI.URL,
+			Service:         service,
+			TimeoutMillis:   5000,
+			WaitSeconds:     1,
+			BlockingQueries: true,
+		}
+	}
+	go ReadHosts(newSettings("slow"), nil)
+	<-started
+	defer close(release)
+
+	// the slow service's initial read does not hold up another service
+	done := make(chan error, 1)
+	go func() {
+		_, err := ReadHosts(newSettings("fast"), nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		test.S(t).ExpectNil(err)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected reading a service not to wait on another service's initial read")
+	}
+}
@@ -0,0 +1,188 @@
+package dns
+
+//
+// A minimal DNS message codec: just enough to ask a question and read the A & SRV records of the answer
+//
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	typeA   uint16 = 1
+	typeSRV uint16 = 33
+	typeOPT uint16 = 41
+	classIN uint16 = 1
+)
+
+const (
+	rcodeSuccess        = 0
+	rcodeServerFailure  = 2
+	rcodeNameError      = 3
+	flagResponse        = 1 << 15
+	flagTruncated       = 1 << 9
+	flagRecursionDesire = 1 << 8
+)
+
+const headerLen = 12
+
+// maxUDPPayload is advertised via EDNS0, so that large SRV sets need not be re-queried over TCP
+const maxUDPPayload = 4096
+
+var errShortMessage = errors.New("short DNS message")
+
+// record is a resource record of an answer
+type record struct {
+	rrType uint16
+	ttl    uint32
+	host   string // A: the address; SRV: the target
+	port   int    // SRV only
+}
+
+// response is a decoded DNS response
+type response struct {
+	id        uint16
+	rcode     int
+	truncated bool
+	answers   []record
+}
+
+func appendName(message []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return message, fmt.Errorf("invalid DNS name: %s", name)
+			}
+			message = append(message, byte(len(label)))
+			message = append(message, label...)
+		}
+	}
+	return append(message, 0), nil
+}
+
+// encodeQuery builds a recursive query for given name & record type
+func encodeQuery(id uint16, name string, rrType uint16) ([]byte, error) {
+	message := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(message[0:], id)
+	binary.BigEndian.PutUint16(message[2:], flagRecursionDesire)
+	binary.BigEndian.PutUint16(message[4:], 1)  // questions
+	binary.BigEndian.PutUint16(message[10:], 1) // additional: EDNS0 OPT
+
+	message, err := appendName(message, name)
+	if err != nil {
+		return message, err
+	}
+	message = binary.BigEndian.AppendUint16(message, rrType)
+	message = binary.BigEndian.AppendUint16(message, classIN)
+
+	// OPT pseudo record: root name, type, UDP payload size as class, zero extended rcode & flags, no data
+	message = append(message, 0)
+	message = binary.BigEndian.AppendUint16(message, typeOPT)
+	message = binary.BigEndian.AppendUint16(message, maxUDPPayload)
+	message = binary.BigEndian.AppendUint32(message, 0)
+	message = binary.BigEndian.AppendUint16(message, 0)
+	return message, nil
+}
+
+// readName reads a possibly compressed name at given offset, and returns it along with the offset following it
+func readName(message []byte, offset int) (name string, next int, err error) {
+	var labels []string
+	next = -1
+	for jumps := 0; ; {
+		if offset >= len(message) {
+			return name, next, errShortMessage
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(message) {
+				return name, next, errShortMessage
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			if jumps++; jumps > 64 {
+				return name, next, fmt.Errorf("DNS name compression loop")
+			}
+			offset = int(binary.BigEndian.Uint16(message[offset:]) & 0x3FFF)
+		default:
+			if offset+1+length > len(message) {
+				return name, next, errShortMessage
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// decodeResponse reads the header and the answer section of a response
+func decodeResponse(message []byte) (resp *response, err error) {
+	if len(message) < headerLen {
+		return nil, errShortMessage
+	}
+	flags := binary.BigEndian.Uint16(message[2:])
+	if flags&flagResponse == 0 {
+		return nil, fmt.Errorf("DNS message is not a response")
+	}
+	resp = &response{
+		id:        binary.BigEndian.Uint16(message[0:]),
+		rcode:     int(flags & 0xF),
+		truncated: flags&flagTruncated != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	answers := int(binary.BigEndian.Uint16(message[6:]))
+
+	offset := headerLen
+	for i := 0; i < questions; i++ {
+		if _, offset, err = readName(message, offset); err != nil {
+			return resp, err
+		}
+		offset += 4
+	}
+	for i := 0; i < answers; i++ {
+		if _, offset, err = readName(message, offset); err != nil {
+			return resp, err
+		}
+		if offset+10 > len(message) {
+			return resp, errShortMessage
+		}
+		rrType := binary.BigEndian.Uint16(message[offset:])
+		ttl := binary.BigEndian.Uint32(message[offset+4:])
+		dataLen := int(binary.BigEndian.Uint16(message[offset+8:]))
+		offset += 10
+		if offset+dataLen > len(message) {
+			return resp, errShortMessage
+		}
+		data := message[offset : offset+dataLen]
+		switch rrType {
+		case typeA:
+			if dataLen != net.IPv4len {
+				return resp, fmt.Errorf("invalid A record length: %d", dataLen)
+			}
+			resp.answers = append(resp.answers, record{rrType: rrType, ttl: ttl, host: net.IP(data).String()})
+		case typeSRV:
+			if dataLen < 7 {
+				return resp, fmt.Errorf("invalid SRV record length: %d", dataLen)
+			}
+			// priority & weight are of no interest; all targets are probed
+			target, _, err := readName(message, offset+6)
+			if err != nil {
+				return resp, err
+			}
+			port := int(binary.BigEndian.Uint16(data[4:]))
+			resp.answers = append(resp.answers, record{rrType: rrType, ttl: ttl, host: target, port: port})
+		}
+		offset += dataLen
+	}
+	return resp, nil
+}
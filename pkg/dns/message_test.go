@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// encodeResponse builds a response to given query, with given records as answers
+func encodeResponse(query []byte, rcode int, truncated bool, answers []record) []byte {
+	_, questionEnd, _ := readName(query, headerLen)
+	questionEnd += 4
+
+	message := make([]byte, headerLen)
+	copy(message, query[:2])
+	flags := uint16(flagResponse|flagRecursionDesire) | uint16(rcode)
+	if truncated {
+		flags |= flagTruncated
+	}
+	binary.BigEndian.PutUint16(message[2:], flags)
+	binary.BigEndian.PutUint16(message[4:], 1)
+	binary.BigEndian.PutUint16(message[6:], uint16(len(answers)))
+	message = append(message, query[headerLen:questionEnd]...)
+	for _, answer := range answers {
+		var data []byte
+		switch answer.rrType {
+		case typeA:
+			data = net.ParseIP(answer.host).To4()
+		case typeSRV:
+			data = binary.BigEndian.AppendUint16(data, 10) // priority
+			data = binary.BigEndian.AppendUint16(data, 5)  // weight
+			data = binary.BigEndian.AppendUint16(data, uint16(answer.port))
+			data, _ = appendName(data, answer.host)
+		}
+		message = binary.BigEndian.AppendUint16(message, 0xC000|headerLen) // pointer to the question's name
+		message = binary.BigEndian.AppendUint16(message, answer.rrType)
+		message = binary.BigEndian.AppendUint16(message, classIN)
+		message = binary.BigEndian.AppendUint32(message, answer.ttl)
+		message = binary.BigEndian.AppendUint16(message, uint16(len(data)))
+		message = append(message, data...)
+	}
+	return message
+}
+
+func TestEncodeQuery(t *testing.T) {
+	query, err := encodeQuery(0x1234, "_mysql._tcp.example.com.", typeSRV)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(binary.BigEndian.Uint16(query), uint16(0x1234))
+	name, next, err := readName(query, headerLen)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(name, "_mysql._tcp.example.com")
+	test.S(t).ExpectEquals(binary.BigEndian.Uint16(query[next:]), typeSRV)
+
+	_, err = encodeQuery(0x1234, "bad..name", typeA)
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestDecodeResponse(t *testing.T) {
+	query, _ := encodeQuery(7, "_mysql._tcp.example.com", typeSRV)
+	{
+		message := encodeResponse(query, rcodeSuccess, false, []record{
+			{rrType: typeSRV, ttl: 30, host: "db1.example.com", port: 3306},
+			{rrType: typeSRV, ttl: 20, host: "db2.example.com", port: 3307},
+		})
+		resp, err := decodeResponse(message)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(resp.id, uint16(7))
+		test.S(t).ExpectEquals(resp.rcode, rcodeSuccess)
+		test.S(t).ExpectFalse(resp.truncated)
+		test.S(t).ExpectEquals(len(resp.answers), 2)
+		test.S(t).ExpectEquals(resp.answers[0], record{rrType: typeSRV, ttl: 30, host: "db1.example.com", port: 3306})
+		test.S(t).ExpectEquals(resp.answers[1].port, 3307)
+
+		_, err = decodeResponse(message[:len(message)-3])
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		message := encodeResponse(query, rcodeNameError, true, nil)
+		resp, err := decodeResponse(message)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(resp.rcode, rcodeNameError)
+		test.S(t).ExpectTrue(resp.truncated)
+		test.S(t).ExpectEquals(len(resp.answers), 0)
+	}
+	{
+		_, err := decodeResponse(query)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadNameCompressionLoop(t *testing.T) {
+	message := make([]byte, headerLen)
+	message = append(message, 0xC0, headerLen)
+	_, _, err := readName(message, headerLen)
+	test.S(t).ExpectNotNil(err)
+}
@@ -0,0 +1,211 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+)
+
+const resolvConfPath = "/etc/resolv.conf"
+const defaultResolver = "127.0.0.1:53"
+
+// nameError indicates the name could not be resolved: NXDOMAIN, no records of the requested type, or SERVFAIL
+type nameError struct {
+	name   string
+	reason string
+}
+
+func (err *nameError) Error() string {
+	return fmt.Sprintf("%s: %s", err.name, err.reason)
+}
+
+// IsNameError returns true when given error indicates a name that does not resolve, as opposed to e.g. a timeout
+func IsNameError(err error) bool {
+	_, ok := err.(*nameError)
+	return ok
+}
+
+type cacheEntry struct {
+	hosts     []config.HostPort
+	expiresAt time.Time
+}
+
+var cache = make(map[string]cacheEntry)
+var cacheMutex sync.Mutex
+
+// readResolvConf returns the nameservers listed in given resolv.conf file
+func readResolvConf(path string) (servers []string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return servers
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return servers
+}
+
+func resolvers(settings *config.DNSConfigurationSettings) []string {
+	if settings.Resolver != "" {
+		return []string{settings.Resolver}
+	}
+	if servers := readResolvConf(resolvConfPath); len(servers) > 0 {
+		return servers
+	}
+	return []string{defaultResolver}
+}
+
+// exchangeOver sends a query to a server over given network ("udp" or "tcp"), and reads its response
+func exchangeOver(network string, server string, query []byte, timeout time.Duration) (*response, error) {
+	conn, err := net.DialTimeout(network, server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	id := binary.BigEndian.Uint16(query)
+
+	if network == "tcp" {
+		if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(query)))); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		message := make([]byte, length)
+		if _, err := io.ReadFull(conn, message); err != nil {
+			return nil, err
+		}
+		return decodeResponse(message)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, maxUDPPayload)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := decodeResponse(buffer[:n])
+		if err != nil || resp.id != id {
+			// Stray or garbled datagram; keep waiting for ours
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// exchange queries a server over UDP, and retries over TCP if the response is truncated
+func exchange(server string, query []byte, timeout time.Duration) (*response, error) {
+	resp, err := exchangeOver("udp", server, query, timeout)
+	if err == nil && resp.truncated {
+		resp, err = exchangeOver("tcp", server, query, timeout)
+	}
+	return resp, err
+}
+
+// lookup resolves the hosts of given settings, and returns them along with the time they may be cached for
+func lookup(settings *config.DNSConfigurationSettings) (hosts []config.HostPort, ttl time.Duration, err error) {
+	rrType := typeSRV
+	if settings.RecordType == config.DNSRecordTypeA {
+		rrType = typeA
+	}
+	query, err := encodeQuery(uint16(rand.Intn(1<<16)), settings.Name, rrType)
+	if err != nil {
+		return hosts, ttl, err
+	}
+	timeout := time.Duration(settings.TimeoutMillis) * time.Millisecond
+
+	for _, server := range resolvers(settings) {
+		var resp *response
+		resp, err = exchange(server, query, timeout)
+		if err != nil {
+			// try next server
+			continue
+		}
+		switch resp.rcode {
+		case rcodeSuccess:
+		case rcodeNameError:
+			return hosts, ttl, &nameError{name: settings.Name, reason: "NXDOMAIN"}
+		case rcodeServerFailure:
+			// try next server
+			err = &nameError{name: settings.Name, reason: fmt.Sprintf("SERVFAIL from %s", server)}
+			continue
+		default:
+			err = fmt.Errorf("%s: unexpected rcode %d from %s", settings.Name, resp.rcode, server)
+			continue
+		}
+		var minTTL uint32
+		for _, answer := range resp.answers {
+			if answer.rrType != rrType {
+				continue
+			}
+			hosts = append(hosts, config.HostPort{Host: answer.host, Port: answer.port})
+			if len(hosts) == 1 || answer.ttl < minTTL {
+				minTTL = answer.ttl
+			}
+		}
+		if len(hosts) == 0 {
+			return hosts, ttl, &nameError{name: settings.Name, reason: fmt.Sprintf("no %s records", settings.RecordType)}
+		}
+		sort.Slice(hosts, func(i, j int) bool { return hosts[i].String() < hosts[j].String() })
+		return hosts, time.Duration(minTTL) * time.Second, nil
+	}
+	return hosts, ttl, err
+}
+
+func cacheKey(settings *config.DNSConfigurationSettings) string {
+	return fmt.Sprintf("%s|%s|%s", settings.Resolver, settings.RecordType, settings.Name)
+}
+
+// ReadHosts resolves the hosts of SRV or A records. Results are cached for as long as the records' TTL allows.
+// Hosts of A records have a zero Port. When the name does not resolve and the settings' OnFailure is "fail",
+// no hosts are returned, with no error.
+func ReadHosts(settings *config.DNSConfigurationSettings) (hosts []config.HostPort, err error) {
+	key := cacheKey(settings)
+
+	cacheMutex.Lock()
+	entry, found := cache[key]
+	cacheMutex.Unlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		return entry.hosts, nil
+	}
+
+	hosts, ttl, err := lookup(settings)
+	if err != nil {
+		if IsNameError(err) && settings.OnFailure == config.DNSOnFailureFail {
+			log.Errorf("DNS name does not resolve; no hosts: %+v", err)
+			return nil, nil
+		}
+		return hosts, err
+	}
+	if ttl > 0 {
+		cacheMutex.Lock()
+		cache[key] = cacheEntry{hosts: hosts, expiresAt: time.Now().Add(ttl)}
+		cacheMutex.Unlock()
+	}
+	return hosts, nil
+}
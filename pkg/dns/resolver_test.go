@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// fakeServer answers queries over UDP & TCP on the same port, by way of a handler
+type fakeServer struct {
+	udp     net.PacketConn
+	tcp     net.Listener
+	queries int64
+	handler func(name string, rrType uint16, overTCP bool) (rcode int, truncated bool, answers []record)
+}
+
+func newFakeServer(t *testing.T, handler func(name string, rrType uint16, overTCP bool) (int, bool, []record)) *fakeServer {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeServer{udp: udp, tcp: tcp, handler: handler}
+	go server.serveUDP()
+	go server.serveTCP()
+	return server
+}
+
+func (server *fakeServer) Addr() string {
+	return server.udp.LocalAddr().String()
+}
+
+func (server *fakeServer) Close() {
+	server.udp.Close()
+	server.tcp.Close()
+}
+
+func (server *fakeServer) respond(query []byte, overTCP bool) []byte {
+	atomic.AddInt64(&server.queries, 1)
+	name, next, _ := readName(query, headerLen)
+	rrType := binary.BigEndian.Uint16(query[next:])
+	rcode, truncated, answers := server.handler(name, rrType, overTCP)
+	return encodeResponse(query, rcode, truncated, answers)
+}
+
+func (server *fakeServer) serveUDP() {
+	buffer := make([]byte, maxUDPPayload)
+	for {
+		n, addr, err := server.udp.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		server.udp.WriteTo(server.respond(buffer[:n], false), addr)
+	}
+}
+
+func (server *fakeServer) serveTCP() {
+	for {
+		conn, err := server.tcp.Accept()
+		if err != nil {
+			return
+		}
+		var length uint16
+		binary.Read(conn, binary.BigEndian, &length)
+		query := make([]byte, length)
+		io.ReadFull(conn, query)
+		message := server.respond(query, true)
+		conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(message))))
+		conn.Write(message)
+		conn.Close()
+	}
+}
+
+func newTestSettings(name string, recordType string, resolver string) *config.DNSConfigurationSettings {
+	return &config.DNSConfigurationSettings{
+		Name:          name,
+		RecordType:    recordType,
+		Resolver:      resolver,
+		TimeoutMillis: 1000,
+		OnFailure:     config.DNSOnFailureKeep,
+	}
+}
+
+func TestReadHostsSRV(t *testing.T) {
+	server := newFakeServer(t, func(name string, rrType uint16, overTCP bool) (int, bool, []record) {
+		if name != "_mysql._tcp.shard1.example.com" || rrType != typeSRV {
+			return rcodeNameError, false, nil
+		}
+		return rcodeSuccess, false, []record{
+			{rrType: typeSRV, ttl: 60, host: "db2.example.com", port: 3307},
+			{rrType: typeSRV, ttl: 30, host: "db1.example.com", port: 3306},
+		}
+	})
+	defer server.Close()
+
+	settings := newTestSettings("_mysql._tcp.shard1.example.com", config.DNSRecordTypeSRV, server.Addr())
+	{
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 2)
+		test.S(t).ExpectEquals(hosts[0], config.HostPort{Host: "db1.example.com", Port: 3306})
+		test.S(t).ExpectEquals(hosts[1], config.HostPort{Host: "db2.example.com", Port: 3307})
+	}
+	{
+		// cached
+		_, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(atomic.LoadInt64(&server.queries), int64(1))
+	}
+}
+
+func TestReadHostsA(t *testing.T) {
+	server := newFakeServer(t, func(name string, rrType uint16, overTCP bool) (int, bool, []record) {
+		if !overTCP {
+			return rcodeSuccess, true, nil
+		}
+		return rcodeSuccess, false, []record{
+			{rrType: typeA, ttl: 0, host: "10.0.0.2"},
+			{rrType: typeA, ttl: 0, host: "10.0.0.1"},
+		}
+	})
+	defer server.Close()
+
+	settings := newTestSettings("shard2.example.com", config.DNSRecordTypeA, server.Addr())
+	for i := 0; i < 2; i++ {
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 2)
+		test.S(t).ExpectEquals(hosts[0], config.HostPort{Host: "10.0.0.1", Port: 0})
+		test.S(t).ExpectEquals(hosts[1], config.HostPort{Host: "10.0.0.2", Port: 0})
+	}
+	// Truncated over UDP, then retried over TCP; zero TTL is never cached
+	test.S(t).ExpectEquals(atomic.LoadInt64(&server.queries), int64(4))
+}
+
+func TestReadHostsOnFailure(t *testing.T) {
+	server := newFakeServer(t, func(name string, rrType uint16, overTCP bool) (int, bool, []record) {
+		switch name {
+		case "servfail.example.com":
+			return rcodeServerFailure, false, nil
+		case "nodata.example.com":
+			return rcodeSuccess, false, []record{{rrType: typeA, ttl: 60, host: "10.0.0.1"}}
+		}
+		return rcodeNameError, false, nil
+	})
+	defer server.Close()
+
+	for _, name := range []string{"nxdomain.example.com", "servfail.example.com", "nodata.example.com"} {
+		settings := newTestSettings(name, config.DNSRecordTypeSRV, server.Addr())
+		{
+			hosts, err := ReadHosts(settings)
+			test.S(t).ExpectNotNil(err)
+			test.S(t).ExpectTrue(IsNameError(err))
+			test.S(t).ExpectEquals(len(hosts), 0)
+		}
+		{
+			settings.OnFailure = config.DNSOnFailureFail
+			hosts, err := ReadHosts(settings)
+			test.S(t).ExpectNil(err)
+			test.S(t).ExpectEquals(len(hosts), 0)
+		}
+	}
+}
+
+func TestReadHostsUnreachableResolver(t *testing.T) {
+	server := newFakeServer(t, nil)
+	addr := server.Addr()
+	server.Close()
+
+	settings := newTestSettings("shard1.example.com", config.DNSRecordTypeA, addr)
+	settings.TimeoutMillis = 100
+	settings.OnFailure = config.DNSOnFailureFail
+	_, err := ReadHosts(settings)
+	test.S(t).ExpectNotNil(err)
+	test.S(t).ExpectFalse(IsNameError(err))
+}
+
+func TestReadResolvConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	os.WriteFile(path, []byte("# comment\nsearch example.com\nnameserver 10.0.0.53\nnameserver ::1\noptions ndots:2\n"), 0644)
+	servers := readResolvConf(path)
+	test.S(t).ExpectEquals(len(servers), 2)
+	test.S(t).ExpectEquals(servers[0], "10.0.0.53:53")
+	test.S(t).ExpectEquals(servers[1], "[::1]:53")
+
+	test.S(t).ExpectEquals(len(readResolvConf(filepath.Join(t.TempDir(), "no-such-file"))), 0)
+}
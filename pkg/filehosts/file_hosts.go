@@ -0,0 +1,158 @@
+package filehosts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+	"gopkg.in/yaml.v3"
+)
+
+// fileCheckInterval is the interval between two checks of a hosts file for changes
+const fileCheckInterval = time.Second
+
+// hostsDocument is a JSON or YAML hosts file given as an object, rather than as a list
+type hostsDocument struct {
+	Hosts []string `json:"Hosts" yaml:"Hosts"`
+}
+
+// parseHosts reads the hosts listed in a file's content
+func parseHosts(content []byte, format string) (hosts []string, err error) {
+	switch format {
+	case config.FileHostsFormatJSON:
+		if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+			var document hostsDocument
+			err = json.Unmarshal(content, &document)
+			hosts = document.Hosts
+		} else {
+			err = json.Unmarshal(content, &hosts)
+		}
+	case config.FileHostsFormatYAML:
+		var node yaml.Node
+		if err = yaml.Unmarshal(content, &node); err == nil && len(node.Content) > 0 && node.Content[0].Kind == yaml.MappingNode {
+			var document hostsDocument
+			err = node.Decode(&document)
+			hosts = document.Hosts
+		} else if err == nil && len(node.Content) > 0 {
+			err = node.Decode(&hosts)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			hosts = append(hosts, line)
+		}
+		err = scanner.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %s hosts: %+v", format, err)
+	}
+	var validHosts []string
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			validHosts = append(validHosts, host)
+		}
+	}
+	return validHosts, nil
+}
+
+// watcher keeps the latest hosts of a file, and checks it for changes in the background
+type watcher struct {
+	settings config.FileHostsConfigurationSettings
+	onChange func()
+	hosts    []string
+	err      error
+	modTime  time.Time
+	size     int64
+	mutex    sync.Mutex
+}
+
+var watchers = base.NewWatcherRegistry()
+
+// getWatcher returns the watcher of given file, starting one with an initial read if there is none
+func getWatcher(settings *config.FileHostsConfigurationSettings, onChange func()) *watcher {
+	key := fmt.Sprintf("%s|%s", settings.Path, settings.Format)
+	return watchers.Get(key, func() base.Watcher {
+		w := &watcher{settings: *settings, onChange: onChange}
+		w.check()
+		return w
+	}).(*watcher)
+}
+
+// check re-reads the file if it changed, and returns true when its hosts have changed. A file that cannot be
+// read or parsed keeps its last known hosts, if any.
+func (w *watcher) check() (changed bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	fileInfo, err := os.Stat(w.settings.Path)
+	if err == nil && fileInfo.ModTime().Equal(w.modTime) && fileInfo.Size() == w.size {
+		return false
+	}
+	var hosts []string
+	if err == nil {
+		var content []byte
+		if content, err = os.ReadFile(w.settings.Path); err == nil {
+			hosts, err = parseHosts(content, w.settings.Format)
+		}
+	}
+	if err != nil {
+		if w.hosts != nil {
+			log.Errorf("hosts file %s: %+v; using last known hosts", w.settings.Path, err)
+			return false
+		}
+		w.err = err
+		return false
+	}
+	if hosts == nil {
+		hosts = []string{}
+	}
+	// The initial read is no change, but recovering from an error is
+	changed = w.err != nil || (w.hosts != nil && !reflect.DeepEqual(hosts, w.hosts))
+	if changed {
+		log.Infof("hosts file %s changed; reloading", w.settings.Path)
+	}
+	w.hosts, w.err = hosts, nil
+	w.modTime, w.size = fileInfo.ModTime(), fileInfo.Size()
+	return changed
+}
+
+func (w *watcher) read() ([]string, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.hosts, w.err
+}
+
+// Watch checks the file for changes every fileCheckInterval, until the watcher is idle
+func (w *watcher) Watch(isIdle func() bool) {
+	ticker := time.NewTicker(fileCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if isIdle() {
+			return
+		}
+		if w.check() && w.onChange != nil {
+			w.onChange()
+		}
+	}
+}
+
+// ReadHosts returns the hosts listed in a file, as last read. The file is watched in the background, and onChange
+// is called whenever its hosts change.
+func ReadHosts(settings *config.FileHostsConfigurationSettings, onChange func()) (hosts []string, err error) {
+	return getWatcher(settings, onChange).read()
+}
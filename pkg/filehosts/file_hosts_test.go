@@ -0,0 +1,119 @@
+package filehosts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestParseHosts(t *testing.T) {
+	expected := "10.0.0.1,10.0.0.2:3307"
+	for _, tc := range []struct {
+		format  string
+		content string
+	}{
+		{config.FileHostsFormatLines, "# replicas\n10.0.0.1\n\n  10.0.0.2:3307  \n"},
+		{config.FileHostsFormatJSON, `["10.0.0.1", "10.0.0.2:3307"]`},
+		{config.FileHostsFormatJSON, `{"Hosts": ["10.0.0.1", "10.0.0.2:3307", ""]}`},
+		{config.FileHostsFormatYAML, "- 10.0.0.1\n- 10.0.0.2:3307\n"},
+		{config.FileHostsFormatYAML, "# replicas\nHosts:\n  - 10.0.0.1\n  - \"10.0.0.2:3307\"\n"},
+	} {
+		hosts, err := parseHosts([]byte(tc.content), tc.format)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(strings.Join(hosts, ","), expected)
+	}
+	for _, format := range []string{config.FileHostsFormatLines, config.FileHostsFormatJSON, config.FileHostsFormatYAML} {
+		hosts, err := parseHosts([]byte(""), format)
+		if format == config.FileHostsFormatJSON {
+			test.S(t).ExpectNotNil(err)
+			continue
+		}
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 0)
+	}
+	{
+		_, err := parseHosts([]byte(`{"Hosts": [`), config.FileHostsFormatJSON)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := parseHosts([]byte("Hosts: {a: b}\n"), config.FileHostsFormatYAML)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func writeHostsFile(path string, content string, modTime time.Time) {
+	os.WriteFile(path, []byte(content), 0644)
+	os.Chtimes(path, modTime, modTime)
+}
+
+func TestWatcherCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.txt")
+	w := &watcher{settings: config.FileHostsConfigurationSettings{Path: path, Format: config.FileHostsFormatLines}}
+	modTime := time.Now().Add(-time.Hour)
+
+	test.S(t).ExpectFalse(w.check())
+	{
+		_, err := w.read()
+		test.S(t).ExpectNotNil(err)
+	}
+	writeHostsFile(path, "10.0.0.1\n", modTime)
+	test.S(t).ExpectTrue(w.check())
+	{
+		hosts, err := w.read()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(strings.Join(hosts, ","), "10.0.0.1")
+	}
+	test.S(t).ExpectFalse(w.check())
+
+	// unchanged hosts
+	writeHostsFile(path, "# comment\n10.0.0.1\n", modTime.Add(time.Second))
+	test.S(t).ExpectFalse(w.check())
+
+	writeHostsFile(path, "10.0.0.1\n10.0.0.2\n", modTime.Add(2*time.Second))
+	test.S(t).ExpectTrue(w.check())
+
+	// a missing file keeps last known hosts
+	os.Remove(path)
+	test.S(t).ExpectFalse(w.check())
+	{
+		hosts, err := w.read()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(strings.Join(hosts, ","), "10.0.0.1,10.0.0.2")
+	}
+}
+
+func TestReadHostsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	writeHostsFile(path, `["10.0.0.1"]`, time.Now().Add(-time.Hour))
+	settings := &config.FileHostsConfigurationSettings{Path: path, Format: config.FileHostsFormatJSON}
+
+	changed := make(chan bool, 1)
+	onChange := func() {
+		select {
+		case changed <- true:
+		default:
+		}
+	}
+	{
+		hosts, err := ReadHosts(settings, onChange)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(strings.Join(hosts, ","), "10.0.0.1")
+	}
+	writeHostsFile(path, `["10.0.0.1", "10.0.0.3"]`, time.Now())
+	select {
+	case <-changed:
+	case <-time.After(5 * fileCheckInterval):
+		t.Fatalf("expected a change notification")
+	}
+	{
+		hosts, err := ReadHosts(settings, onChange)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(strings.Join(hosts, ","), "10.0.0.1,10.0.0.3")
+	}
+}
@@ -32,4 +32,9 @@ func TestGetBackendDBUri(t *testing.T) {
 	config.Settings().BackendMySQLCollation = "utf8mb4_unicode_ci"
 	dbUri = getBackendDBUri()
 	test.S(t).ExpectEquals(dbUri, "gromit:penguin@tcp(myhost:3306)/test_database?interpolateParams=true&collation=utf8mb4_unicode_ci&timeout=500ms")
+
+	// test TLS
+	config.Settings().BackendMySQLTLSSettings = config.TLSConfigurationSettings{Enabled: true}
+	dbUri = getBackendDBUri()
+	test.S(t).ExpectEquals(dbUri, "gromit:penguin@tcp(myhost:3306)/test_database?interpolateParams=true&collation=utf8mb4_unicode_ci&timeout=500ms&tls="+config.Settings().BackendMySQLTLSSettings.ConfigName())
 }
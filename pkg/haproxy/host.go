@@ -6,6 +6,8 @@ const (
 	StatusDown    BackendHostStatus = "DOWN"
 	StatusNOLB    BackendHostStatus = "NOLB"
 	StatusUp      BackendHostStatus = "UP"
+	StatusMaint   BackendHostStatus = "MAINT"
+	StatusDrain   BackendHostStatus = "DRAIN"
 	StatusNoCheck BackendHostStatus = "no check"
 	StatusUnknown BackendHostStatus = "unkown"
 )
@@ -18,6 +20,10 @@ func ToBackendHostStatus(status string) BackendHostStatus {
 		return StatusNOLB
 	case "UP":
 		return StatusUp
+	case "MAINT":
+		return StatusMaint
+	case "DRAIN":
+		return StatusDrain
 	case "no check":
 		return StatusNoCheck
 	default:
@@ -1,6 +1,8 @@
 package haproxy
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/config"
 
 	"github.com/patrickmn/go-cache"
 )
@@ -68,34 +71,115 @@ func ParseStatus(fullStatus string) (status BackendHostStatus, isTransitioning b
 	return ToBackendHostStatus(statusTokens[0]), isTransitioning
 }
 
-// ParseHosts reads HAProxy CSV lines and returns lists of hosts participating in the given pool (backend)
-// Returned are all non-disabled hosts in given backend. Thus, a NOLB is skipped; any UP or DOWN hosts are returned.
-// Such list indicates the hosts which can be expected to be active, which is then the list freno will probe.
-func ParseHosts(csvLines []string, poolName string) (backendHosts [](*BackendHost), err error) {
-	if len(csvLines) < 1 {
-		return backendHosts, HAProxyEmptyStatus
+// statRow is a row of HAProxy stats, mapping field names to values
+type statRow map[string]string
+
+// splitCSVLine splits a CSV line into fields, unquoting quoted fields
+func splitCSVLine(line string) []string {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if fields, err := reader.Read(); err == nil {
+		return fields
 	}
-	if len(csvLines) == 1 {
-		return backendHosts, HAProxyPartialStatus
+	return strings.Split(line, ",")
+}
+
+// parseCSVRows reads HAProxy CSV lines, the first of which is the header
+func parseCSVRows(csvLines []string) (rows []statRow) {
+	tokensMap := parseHeader(csvLines[0])
+	for _, line := range csvLines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tokens := splitCSVLine(line)
+		row := statRow{}
+		for name, i := range tokensMap {
+			if i < len(tokens) {
+				row[name] = tokens[i]
+			}
+		}
+		rows = append(rows, row)
 	}
-	var tokensMap map[string]int
+	return rows
+}
+
+// parseTypedRows reads the output of "show stat typed", which lists one field per line, as in:
+// <type>.<proxy id>.<object id>.<field position>.<field name>.<process>:<tags>:<value type>:<value>
+func parseTypedRows(typed string) (rows []statRow, err error) {
+	rowsMap := map[string]statRow{}
+	var objects []string
+	for _, line := range parseLines(typed) {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) == "" {
+			continue
+		}
+		tokens := strings.SplitN(line, ":", 4)
+		if len(tokens) != 4 {
+			return rows, fmt.Errorf("Haproxy typed stats parsing error: unexpected line: %s", line)
+		}
+		ids := strings.Split(tokens[0], ".")
+		if len(ids) != 6 {
+			return rows, fmt.Errorf("Haproxy typed stats parsing error: unexpected field: %s", tokens[0])
+		}
+		object := strings.Join([]string{ids[0], ids[1], ids[2], ids[5]}, ".")
+		row, found := rowsMap[object]
+		if !found {
+			row = statRow{}
+			rowsMap[object] = row
+			objects = append(objects, object)
+		}
+		row[ids[4]] = tokens[3]
+	}
+	for _, object := range objects {
+		rows = append(rows, rowsMap[object])
+	}
+	return rows, nil
+}
+
+// jsonStatField is a field of "show stat json" output, which lists rows as arrays of fields
+type jsonStatField struct {
+	Field struct {
+		Name string `json:"name"`
+	} `json:"field"`
+	Value struct {
+		Value interface{} `json:"value"`
+	} `json:"value"`
+}
+
+// parseJSONRows reads the output of "show stat json"
+func parseJSONRows(text string) (rows []statRow, err error) {
+	var objects [][]jsonStatField
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return rows, fmt.Errorf("Haproxy JSON stats parsing error: %+v", err)
+	}
+	for _, fields := range objects {
+		row := statRow{}
+		for _, field := range fields {
+			row[field.Field.Name] = fmt.Sprintf("%v", field.Value.Value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parsePoolHosts returns the hosts participating in the given pool (backend), by their status.
+// Returned are all non-disabled hosts in given backend. Thus, a NOLB is skipped; any UP or DOWN hosts are returned.
+// Such list indicates the hosts which can be expected to be active, which is then the list freno will probe.
+func parsePoolHosts(rows []statRow, poolName string) (backendHosts [](*BackendHost), err error) {
 	poolFound := false
 	countHosts := 0
 	countUpHosts := 0
 	countTransitioningHosts := 0
 	countTransitioningUpHosts := 0
-	for i, line := range csvLines {
-		if i == 0 {
-			tokensMap = parseHeader(csvLines[0])
-			continue
-		}
-		tokens := strings.Split(line, ",")
-		if tokens[tokensMap["pxname"]] == poolName {
+	for _, row := range rows {
+		if row["pxname"] == poolName {
 			poolFound = true
-			if host := tokens[tokensMap["svname"]]; host != "BACKEND" && host != "FRONTEND" {
+			if host := row["svname"]; host != "BACKEND" && host != "FRONTEND" {
 				countHosts++
 
-				status, isTransitioning := ParseStatus(tokens[tokensMap["status"]])
+				status, isTransitioning := ParseStatus(row["status"])
 
 				backendHosts = append(backendHosts, NewBackendHost(host, status, isTransitioning))
 				if isTransitioning {
@@ -122,6 +206,18 @@ func ParseHosts(csvLines []string, poolName string) (backendHosts [](*BackendHos
 	return backendHosts, nil
 }
 
+// ParseHosts reads HAProxy CSV lines and returns lists of hosts participating in the given pool (backend).
+// See comment for parsePoolHosts
+func ParseHosts(csvLines []string, poolName string) (backendHosts [](*BackendHost), err error) {
+	if len(csvLines) < 1 {
+		return backendHosts, HAProxyEmptyStatus
+	}
+	if len(csvLines) == 1 {
+		return backendHosts, HAProxyPartialStatus
+	}
+	return parsePoolHosts(parseCSVRows(csvLines), poolName)
+}
+
 // ParseCsvHosts reads HAProxy CSV text and returns lists of hosts participating in the given pool (backend).
 // See comment for ParseHosts
 func ParseCsvHosts(csv string, poolName string) (backendHosts [](*BackendHost), err error) {
@@ -129,13 +225,52 @@ func ParseCsvHosts(csv string, poolName string) (backendHosts [](*BackendHost),
 	return ParseHosts(csvLines, poolName)
 }
 
+// ParseTypedHosts reads "show stat typed" output and returns lists of hosts participating in the given pool (backend).
+// See comment for parsePoolHosts
+func ParseTypedHosts(typed string, poolName string) (backendHosts [](*BackendHost), err error) {
+	rows, err := parseTypedRows(typed)
+	if err != nil {
+		return backendHosts, err
+	}
+	if len(rows) == 0 {
+		return backendHosts, HAProxyEmptyStatus
+	}
+	return parsePoolHosts(rows, poolName)
+}
+
+// ParseJSONHosts reads "show stat json" output and returns lists of hosts participating in the given pool (backend).
+// See comment for parsePoolHosts
+func ParseJSONHosts(text string, poolName string) (backendHosts [](*BackendHost), err error) {
+	rows, err := parseJSONRows(text)
+	if err != nil {
+		return backendHosts, err
+	}
+	if len(rows) == 0 {
+		return backendHosts, HAProxyEmptyStatus
+	}
+	return parsePoolHosts(rows, poolName)
+}
+
+// ParseStatsHosts reads HAProxy stats in given format ("csv", "typed" or "json") and returns lists of hosts
+// participating in the given pool (backend)
+func ParseStatsHosts(stats string, format string, poolName string) (backendHosts [](*BackendHost), err error) {
+	switch format {
+	case config.HAProxyStatsFormatTyped:
+		return ParseTypedHosts(stats, poolName)
+	case config.HAProxyStatsFormatJSON:
+		return ParseJSONHosts(stats, poolName)
+	default:
+		return ParseCsvHosts(stats, poolName)
+	}
+}
+
 func toCSVUrl(u url.URL) *url.URL {
 	u.Path = fmt.Sprintf("%s;csv;norefresh", u.Path)
 	return &u
 }
 
-// Read will read HAProxy URI and return with the CSV text
-func Read(u *url.URL) (csv string, err error) {
+// Read will read HAProxy URI and return with the CSV text. user & password, if given, authenticate with the stats page
+func Read(u *url.URL, user, password string) (csv string, err error) {
 	httpGetConcurrencyChan <- true
 	defer func() { <-httpGetConcurrencyChan }()
 
@@ -145,13 +280,23 @@ func Read(u *url.URL) (csv string, err error) {
 		return cachedCSV.(string), nil
 	}
 
-	resp, err := httpClient.Get(haproxyUrl)
+	request, err := http.NewRequest(http.MethodGet, haproxyUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	if user != "" {
+		request.SetBasicAuth(user, password)
+	}
+	resp, err := httpClient.Do(request)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
 		return "", err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Haproxy GET error: %s", resp.Status)
+	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -6,6 +6,8 @@
 package haproxy
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -78,6 +80,55 @@ statsctl,FRONTEND,,,1,3,2000,21718,2788357,173364223,0,0,315,,,,,OPEN,,,,,,,,,1,
 statsctl,BACKEND,0,0,0,0,200,0,2788357,173364223,0,0,,0,0,0,0,UP,0,0,0,,0,1032064,0,,1,9,0,,0,,1,0,,0,,,,0,0,0,0,0,0,,,,,0,0,0,0,0,0,0,,,0,0,0,0,
 `
 
+var csvMaintDrain = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,
+mysqlcluster0_ro_main,mysqlcluster0a-dc,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,10,1,0,49,6,89174,368958,,1,6,1,,0,,2,0,,0,L7OK,200,18,,,,,,,0,,,,0,0,,,,,-1,"OK, replicating",,0,0,0,0,
+mysqlcluster0_ro_main,mysqlcluster0b-dc,0,0,0,0,,0,0,0,,0,,0,0,0,0,MAINT,10,1,0,41,5,1912,1000,,1,6,2,,0,,2,0,,0,L7OK,200,24,,,,,,,0,,,,0,0,,,,,-1,"maintenance, by ""ops""",,0,0,0,0,
+mysqlcluster0_ro_main,mysqlcluster0c-dc,0,0,0,0,,0,0,0,,0,,0,0,0,0,MAINT (via mysqlcluster0_ro_backup/mysqlcluster0c-dc),10,1,0,0,0,1032061,0,,1,6,3,,0,,2,0,,0,L7OKC,404,12,,,,,,,0,,,,0,0,,,,,-1,Not Found,,0,0,0,0,
+mysqlcluster0_ro_main,mysqlcluster0d-dc,0,0,0,0,,0,0,0,,0,,0,0,0,0,DRAIN,10,1,0,0,0,1032061,0,,1,6,4,,0,,2,0,,0,L7OK,200,12,,,,,,,0,,,,0,0,,,,,-1,OK,,0,0,0,0,
+mysqlcluster0_ro_main,mysqlcluster0e-dc,0,0,0,0,,0,0,0,,0,,0,0,0,0,DOWN,10,1,0,0,0,1032061,0,,1,6,5,,0,,2,0,,0,L4CON,,12,,,,,,,0,,,,0,0,,,,,-1,"Connection refused",,0,0,0,0,
+mysqlcluster0_ro_main,BACKEND,0,0,0,0,2000,0,0,0,0,0,,0,0,0,0,UP,20,2,0,,4,89174,728,,1,6,0,,0,,1,0,,0,,,,,,,,,,,,,,0,0,0,0,0,0,-1,,,0,0,0,0,
+`
+
+var typedStats = `F.2.0.0.pxname.1:KNSN:str:mysqlcluster0ro
+F.2.0.1.svname.1:KNSN:str:FRONTEND
+F.2.0.17.status.1:MGP:str:OPEN
+S.3.1.0.pxname.1:KNSN:str:mysqlcluster0_ro_main
+S.3.1.1.svname.1:KNSN:str:mysqlcluster0a-dc
+S.3.1.17.status.1:MGP:str:UP
+S.3.1.36.check_status.1:MNP:str:L7OK
+S.3.2.0.pxname.1:KNSN:str:mysqlcluster0_ro_main
+S.3.2.1.svname.1:KNSN:str:mysqlcluster0b-dc
+S.3.2.17.status.1:MGP:str:MAINT
+S.3.2.36.check_status.1:MNP:str:L7OK: replicating
+S.3.3.0.pxname.1:KNSN:str:mysqlcluster0_ro_main
+S.3.3.1.svname.1:KNSN:str:mysqlcluster0c-dc
+S.3.3.17.status.1:MGP:str:DOWN
+B.3.0.0.pxname.1:KNSN:str:mysqlcluster0_ro_main
+B.3.0.1.svname.1:KNSN:str:BACKEND
+B.3.0.17.status.1:MGP:str:UP
+`
+
+var jsonStats = `[
+  [
+    {"objType":"Server","proxyId":3,"id":1,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"mysqlcluster0_ro_main"}},
+    {"objType":"Server","proxyId":3,"id":1,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"mysqlcluster0a-dc"}},
+    {"objType":"Server","proxyId":3,"id":1,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Output","scope":"Process"},"value":{"type":"str","value":"DRAIN"}},
+    {"objType":"Server","proxyId":3,"id":1,"field":{"pos":18,"name":"weight"},"processNum":1,"tags":{"origin":"Metric","nature":"Avg","scope":"Service"},"value":{"type":"u32","value":0}}
+  ],
+  [
+    {"objType":"Server","proxyId":3,"id":2,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"mysqlcluster0_ro_main"}},
+    {"objType":"Server","proxyId":3,"id":2,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"mysqlcluster0b-dc"}},
+    {"objType":"Server","proxyId":3,"id":2,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Output","scope":"Process"},"value":{"type":"str","value":"UP"}},
+    {"objType":"Server","proxyId":3,"id":2,"field":{"pos":18,"name":"weight"},"processNum":1,"tags":{"origin":"Metric","nature":"Avg","scope":"Service"},"value":{"type":"u32","value":10}}
+  ],
+  [
+    {"objType":"Backend","proxyId":3,"id":0,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"mysqlcluster0_ro_main"}},
+    {"objType":"Backend","proxyId":3,"id":0,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"BACKEND"}},
+    {"objType":"Backend","proxyId":3,"id":0,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Output","scope":"Process"},"value":{"type":"str","value":"UP"}}
+  ]
+]
+`
+
 func init() {
 	log.SetLevel(log.ERROR)
 }
@@ -103,6 +154,29 @@ func TestToCsvUrl(t *testing.T) {
 	}
 }
 
+func TestReadBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "stats" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(csv0))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/stats-basic-auth")
+	test.S(t).ExpectNil(err)
+	{
+		_, err := Read(u, "stats", "wrong")
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		csv, err := Read(u, "stats", "secret")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(csv, csv0)
+	}
+}
+
 func TestParseHeader(t *testing.T) {
 	header := "# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,"
 	tokensMap := parseHeader(header)
@@ -161,6 +235,62 @@ func TestParseHostsTransitioning(t *testing.T) {
 	}
 }
 
+func TestParseHostsMaintDrain(t *testing.T) {
+	backendHosts, err := ParseCsvHosts(csvMaintDrain, "mysqlcluster0_ro_main")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(backendHosts), 5)
+	test.S(t).ExpectEquals(backendHosts[1].Status, StatusMaint)
+	test.S(t).ExpectEquals(backendHosts[2].Status, StatusMaint)
+	test.S(t).ExpectFalse(backendHosts[2].IsTransitioning)
+	test.S(t).ExpectEquals(backendHosts[3].Status, StatusDrain)
+
+	hosts := FilterThrotllerHosts(backendHosts)
+	test.S(t).ExpectTrue(reflect.DeepEqual(hosts, []string{"mysqlcluster0a-dc", "mysqlcluster0e-dc"}))
+}
+
+func TestParseTypedHosts(t *testing.T) {
+	{
+		backendHosts, err := ParseTypedHosts(typedStats, "mysqlcluster0_ro_main")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(backendHosts), 3)
+
+		hosts := FilterThrotllerHosts(backendHosts)
+		test.S(t).ExpectTrue(reflect.DeepEqual(hosts, []string{"mysqlcluster0a-dc", "mysqlcluster0c-dc"}))
+	}
+	{
+		_, err := ParseTypedHosts(typedStats, "no_such_pool")
+		test.S(t).ExpectEquals(err, HAProxyMissingPool)
+	}
+	{
+		_, err := ParseTypedHosts("", "mysqlcluster0_ro_main")
+		test.S(t).ExpectEquals(err, HAProxyEmptyStatus)
+	}
+	{
+		_, err := ParseTypedHosts("Unknown command", "mysqlcluster0_ro_main")
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestParseJSONHosts(t *testing.T) {
+	{
+		backendHosts, err := ParseJSONHosts(jsonStats, "mysqlcluster0_ro_main")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(backendHosts), 2)
+		test.S(t).ExpectEquals(backendHosts[0].Status, StatusDrain)
+
+		hosts := FilterThrotllerHosts(backendHosts)
+		test.S(t).ExpectTrue(reflect.DeepEqual(hosts, []string{"mysqlcluster0b-dc"}))
+	}
+	{
+		_, err := ParseJSONHosts("[]", "mysqlcluster0_ro_main")
+		test.S(t).ExpectEquals(err, HAProxyEmptyStatus)
+	}
+	{
+		_, err := ParseJSONHosts("Permission denied", "mysqlcluster0_ro_main")
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
 func TestParseStatus(t *testing.T) {
 	{
 		status, isTransitioning := ParseStatus("NOLB")
@@ -182,6 +312,16 @@ func TestParseStatus(t *testing.T) {
 		test.S(t).ExpectFalse(isTransitioning)
 		test.S(t).ExpectEquals(status, StatusDown)
 	}
+	{
+		status, isTransitioning := ParseStatus("MAINT (via mysqlcluster0_ro_backup/mysqlcluster0c-dc)")
+		test.S(t).ExpectFalse(isTransitioning)
+		test.S(t).ExpectEquals(status, StatusMaint)
+	}
+	{
+		status, isTransitioning := ParseStatus("DRAIN (agent)")
+		test.S(t).ExpectFalse(isTransitioning)
+		test.S(t).ExpectEquals(status, StatusDrain)
+	}
 	{
 		status, isTransitioning := ParseStatus("no check")
 		test.S(t).ExpectFalse(isTransitioning)
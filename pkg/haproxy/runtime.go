@@ -0,0 +1,96 @@
+package haproxy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// runtimeAPITimeout bounds connecting to the runtime API, and reading a command's output
+const runtimeAPITimeout = time.Second
+
+// typedStatRegexp matches the beginning of "show stat typed" output, e.g. "F.2.0.0.pxname.1:KNSN:str:http-in"
+var typedStatRegexp = regexp.MustCompile(`^[A-Z]\.[0-9]+\.[0-9]+\.`)
+
+// showStatCommand returns the runtime API command listing stats in given format
+func showStatCommand(format string) string {
+	switch format {
+	case config.HAProxyStatsFormatTyped:
+		return "show stat typed"
+	case config.HAProxyStatsFormatJSON:
+		return "show stat json"
+	default:
+		return "show stat"
+	}
+}
+
+// runCommand runs a command on the runtime API at given unix:// or tcp:// address. In non-interactive
+// mode, HAProxy closes the connection once the command's output is written.
+func runCommand(u *url.URL, command string) (output string, err error) {
+	network, address := "unix", u.Path
+	if u.Scheme == "tcp" {
+		network, address = "tcp", u.Host
+	}
+	conn, err := net.DialTimeout(network, address, runtimeAPITimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(runtimeAPITimeout)); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte(command + "\n")); err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ReadRuntimeStats will issue "show stat" on HAProxy's runtime API, and return with the stats in given format
+func ReadRuntimeStats(u *url.URL, format string) (stats string, err error) {
+	httpGetConcurrencyChan <- true
+	defer func() { <-httpGetConcurrencyChan }()
+
+	cacheKey := fmt.Sprintf("%s|%s", u.String(), format)
+	if cachedStats, found := csvCache.Get(cacheKey); found {
+		return cachedStats.(string), nil
+	}
+
+	stats, err = runCommand(u, showStatCommand(format))
+	if err != nil {
+		return "", err
+	}
+	trimmed := strings.TrimSpace(stats)
+	if trimmed == "" {
+		return "", HAProxyEmptyBody
+	}
+	// Command errors, e.g. "Permission denied", are returned as plain text
+	switch format {
+	case config.HAProxyStatsFormatTyped:
+		if !typedStatRegexp.MatchString(trimmed) {
+			return "", fmt.Errorf("Haproxy runtime API error: %s", trimmed)
+		}
+	case config.HAProxyStatsFormatJSON:
+		if !strings.HasPrefix(trimmed, "[") {
+			return "", fmt.Errorf("Haproxy runtime API error: %s", trimmed)
+		}
+	default:
+		if !strings.HasPrefix(trimmed, "#") {
+			return "", fmt.Errorf("Haproxy runtime API error: %s", trimmed)
+		}
+	}
+	csvCache.Set(cacheKey, stats, cache.DefaultExpiration)
+	return stats, nil
+}
@@ -0,0 +1,100 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package haproxy
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// serveRuntimeAPI answers runtime API commands on given listener, as HAProxy does in non-interactive mode
+func serveRuntimeAPI(listener net.Listener, outputs map[string]string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			command, _ := bufio.NewReader(conn).ReadString('\n')
+			output, found := outputs[command]
+			if !found {
+				output = "Unknown command.\n"
+			}
+			conn.Write([]byte(output))
+		}(conn)
+	}
+}
+
+func TestReadRuntimeStats(t *testing.T) {
+	outputs := map[string]string{
+		"show stat\n":       csv0,
+		"show stat typed\n": typedStats,
+	}
+	dir, err := os.MkdirTemp("", "freno-haproxy")
+	test.S(t).ExpectNil(err)
+	defer os.RemoveAll(dir)
+
+	unixListener, err := net.Listen("unix", filepath.Join(dir, "haproxy.sock"))
+	test.S(t).ExpectNil(err)
+	defer unixListener.Close()
+	go serveRuntimeAPI(unixListener, outputs)
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.S(t).ExpectNil(err)
+	defer tcpListener.Close()
+	go serveRuntimeAPI(tcpListener, outputs)
+
+	{
+		u, err := url.Parse("unix://" + filepath.Join(dir, "haproxy.sock"))
+		test.S(t).ExpectNil(err)
+		stats, err := ReadRuntimeStats(u, config.HAProxyStatsFormatCSV)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(stats, csv0)
+	}
+	{
+		u, err := url.Parse("tcp://" + tcpListener.Addr().String())
+		test.S(t).ExpectNil(err)
+		stats, err := ReadRuntimeStats(u, config.HAProxyStatsFormatTyped)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(stats, typedStats)
+	}
+	{
+		u, err := url.Parse("tcp://" + tcpListener.Addr().String())
+		test.S(t).ExpectNil(err)
+		_, err = ReadRuntimeStats(u, config.HAProxyStatsFormatJSON)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		settings := &config.HAProxyConfigurationSettings{
+			Addresses:   "unix://" + filepath.Join(dir, "haproxy.sock"),
+			PoolName:    "mysqlcluster0_ro_backup",
+			StatsFormat: config.HAProxyStatsFormatCSV,
+		}
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(reflect.DeepEqual(hosts, []string{"mysqlcluster0e-dc", "mysqlcluster0f-dc", "mysqlcluster0h-dc"}))
+	}
+	{
+		settings := &config.HAProxyConfigurationSettings{
+			Addresses:   "unix://" + filepath.Join(dir, "haproxy.sock"),
+			PoolNames:   []string{"mysqlcluster0_ro_main", "mysqlcluster0_ro_backup"},
+			StatsFormat: config.HAProxyStatsFormatCSV,
+		}
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(reflect.DeepEqual(hosts, []string{"mysqlcluster0a-dc", "mysqlcluster0b-dc", "mysqlcluster0e-dc", "mysqlcluster0f-dc", "mysqlcluster0h-dc"}))
+	}
+}
@@ -1,5 +1,14 @@
 package haproxy
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+)
+
 func FilterThrotllerHosts(backendHosts [](*BackendHost)) (hosts []string) {
 	for _, backendHost := range backendHosts {
 		hostIsRelevant := false
@@ -19,3 +28,47 @@ func FilterThrotllerHosts(backendHosts [](*BackendHost)) (hosts []string) {
 	}
 	return hosts
 }
+
+// ReadHosts reads the hosts of the configured HAProxy pools, across all HAProxy addresses: stats pages, or runtime APIs.
+// A host listed more than once is returned once.
+func ReadHosts(settings *config.HAProxyConfigurationSettings) (totalHosts []string, err error) {
+	poolNames := settings.GetPoolNames()
+	user, password, err := settings.Credentials()
+	if err != nil {
+		return totalHosts, fmt.Errorf("Unable to read HAproxy credentials: %+v", err)
+	}
+	hostsMap := make(map[string]bool)
+	addresses, _ := settings.GetProxyAddresses()
+	for _, u := range addresses {
+		log.Debugf("getting haproxy data from %s", u.String())
+		var stats string
+		format := config.HAProxyStatsFormatCSV
+		if config.IsRuntimeAPIAddress(u) {
+			format = settings.StatsFormat
+			stats, err = ReadRuntimeStats(u, format)
+		} else {
+			stats, err = Read(u, user, password)
+		}
+		if err != nil {
+			return totalHosts, fmt.Errorf("Unable to get HAproxy data from %s: %+v", u.String(), err)
+		}
+		for _, poolName := range poolNames {
+			if backendHosts, err := ParseStatsHosts(stats, format, poolName); err == nil {
+				hosts := FilterThrotllerHosts(backendHosts)
+				for _, host := range hosts {
+					if !hostsMap[host] {
+						hostsMap[host] = true
+						totalHosts = append(totalHosts, host)
+					}
+				}
+				log.Debugf("Read %+v hosts from haproxy %s/#%s", len(hosts), u.String(), poolName)
+			} else {
+				log.Errorf("Unable to get HAproxy hosts from %s/#%s: %+v", u.String(), poolName, err)
+			}
+		}
+	}
+	if len(totalHosts) == 0 {
+		return totalHosts, fmt.Errorf("Unable to get any HAproxy hosts for pools: %s", strings.Join(poolNames, ","))
+	}
+	return totalHosts, nil
+}
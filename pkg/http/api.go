@@ -38,6 +38,7 @@ type API interface {
 	ThrottledApps(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	SkipHost(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	SkippedHosts(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ConnectionPools(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	RecoverHost(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	RecentApps(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	Help(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
@@ -163,9 +164,12 @@ func (api *APIImpl) check(w http.ResponseWriter, r *http.Request, ps httprouter.
 		remoteAddr = r.RemoteAddr
 		remoteAddr = strings.Split(remoteAddr, ":")[0]
 	}
-	flags.LowPriority = (r.URL.Query().Get("p") == "low")
+	// flags may be shared between requests; work on a copy
+	checkFlags := *flags
+	checkFlags.LowPriority = (r.URL.Query().Get("p") == "low")
+	checkFlags.Explain = (r.URL.Query().Get("explain") == "true")
 
-	checkResult := api.throttlerCheck.Check(appName, storeType, storeName, remoteAddr, flags)
+	checkResult := api.throttlerCheck.Check(appName, storeType, storeName, remoteAddr, &checkFlags)
 	if checkResult.StatusCode == http.StatusNotFound && flags.OKIfNotExists {
 		checkResult.StatusCode = http.StatusOK // 200
 	}
@@ -393,6 +397,12 @@ func (api *APIImpl) SkippedHosts(w http.ResponseWriter, r *http.Request, ps http
 	json.NewEncoder(w).Encode(api.consensusService.SkippedHostsMap())
 }
 
+// ConnectionPools returns the stats of connection pools to probed MySQL servers
+func (api *APIImpl) ConnectionPools(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.throttlerCheck.ConnectionPoolsStats())
+}
+
 // ConfigureRoutes configures a set of HTTP routes to be actions dispatched by the
 // given api's methods.
 func ConfigureRoutes(api API) *httprouter.Router {
@@ -431,6 +441,8 @@ func ConfigureRoutes(api API) *httprouter.Router {
 	register(router, "/skipped-hosts", api.SkippedHosts)
 	register(router, "/recover-host/:hostName", api.RecoverHost)
 
+	register(router, "/connection-pools", api.ConnectionPools)
+
 	register(router, "/debug/vars", metricsHandle)
 	register(router, "/debug/metrics", metricsHandle)
 
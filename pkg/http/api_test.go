@@ -37,6 +37,7 @@ func TestRoutes(t *testing.T) {
 	}{
 		{http.MethodGet, "/lb-check", http.StatusOK},
 		{http.MethodGet, "/config/memcache", http.StatusOK},
+		{http.MethodGet, "/connection-pools", http.StatusOK},
 	}
 	for _, route := range expectedRoutes {
 		r, _ := http.NewRequest(route.verb, route.path, nil)
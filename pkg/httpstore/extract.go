@@ -0,0 +1,97 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValueExtractor extracts a metric value off a response body
+type ValueExtractor interface {
+	Extract(body []byte) (float64, error)
+}
+
+// JSONPathExtractor extracts a value off a JSON document by dotted path. Path tokens index into objects by key,
+// and into arrays by position, e.g. "consumers.0.lag"
+type JSONPathExtractor struct {
+	Path string
+}
+
+func (extractor *JSONPathExtractor) Extract(body []byte) (float64, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return 0, fmt.Errorf("invalid JSON body: %+v", err)
+	}
+	for _, token := range strings.Split(extractor.Path, ".") {
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			var found bool
+			if value, found = typedValue[token]; !found {
+				return 0, fmt.Errorf("%s: %s not found", extractor.Path, token)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(typedValue) {
+				return 0, fmt.Errorf("%s: invalid index %s", extractor.Path, token)
+			}
+			value = typedValue[index]
+		default:
+			return 0, fmt.Errorf("%s: cannot look up %s in a scalar value", extractor.Path, token)
+		}
+	}
+	switch typedValue := value.(type) {
+	case json.Number:
+		return typedValue.Float64()
+	case string:
+		return parseFloat(typedValue)
+	case bool:
+		if typedValue {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%s: not a numeric value: %+v", extractor.Path, value)
+}
+
+// RegexpExtractor extracts a value off a response body by regular expression. The value is the first submatch, or
+// the entire match for expressions without submatches
+type RegexpExtractor struct {
+	Regexp *regexp.Regexp
+}
+
+func NewRegexpExtractor(expression string) (*RegexpExtractor, error) {
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexpExtractor{Regexp: compiled}, nil
+}
+
+func (extractor *RegexpExtractor) Extract(body []byte) (float64, error) {
+	submatch := extractor.Regexp.FindSubmatch(body)
+	if submatch == nil {
+		return 0, fmt.Errorf("%s: no match", extractor.Regexp.String())
+	}
+	if len(submatch) > 1 {
+		return parseFloat(string(submatch[1]))
+	}
+	return parseFloat(string(submatch[0]))
+}
+
+func parseFloat(s string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a numeric value: %s", s)
+	}
+	return value, nil
+}
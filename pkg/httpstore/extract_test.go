@@ -0,0 +1,90 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestJSONPathExtractor(t *testing.T) {
+	body := []byte(`{"queue": {"depth": 42, "lag": "1.5", "paused": false}, "consumers": [{"lag": 3}, {"lag": 7.25}]}`)
+	{
+		value, err := (&JSONPathExtractor{Path: "queue.depth"}).Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 42.0)
+	}
+	{
+		value, err := (&JSONPathExtractor{Path: "queue.lag"}).Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.5)
+	}
+	{
+		value, err := (&JSONPathExtractor{Path: "queue.paused"}).Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.0)
+	}
+	{
+		value, err := (&JSONPathExtractor{Path: "consumers.1.lag"}).Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 7.25)
+	}
+	{
+		_, err := (&JSONPathExtractor{Path: "consumers.2.lag"}).Extract(body)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := (&JSONPathExtractor{Path: "queue.size"}).Extract(body)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := (&JSONPathExtractor{Path: "queue"}).Extract(body)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := (&JSONPathExtractor{Path: "queue.depth.value"}).Extract(body)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := (&JSONPathExtractor{Path: "queue.depth"}).Extract([]byte("depth: 42"))
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestRegexpExtractor(t *testing.T) {
+	body := []byte("indexing_lag_seconds 12.5\nqueue_depth 3\n")
+	{
+		extractor, err := NewRegexpExtractor(`indexing_lag_seconds ([0-9.]+)`)
+		test.S(t).ExpectNil(err)
+		value, err := extractor.Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 12.5)
+	}
+	{
+		extractor, err := NewRegexpExtractor(`[0-9]+$`)
+		test.S(t).ExpectNil(err)
+		_, err = extractor.Extract(body)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		extractor, err := NewRegexpExtractor(`(?m)[0-9]+$`)
+		test.S(t).ExpectNil(err)
+		value, err := extractor.Extract(body)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 5.0)
+	}
+	{
+		extractor, err := NewRegexpExtractor(`queue_depth (\w+)`)
+		test.S(t).ExpectNil(err)
+		_, err = extractor.Extract([]byte("queue_depth unknown"))
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := NewRegexpExtractor(`queue_depth (`)
+		test.S(t).ExpectNotNil(err)
+	}
+}
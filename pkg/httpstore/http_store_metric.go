@@ -0,0 +1,100 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+
+	"github.com/patrickmn/go-cache"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+// maxBodySize limits the size of a response body read for a metric
+const maxBodySize = 4 * 1024 * 1024
+
+var httpClient = base.SetupHttpClient(0)
+
+var httpStoreMetricCache = cache.New(cache.NoExpiration, 10*time.Millisecond)
+
+type HTTPStoreMetric struct {
+	StoreType   string
+	ClusterName string
+	URL         string
+	Value       float64
+	Err         error
+}
+
+func (metric *HTTPStoreMetric) HashKey() string {
+	return MetricHashKey(metric.ClusterName, metric.URL)
+}
+
+func (metric *HTTPStoreMetric) Get() (float64, error) {
+	return metric.Value, metric.Err
+}
+
+// readBody GETs the probe's URL, and returns the body of a successful response
+func readBody(probe *Probe) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(probe.TimeoutMillis)*time.Millisecond)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range probe.Headers {
+		request.Header.Set(name, value)
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status: %d", probe.URL, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+}
+
+// ReadMetric reads the probe's URL and extracts the metric value off the response
+func ReadMetric(probe *Probe, clusterName string) (metric *HTTPStoreMetric) {
+	cacheKey := fmt.Sprintf("%s/%s", probe.StoreType, MetricHashKey(clusterName, probe.URL))
+	if probe.CacheMillis > 0 {
+		if cached, found := httpStoreMetricCache.Get(cacheKey); found {
+			return cached.(*HTTPStoreMetric)
+		}
+	}
+	metric = &HTTPStoreMetric{StoreType: probe.StoreType, ClusterName: clusterName, URL: probe.URL}
+
+	started := time.Now()
+	defer func(metric *HTTPStoreMetric, started time.Time) {
+		go func() {
+			metrics.GetOrRegisterTimer("httpstore.probes.latency", nil).Update(time.Since(started))
+			metrics.GetOrRegisterCounter("httpstore.probes.total", nil).Inc(1)
+			if metric.Err != nil {
+				metrics.GetOrRegisterCounter("httpstore.probes.error", nil).Inc(1)
+			}
+		}()
+	}(metric, started)
+
+	body, err := readBody(probe)
+	if err != nil {
+		metric.Err = err
+		return metric
+	}
+	if metric.Value, metric.Err = probe.Extractor.Extract(body); metric.Err != nil {
+		return metric
+	}
+	if probe.CacheMillis > 0 {
+		httpStoreMetricCache.Set(cacheKey, metric, time.Duration(probe.CacheMillis)*time.Millisecond)
+	}
+	return metric
+}
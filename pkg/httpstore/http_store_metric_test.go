@@ -0,0 +1,85 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestReadMetric(t *testing.T) {
+	depth := "42"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queue":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"queue": {"depth": ` + depth + `}}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	probe := &Probe{
+		URL:           server.URL + "/queue",
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+		TimeoutMillis: 1000,
+		Extractor:     &JSONPathExtractor{Path: "queue.depth"},
+	}
+	{
+		metric := ReadMetric(probe, "queues")
+		value, err := metric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 42.0)
+		test.S(t).ExpectEquals(metric.HashKey(), MetricHashKey("queues", probe.URL))
+	}
+	{
+		depth = `"unknown"`
+		_, err := ReadMetric(probe, "queues").Get()
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		unauthorizedProbe := *probe
+		unauthorizedProbe.Headers = nil
+		_, err := ReadMetric(&unauthorizedProbe, "queues").Get()
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		slowProbe := *probe
+		slowProbe.URL = server.URL + "/slow"
+		slowProbe.TimeoutMillis = 50
+		_, err := ReadMetric(&slowProbe, "queues").Get()
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadMetricCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("lag=3"))
+	}))
+	defer server.Close()
+
+	extractor, err := NewRegexpExtractor(`lag=([0-9]+)`)
+	test.S(t).ExpectNil(err)
+	probe := &Probe{URL: server.URL, TimeoutMillis: 1000, CacheMillis: 60000, Extractor: extractor}
+	for i := 0; i < 3; i++ {
+		value, err := ReadMetric(probe, "TestReadMetricCache").Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 3.0)
+	}
+	test.S(t).ExpectEquals(requests, 1)
+}
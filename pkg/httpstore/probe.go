@@ -0,0 +1,67 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/store"
+)
+
+// Probe is the minimal configuration required to read a metric off an HTTP endpoint
+type Probe struct {
+	StoreType     string // "http" or "prometheus"
+	URL           string
+	Headers       map[string]string
+	TimeoutMillis int
+	CacheMillis   int
+	Extractor     ValueExtractor
+}
+
+// NewProbes returns probes of given URLs, sharing the settings of given probe template
+func NewProbes(urls []string, extractor ValueExtractor, probeTemplate Probe) (probes []store.Prober) {
+	for _, u := range urls {
+		probes = append(probes, &Probe{
+			StoreType:     probeTemplate.StoreType,
+			URL:           u,
+			Headers:       probeTemplate.Headers,
+			TimeoutMillis: probeTemplate.TimeoutMillis,
+			CacheMillis:   probeTemplate.CacheMillis,
+			Extractor:     extractor,
+		})
+	}
+	return probes
+}
+
+func (probe *Probe) String() string {
+	return probe.URL
+}
+
+// ProbeKey identifies the probed endpoint within its cluster
+func (probe *Probe) ProbeKey() string {
+	return probe.URL
+}
+
+// ProbeHostname returns the hostname of the probe's URL, by which the endpoint is subject to skipped hosts
+func (probe *Probe) ProbeHostname() string {
+	parsed, err := url.Parse(probe.URL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// ReadMetric reads the endpoint's metric, see ReadMetric
+func (probe *Probe) ReadMetric(clusterName string) base.MetricResult {
+	return ReadMetric(probe, clusterName)
+}
+
+// MetricHashKey identifies the metric of a given URL in a given cluster
+func MetricHashKey(clusterName string, url string) string {
+	return fmt.Sprintf("%s:%s", clusterName, url)
+}
@@ -0,0 +1,59 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/store"
+)
+
+const StoreType = "http"
+
+func init() {
+	store.Register(&storeDriver{})
+}
+
+// storeDriver is the "http" store driver: metrics read off HTTP endpoints via JSON path or regular expression
+type storeDriver struct{}
+
+func (driver *storeDriver) StoreType() string {
+	return StoreType
+}
+
+func (driver *storeDriver) Clusters() (clusters [](*store.Cluster)) {
+	for clusterName, clusterSettings := range config.Settings().Stores.HTTP.Clusters {
+		clusterSettings := clusterSettings
+		clusters = append(clusters, &store.Cluster{
+			Name:                 clusterName,
+			ThrottleThreshold:    clusterSettings.ThrottleThreshold,
+			IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
+			IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
+			IgnoreHosts:          clusterSettings.IgnoreHosts,
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return discover(clusterSettings)
+			}),
+		})
+	}
+	return clusters
+}
+
+// discover returns probes of a cluster's configured URLs
+func discover(clusterSettings *config.HTTPStoreClusterConfigurationSettings) ([]store.Prober, error) {
+	var extractor ValueExtractor = &JSONPathExtractor{Path: clusterSettings.JSONPath}
+	if clusterSettings.Regexp != "" {
+		regexpExtractor, err := NewRegexpExtractor(clusterSettings.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		extractor = regexpExtractor
+	}
+	return NewProbes(clusterSettings.URLs, extractor, Probe{
+		StoreType:     StoreType,
+		Headers:       clusterSettings.Headers,
+		TimeoutMillis: clusterSettings.TimeoutMillis,
+		CacheMillis:   clusterSettings.CacheMillis,
+	}), nil
+}
@@ -0,0 +1,33 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package httpstore
+
+import (
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestProbeHostname(t *testing.T) {
+	test.S(t).ExpectEquals((&Probe{URL: "http://queue-1.example.com:8080/stats"}).ProbeHostname(), "queue-1.example.com")
+	test.S(t).ExpectEquals((&Probe{URL: "https://10.0.0.1/lag"}).ProbeHostname(), "10.0.0.1")
+	test.S(t).ExpectEquals((&Probe{URL: "http://[::1]:8080/stats"}).ProbeHostname(), "::1")
+}
+
+func TestStoreDriverClusters(t *testing.T) {
+	clusters := config.Settings().Stores.HTTP.Clusters
+	defer func() { config.Settings().Stores.HTTP.Clusters = clusters }()
+
+	config.Settings().Stores.HTTP.Clusters = map[string](*config.HTTPStoreClusterConfigurationSettings){
+		"queues": {URLs: []string{"http://queue-1:8080/stats"}, JSONPath: "depth", IgnoreHosts: []string{"queue-9"}},
+	}
+	driverClusters := (&storeDriver{}).Clusters()
+	test.S(t).ExpectEquals(len(driverClusters), 1)
+	test.S(t).ExpectEquals(len(driverClusters[0].IgnoreHosts), 1)
+	test.S(t).ExpectEquals(driverClusters[0].IgnoreHosts[0], "queue-9")
+}
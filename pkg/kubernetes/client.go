@@ -0,0 +1,254 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceAccountDir is where pods find their service account's credentials
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// client makes authenticated requests to an API server
+type client struct {
+	server     string
+	namespace  string // default namespace
+	tokenFile  string // re-read on each request, as service account tokens rotate
+	token      string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// kubeconfig is the subset of a kubeconfig file freno makes use of
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string
+		Cluster struct {
+			Server                   string
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		}
+	}
+	Users []struct {
+		Name string
+		User struct {
+			Token                 string
+			TokenFile             string `yaml:"tokenFile"`
+			Username              string
+			Password              string
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		}
+	}
+	Contexts []struct {
+		Name    string
+		Context struct {
+			Cluster   string
+			User      string
+			Namespace string
+		}
+	}
+}
+
+var clients = make(map[string](*client))
+var clientsMutex sync.Mutex
+
+// getClient returns a client per kubeconfig & context, or per in-cluster credentials when kubeconfigPath is empty.
+// Clients are created once, so that their connections are reused.
+func getClient(kubeconfigPath string, context string, timeout time.Duration) (*client, error) {
+	clientKey := fmt.Sprintf("%s|%s|%s", kubeconfigPath, context, timeout)
+
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if c, found := clients[clientKey]; found {
+		return c, nil
+	}
+	var c *client
+	var err error
+	if kubeconfigPath == "" {
+		c, err = newInClusterClient(timeout)
+	} else {
+		c, err = newKubeconfigClient(kubeconfigPath, context, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	clients[clientKey] = c
+	return c, nil
+}
+
+func newHTTPClient(tlsConfig *tls.Config, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// newInClusterClient authenticates with the service account of the pod freno runs in
+func newInClusterClient(timeout time.Duration) (*client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("Not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST, KUBERNETES_SERVICE_PORT undefined. Provide a Kubeconfig")
+	}
+	caCert, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("No certificates found in %s", filepath.Join(serviceAccountDir, "ca.crt"))
+	}
+	c := &client{
+		server:     "https://" + net.JoinHostPort(host, port),
+		namespace:  "default",
+		tokenFile:  filepath.Join(serviceAccountDir, "token"),
+		httpClient: newHTTPClient(&tls.Config{RootCAs: rootCAs}, timeout),
+	}
+	if namespace, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		c.namespace = strings.TrimSpace(string(namespace))
+	}
+	return c, nil
+}
+
+// readFileOrData returns given base64 encoded data if non empty, or otherwise the content of given file,
+// relative to the kubeconfig's directory
+func readFileOrData(data string, path string, baseDir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return os.ReadFile(path)
+}
+
+// newKubeconfigClient authenticates with the cluster & user of a kubeconfig context
+func newKubeconfigClient(kubeconfigPath string, context string, timeout time.Duration) (*client, error) {
+	content, err := os.ReadFile(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	var config kubeconfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("Unable to parse kubeconfig %s: %+v", kubeconfigPath, err)
+	}
+	if context == "" {
+		context = config.CurrentContext
+	}
+	c := &client{namespace: "default"}
+	clusterName, userName := "", ""
+	for _, namedContext := range config.Contexts {
+		if namedContext.Name == context {
+			clusterName, userName = namedContext.Context.Cluster, namedContext.Context.User
+			if namedContext.Context.Namespace != "" {
+				c.namespace = namedContext.Context.Namespace
+			}
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("Context %s not found in kubeconfig %s", context, kubeconfigPath)
+	}
+	baseDir := filepath.Dir(kubeconfigPath)
+	tlsConfig := &tls.Config{}
+	for _, namedCluster := range config.Clusters {
+		if namedCluster.Name != clusterName {
+			continue
+		}
+		c.server = strings.TrimRight(namedCluster.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = namedCluster.Cluster.InsecureSkipTLSVerify
+		caCert, err := readFileOrData(namedCluster.Cluster.CertificateAuthorityData, namedCluster.Cluster.CertificateAuthority, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(caCert) > 0 {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("No certificate authority found for cluster %s", clusterName)
+			}
+		}
+	}
+	if c.server == "" {
+		return nil, fmt.Errorf("Cluster %s not found in kubeconfig %s", clusterName, kubeconfigPath)
+	}
+	for _, namedUser := range config.Users {
+		if namedUser.Name != userName {
+			continue
+		}
+		user := namedUser.User
+		c.token, c.username, c.password = user.Token, user.Username, user.Password
+		if user.TokenFile != "" {
+			c.tokenFile = user.TokenFile
+			if !filepath.IsAbs(c.tokenFile) {
+				c.tokenFile = filepath.Join(baseDir, c.tokenFile)
+			}
+		}
+		clientCert, err := readFileOrData(user.ClientCertificateData, user.ClientCertificate, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		clientKey, err := readFileOrData(user.ClientKeyData, user.ClientKey, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(clientCert) > 0 {
+			certificate, err := tls.X509KeyPair(clientCert, clientKey)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+	}
+	c.httpClient = newHTTPClient(tlsConfig, timeout)
+	return c, nil
+}
+
+// get requests an API path, and decodes the JSON response onto given value
+func (c *client) get(path string, value interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return err
+	}
+	token := c.token
+	if c.tokenFile != "" {
+		content, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+	request.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %v", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
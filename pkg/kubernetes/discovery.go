@@ -0,0 +1,140 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+)
+
+// endpoints is the subset of a v1 Endpoints object freno makes use of
+type endpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP string
+		}
+	}
+}
+
+// endpointSliceList is the subset of a discovery.k8s.io/v1 EndpointSliceList freno makes use of
+type endpointSliceList struct {
+	Items []struct {
+		AddressType string
+		Endpoints   []struct {
+			Addresses  []string
+			Conditions struct {
+				Ready *bool
+			}
+		}
+	}
+}
+
+// podList is the subset of a v1 PodList freno makes use of
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			DeletionTimestamp *string
+		}
+		Status struct {
+			PodIP      string
+			Conditions []struct {
+				Type   string
+				Status string
+			}
+		}
+	}
+}
+
+func readEndpointsAddresses(c *client, namespace string, service string) (addresses []string, err error) {
+	var result endpoints
+	if err := c.get(fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s", url.PathEscape(namespace), url.PathEscape(service)), &result); err != nil {
+		return addresses, err
+	}
+	// Addresses of pods which are not ready are listed as NotReadyAddresses, and are of no interest
+	for _, subset := range result.Subsets {
+		for _, address := range subset.Addresses {
+			addresses = append(addresses, address.IP)
+		}
+	}
+	return addresses, nil
+}
+
+func readEndpointSlicesAddresses(c *client, namespace string, service string) (addresses []string, err error) {
+	query := url.Values{}
+	query.Set("labelSelector", fmt.Sprintf("kubernetes.io/service-name=%s", service))
+	var result endpointSliceList
+	if err := c.get(fmt.Sprintf("/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", url.PathEscape(namespace), query.Encode()), &result); err != nil {
+		return addresses, err
+	}
+	for _, slice := range result.Items {
+		if slice.AddressType == "FQDN" {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition is to be interpreted as ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			// All addresses of an endpoint are fungible; the first one is all it takes
+			if len(endpoint.Addresses) > 0 {
+				addresses = append(addresses, endpoint.Addresses[0])
+			}
+		}
+	}
+	return addresses, nil
+}
+
+func readPodsAddresses(c *client, namespace string, labelSelector string) (addresses []string, err error) {
+	query := url.Values{}
+	query.Set("labelSelector", labelSelector)
+	var result podList
+	if err := c.get(fmt.Sprintf("/api/v1/namespaces/%s/pods?%s", url.PathEscape(namespace), query.Encode()), &result); err != nil {
+		return addresses, err
+	}
+	for _, pod := range result.Items {
+		if pod.Metadata.DeletionTimestamp != nil || pod.Status.PodIP == "" {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				addresses = append(addresses, pod.Status.PodIP)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// ReadHosts lists the ready addresses of a service's EndpointSlices or Endpoints, or of pods matching a label
+// selector, along with the settings' Port (which may be zero)
+func ReadHosts(settings *config.KubernetesConfigurationSettings) (hosts []config.HostPort, err error) {
+	c, err := getClient(settings.Kubeconfig, settings.Context, time.Duration(settings.TimeoutMillis)*time.Millisecond)
+	if err != nil {
+		return hosts, err
+	}
+	namespace := settings.Namespace
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	var addresses []string
+	switch settings.Resource {
+	case config.KubernetesResourceEndpoints:
+		addresses, err = readEndpointsAddresses(c, namespace, settings.Service)
+	case config.KubernetesResourcePods:
+		addresses, err = readPodsAddresses(c, namespace, settings.LabelSelector)
+	default:
+		addresses, err = readEndpointSlicesAddresses(c, namespace, settings.Service)
+	}
+	if err != nil {
+		return hosts, err
+	}
+	sort.Strings(addresses)
+	for i, address := range addresses {
+		if i > 0 && address == addresses[i-1] {
+			continue
+		}
+		hosts = append(hosts, config.HostPort{Host: address, Port: settings.Port})
+	}
+	return hosts, nil
+}
@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// newFakeAPIServer serves Endpoints, EndpointSlices and pods of namespace "db", to requests bearing given token
+func newFakeAPIServer(token string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/db/endpoints/mysql-replicas":
+			fmt.Fprint(w, `{"subsets": [
+				{"addresses": [{"ip": "10.0.0.2"}, {"ip": "10.0.0.1"}], "notReadyAddresses": [{"ip": "10.0.0.9"}], "ports": [{"port": 3306}]}
+			]}`)
+		case "/apis/discovery.k8s.io/v1/namespaces/db/endpointslices":
+			if r.URL.Query().Get("labelSelector") != "kubernetes.io/service-name=mysql-replicas" {
+				fmt.Fprint(w, `{"items": []}`)
+				return
+			}
+			fmt.Fprint(w, `{"items": [
+				{"addressType": "IPv4", "endpoints": [
+					{"addresses": ["10.0.0.1"], "conditions": {"ready": true}},
+					{"addresses": ["10.0.0.3"]},
+					{"addresses": ["10.0.0.9"], "conditions": {"ready": false}}
+				]},
+				{"addressType": "IPv4", "endpoints": [
+					{"addresses": ["10.0.0.1"], "conditions": {"ready": true}}
+				]}
+			]}`)
+		case "/api/v1/namespaces/db/pods":
+			if r.URL.Query().Get("labelSelector") != "app=mysql,role=replica" {
+				fmt.Fprint(w, `{"items": []}`)
+				return
+			}
+			fmt.Fprint(w, `{"items": [
+				{"metadata": {"name": "mysql-0"}, "status": {"podIP": "10.0.0.4", "conditions": [{"type": "Ready", "status": "True"}]}},
+				{"metadata": {"name": "mysql-1"}, "status": {"podIP": "10.0.0.5", "conditions": [{"type": "Ready", "status": "False"}]}},
+				{"metadata": {"name": "mysql-2", "deletionTimestamp": "2026-01-01T00:00:00Z"}, "status": {"podIP": "10.0.0.6", "conditions": [{"type": "Ready", "status": "True"}]}},
+				{"metadata": {"name": "mysql-3"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func serverCAPEM(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func hostsString(hosts []config.HostPort) string {
+	tokens := []string{}
+	for _, host := range hosts {
+		tokens = append(tokens, host.String())
+	}
+	return strings.Join(tokens, ",")
+}
+
+func writeKubeconfig(t *testing.T, server *httptest.Server, token string) string {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ca.crt"), serverCAPEM(server), 0644)
+	os.WriteFile(filepath.Join(dir, "token"), []byte(token+"\n"), 0600)
+	kubeconfig := fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+current-context: other
+clusters:
+- name: test-cluster
+  cluster:
+    server: %s
+    certificate-authority: ca.crt
+- name: other-cluster
+  cluster:
+    server: https://127.0.0.1:1
+users:
+- name: freno
+  user:
+    tokenFile: token
+contexts:
+- name: other
+  context:
+    cluster: other-cluster
+    user: freno
+- name: test
+  context:
+    cluster: test-cluster
+    user: freno
+    namespace: db
+`, server.URL)
+	path := filepath.Join(dir, "kubeconfig")
+	os.WriteFile(path, []byte(kubeconfig), 0600)
+	return path
+}
+
+func TestReadHostsKubeconfig(t *testing.T) {
+	server := newFakeAPIServer("s3cr3t")
+	defer server.Close()
+	kubeconfigPath := writeKubeconfig(t, server, "s3cr3t")
+
+	settings := &config.KubernetesConfigurationSettings{
+		Service:       "mysql-replicas",
+		Resource:      config.KubernetesResourceEndpointSlices,
+		Port:          3306,
+		Kubeconfig:    kubeconfigPath,
+		Context:       "test",
+		TimeoutMillis: 1000,
+	}
+	{
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(hostsString(hosts), "10.0.0.1:3306,10.0.0.3:3306")
+	}
+	{
+		settings.Resource = config.KubernetesResourceEndpoints
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(hostsString(hosts), "10.0.0.1:3306,10.0.0.2:3306")
+	}
+	{
+		settings.Resource = config.KubernetesResourcePods
+		settings.LabelSelector = "app=mysql,role=replica"
+		settings.Port = 0
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(hostsString(hosts), "10.0.0.4:0")
+	}
+	{
+		settings.Namespace = "other"
+		_, err := ReadHosts(settings)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		settings.Namespace = ""
+		settings.Context = "no-such-context"
+		_, err := ReadHosts(settings)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadHostsInCluster(t *testing.T) {
+	clients = make(map[string](*client))
+	server := newFakeAPIServer("in-cluster-token")
+	defer server.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ca.crt"), serverCAPEM(server), 0644)
+	os.WriteFile(filepath.Join(dir, "token"), []byte("in-cluster-token"), 0600)
+	os.WriteFile(filepath.Join(dir, "namespace"), []byte("db"), 0644)
+	defer func(dir string) { serviceAccountDir = dir }(serviceAccountDir)
+	serviceAccountDir = dir
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	settings := &config.KubernetesConfigurationSettings{
+		Service:       "mysql-replicas",
+		Resource:      config.KubernetesResourceEndpoints,
+		Port:          3307,
+		TimeoutMillis: 1000,
+	}
+	{
+		hosts, err := ReadHosts(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(hostsString(hosts), "10.0.0.1:3307,10.0.0.2:3307")
+	}
+	{
+		// rotated token
+		os.WriteFile(filepath.Join(dir, "token"), []byte("rotated-token"), 0600)
+		_, err := ReadHosts(settings)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadHostsNotInCluster(t *testing.T) {
+	clients = make(map[string](*client))
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	settings := &config.KubernetesConfigurationSettings{
+		Service:       "mysql-replicas",
+		TimeoutMillis: 1000,
+	}
+	_, err := ReadHosts(settings)
+	test.S(t).ExpectNotNil(err)
+}
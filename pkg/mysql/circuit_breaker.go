@@ -0,0 +1,121 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+type CircuitBreakerState string
+
+const (
+	CircuitClosed   CircuitBreakerState = "closed"    // host is reachable, probes run as usual
+	CircuitOpen     CircuitBreakerState = "open"      // host is unreachable, probes are skipped until backoff expires
+	CircuitHalfOpen CircuitBreakerState = "half-open" // backoff expired, a single trial probe is running
+)
+
+// circuitBreakerFailureThreshold is the number of consecutive connection failures which open the circuit
+const circuitBreakerFailureThreshold = 3
+
+// circuitBreakerMinBackoff and circuitBreakerMaxBackoff bound the time an open circuit waits before a trial probe.
+// The backoff doubles with each failed trial.
+const circuitBreakerMinBackoff = 500 * time.Millisecond
+const circuitBreakerMaxBackoff = 30 * time.Second
+
+// CircuitOpenError is returned for probes of a host whose circuit is open
+type CircuitOpenError struct {
+	LastErr error
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open; retrying at %s; last error: %+v", e.RetryAt.Format(time.RFC3339Nano), e.LastErr)
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return e.LastErr
+}
+
+// isConnectionError returns true for errors indicating a host cannot be reached or does not respond,
+// as opposed to errors returned by a responsive server
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, gomysql.ErrInvalidConn)
+}
+
+// circuitBreaker tracks connection failures to a single host. It is not safe for concurrent use.
+type circuitBreaker struct {
+	state               CircuitBreakerState
+	consecutiveFailures int
+	backoff             time.Duration
+	openUntil           time.Time
+	lastErr             error
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{state: CircuitClosed}
+}
+
+// allow returns nil when a probe may run, or otherwise a CircuitOpenError. Once the backoff expires, the circuit
+// turns half-open and a single trial probe is allowed.
+func (breaker *circuitBreaker) allow(now time.Time) error {
+	switch breaker.state {
+	case CircuitOpen:
+		if now.Before(breaker.openUntil) {
+			return &CircuitOpenError{LastErr: breaker.lastErr, RetryAt: breaker.openUntil}
+		}
+		breaker.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// a trial probe is already running
+		return &CircuitOpenError{LastErr: breaker.lastErr, RetryAt: now}
+	}
+	return nil
+}
+
+// record registers the result of a probe, and returns true when the circuit changed state
+func (breaker *circuitBreaker) record(err error, now time.Time) (changed bool) {
+	if !isConnectionError(err) {
+		// Either success, or an error returned by a responsive server
+		changed = breaker.state != CircuitClosed
+		breaker.state = CircuitClosed
+		breaker.consecutiveFailures = 0
+		breaker.backoff = 0
+		breaker.lastErr = nil
+		return changed
+	}
+	breaker.consecutiveFailures++
+	breaker.lastErr = err
+	switch breaker.state {
+	case CircuitClosed:
+		if breaker.consecutiveFailures < circuitBreakerFailureThreshold {
+			return false
+		}
+		breaker.backoff = circuitBreakerMinBackoff
+	case CircuitHalfOpen:
+		breaker.backoff = breaker.backoff * 2
+		if breaker.backoff > circuitBreakerMaxBackoff {
+			breaker.backoff = circuitBreakerMaxBackoff
+		}
+	}
+	changed = breaker.state != CircuitOpen
+	breaker.state = CircuitOpen
+	breaker.openUntil = now.Add(breaker.backoff)
+	return changed
+}
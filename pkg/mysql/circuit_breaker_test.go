@@ -0,0 +1,100 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+var dialError = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestIsConnectionError(t *testing.T) {
+	test.S(t).ExpectFalse(isConnectionError(nil))
+	test.S(t).ExpectFalse(isConnectionError(errors.New("replication not running")))
+	test.S(t).ExpectTrue(isConnectionError(dialError))
+	test.S(t).ExpectTrue(isConnectionError(fmt.Errorf("wrapped: %w", dialError)))
+	test.S(t).ExpectTrue(isConnectionError(context.DeadlineExceeded))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := newCircuitBreaker()
+	now := time.Now()
+
+	test.S(t).ExpectNil(breaker.allow(now))
+
+	// non-connection errors do not count
+	test.S(t).ExpectFalse(breaker.record(errors.New("replication not running"), now))
+	test.S(t).ExpectEquals(breaker.consecutiveFailures, 0)
+
+	for i := 1; i < circuitBreakerFailureThreshold; i++ {
+		test.S(t).ExpectFalse(breaker.record(dialError, now))
+		test.S(t).ExpectEquals(breaker.state, CircuitClosed)
+		test.S(t).ExpectNil(breaker.allow(now))
+	}
+	test.S(t).ExpectTrue(breaker.record(dialError, now))
+	test.S(t).ExpectEquals(breaker.state, CircuitOpen)
+	test.S(t).ExpectEquals(breaker.backoff, circuitBreakerMinBackoff)
+
+	err := breaker.allow(now.Add(circuitBreakerMinBackoff / 2))
+	test.S(t).ExpectNotNil(err)
+	var circuitOpenError *CircuitOpenError
+	test.S(t).ExpectTrue(errors.As(err, &circuitOpenError))
+	test.S(t).ExpectTrue(errors.Is(err, dialError))
+
+	// backoff expired: a single trial
+	now = now.Add(circuitBreakerMinBackoff)
+	test.S(t).ExpectNil(breaker.allow(now))
+	test.S(t).ExpectEquals(breaker.state, CircuitHalfOpen)
+	test.S(t).ExpectNotNil(breaker.allow(now))
+
+	// failed trial doubles the backoff
+	test.S(t).ExpectTrue(breaker.record(dialError, now))
+	test.S(t).ExpectEquals(breaker.state, CircuitOpen)
+	test.S(t).ExpectEquals(breaker.backoff, 2*circuitBreakerMinBackoff)
+	test.S(t).ExpectNotNil(breaker.allow(now.Add(circuitBreakerMinBackoff)))
+
+	// backoff is bounded
+	for i := 0; i < 20; i++ {
+		now = now.Add(circuitBreakerMaxBackoff)
+		test.S(t).ExpectNil(breaker.allow(now))
+		breaker.record(dialError, now)
+	}
+	test.S(t).ExpectEquals(breaker.backoff, circuitBreakerMaxBackoff)
+
+	// successful trial closes the circuit
+	now = now.Add(circuitBreakerMaxBackoff)
+	test.S(t).ExpectNil(breaker.allow(now))
+	test.S(t).ExpectTrue(breaker.record(nil, now))
+	test.S(t).ExpectEquals(breaker.state, CircuitClosed)
+	test.S(t).ExpectEquals(breaker.consecutiveFailures, 0)
+	test.S(t).ExpectNil(breaker.allow(now))
+}
+
+func TestConnectionManagerCircuitBreaker(t *testing.T) {
+	manager := NewConnectionManager()
+	probe := NewProbe()
+	probe.Key = InstanceKey{Hostname: "unreachable", Port: 3306}
+
+	test.S(t).ExpectNil(manager.allow(&probe.Key))
+	_, err := manager.GetDB(probe)
+	test.S(t).ExpectNil(err)
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		manager.recordResult(&probe.Key, dialError)
+	}
+	test.S(t).ExpectNotNil(manager.allow(&probe.Key))
+
+	stats := manager.Stats()["unreachable:3306"]
+	test.S(t).ExpectEquals(stats.CircuitState, CircuitOpen)
+	test.S(t).ExpectEquals(stats.ConsecutiveFailures, circuitBreakerFailureThreshold)
+	test.S(t).ExpectEquals(stats.DialErrors, int64(circuitBreakerFailureThreshold))
+}
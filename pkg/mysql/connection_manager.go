@@ -0,0 +1,224 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/outbrain/golib/log"
+)
+
+// connectionPoolEvictionGracePeriod is the time a pool of a host which is no longer probed is kept open since
+// last used. This keeps pools of hosts which are used other than for probing, such as a heartbeat primary or
+// a group replication seed. Pools superseded by new connection settings are likewise retired once unused.
+const connectionPoolEvictionGracePeriod = 30 * time.Second
+
+// connectionPoolKey identifies a pool: a server may have several pools, e.g. when clusters probe it with
+// different credentials, or around credentials rotation
+type connectionPoolKey struct {
+	key   InstanceKey
+	dbUri string
+}
+
+// connectionPool is a connection pool to a single server, opened with a single DSN
+type connectionPool struct {
+	db         *sql.DB
+	lastUsedAt time.Time
+}
+
+// serverState is what is known about a server regardless of the pools connecting to it
+type serverState struct {
+	dialErrors int64
+	breaker    *circuitBreaker
+}
+
+// ConnectionPoolStats describes the state of a single server's connection pools
+type ConnectionPoolStats struct {
+	OpenConnections     int
+	InUse               int
+	Idle                int
+	DialErrors          int64
+	CircuitState        CircuitBreakerState
+	ConsecutiveFailures int
+}
+
+// ConnectionManager owns the connection pools to probed servers, one pool per server and DSN
+type ConnectionManager struct {
+	pools   map[connectionPoolKey](*connectionPool)
+	servers map[InstanceKey](*serverState)
+	mutex   sync.Mutex
+}
+
+var connectionManager = NewConnectionManager()
+
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{
+		pools:   make(map[connectionPoolKey](*connectionPool)),
+		servers: make(map[InstanceKey](*serverState)),
+	}
+}
+
+// GetDB returns the connection pool to the probe's server, per the probe's connection settings. When these
+// change, e.g. upon credentials rotation, a new pool is opened; the previous pool is left to queries in flight,
+// and closed by EvictPools once unused.
+func (manager *ConnectionManager) GetDB(probe *Probe) (*sql.DB, error) {
+	return manager.GetDriverDB("mysql", probe.Key, probe.GetDBUri("information_schema"))
+}
+
+// GetDriverDB returns the connection pool to given server via given database/sql driver and DSN, opening one
+// if there is none. This lets non-MySQL stores share pooling and eviction, as GetDB does for MySQL.
+func (manager *ConnectionManager) GetDriverDB(driverName string, key InstanceKey, dbUri string) (*sql.DB, error) {
+	poolKey := connectionPoolKey{key: key, dbUri: dbUri}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if _, found := manager.servers[key]; !found {
+		manager.servers[key] = &serverState{breaker: newCircuitBreaker()}
+	}
+	if pool, found := manager.pools[poolKey]; found {
+		pool.lastUsedAt = time.Now()
+		return pool.db, nil
+	}
+	db, err := sql.Open(driverName, dbUri)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxPoolConnections)
+	db.SetMaxIdleConns(maxIdleConnections)
+	manager.pools[poolKey] = &connectionPool{db: db, lastUsedAt: time.Now()}
+	return db, nil
+}
+
+// allow consults the server's circuit breaker, and returns a CircuitOpenError if the server is not to be probed
+func (manager *ConnectionManager) allow(key *InstanceKey) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if server, found := manager.servers[*key]; found {
+		return server.breaker.allow(time.Now())
+	}
+	return nil
+}
+
+// recordResult registers a probe's result with the server's circuit breaker
+func (manager *ConnectionManager) recordResult(key *InstanceKey, err error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	server, found := manager.servers[*key]
+	if !found {
+		return
+	}
+	if base.IsDialTcpError(err) {
+		server.dialErrors++
+	}
+	if changed := server.breaker.record(err, time.Now()); changed {
+		if server.breaker.state == CircuitOpen {
+			log.Errorf("circuit open for %+v after %d consecutive failures; backing off %+v; last error: %+v", *key, server.breaker.consecutiveFailures, server.breaker.backoff, err)
+		} else {
+			log.Infof("circuit closed for %+v", *key)
+		}
+	}
+}
+
+// isUnreachable returns true when the server's circuit is open or half-open on account of dial errors, i.e. the
+// server could not be reached, as opposed to being reached and failing to respond in time
+func (manager *ConnectionManager) isUnreachable(key *InstanceKey) bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	server, found := manager.servers[*key]
+	if !found {
+		return false
+	}
+	return server.breaker.state != CircuitClosed && base.IsDialTcpError(server.breaker.lastErr)
+}
+
+// EvictPools closes pools not used within the eviction grace period, which are either of servers not found in
+// given active keys, or superseded by a more recently used pool to the same server. It returns the keys of servers
+// whose pools were all closed.
+func (manager *ConnectionManager) EvictPools(activeKeys map[InstanceKey]bool) (evicted []InstanceKey) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	latestUsedAt := make(map[InstanceKey]time.Time)
+	for poolKey, pool := range manager.pools {
+		if pool.lastUsedAt.After(latestUsedAt[poolKey.key]) {
+			latestUsedAt[poolKey.key] = pool.lastUsedAt
+		}
+	}
+	for poolKey, pool := range manager.pools {
+		if time.Since(pool.lastUsedAt) < connectionPoolEvictionGracePeriod {
+			continue
+		}
+		if activeKeys[poolKey.key] && !pool.lastUsedAt.Before(latestUsedAt[poolKey.key]) {
+			continue
+		}
+		pool.db.Close()
+		delete(manager.pools, poolKey)
+	}
+	for key := range manager.servers {
+		if _, found := latestUsedAt[key]; !found {
+			continue
+		}
+		if !manager.hasPools(key) {
+			delete(manager.servers, key)
+			evicted = append(evicted, key)
+		}
+	}
+	return evicted
+}
+
+// hasPools returns true when given server has any open pool. The caller holds the mutex.
+func (manager *ConnectionManager) hasPools(key InstanceKey) bool {
+	for poolKey := range manager.pools {
+		if poolKey.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the stats of all servers' pools, mapped by server
+func (manager *ConnectionManager) Stats() map[string]ConnectionPoolStats {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	stats := make(map[string]ConnectionPoolStats)
+	for poolKey, pool := range manager.pools {
+		server, found := manager.servers[poolKey.key]
+		if !found {
+			continue
+		}
+		dbStats := pool.db.Stats()
+		serverStats := stats[poolKey.key.StringCode()]
+		serverStats.OpenConnections += dbStats.OpenConnections
+		serverStats.InUse += dbStats.InUse
+		serverStats.Idle += dbStats.Idle
+		serverStats.DialErrors = server.dialErrors
+		serverStats.CircuitState = server.breaker.state
+		serverStats.ConsecutiveFailures = server.breaker.consecutiveFailures
+		stats[poolKey.key.StringCode()] = serverStats
+	}
+	return stats
+}
+
+// EvictConnectionPools closes the pools of servers which are no longer probed
+func EvictConnectionPools(activeKeys map[InstanceKey]bool) {
+	for _, key := range connectionManager.EvictPools(activeKeys) {
+		log.Debugf("closed connection pool to %+v", key)
+	}
+}
+
+// ConnectionPoolsStats returns the stats of all connection pools to probed servers, mapped by server
+func ConnectionPoolsStats() map[string]ConnectionPoolStats {
+	return connectionManager.Stats()
+}
@@ -0,0 +1,115 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// setLastUsedAt backdates the pools of given server
+func setLastUsedAt(manager *ConnectionManager, key InstanceKey, lastUsedAt time.Time) {
+	for poolKey, pool := range manager.pools {
+		if poolKey.key == key {
+			pool.lastUsedAt = lastUsedAt
+		}
+	}
+}
+
+func TestConnectionManagerGetDB(t *testing.T) {
+	manager := NewConnectionManager()
+	probe := NewProbe()
+	probe.Key = InstanceKey{Hostname: "myhost", Port: 3306}
+	probe.User = "gromit"
+	probe.Password = "penguin"
+
+	db, err := manager.GetDB(probe)
+	test.S(t).ExpectNil(err)
+	sameDB, err := manager.GetDB(probe.Duplicate())
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(db == sameDB)
+
+	manager.recordResult(&probe.Key, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+	// rotated credentials open a new pool; the previous pool remains open for queries in flight
+	probe.Password = "wallace"
+	rotatedDB, err := manager.GetDB(probe)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(db != rotatedDB)
+	test.S(t).ExpectEquals(len(manager.pools), 2)
+
+	stats := manager.Stats()
+	test.S(t).ExpectEquals(len(stats), 1)
+	test.S(t).ExpectEquals(stats["myhost:3306"].DialErrors, int64(1))
+	test.S(t).ExpectEquals(stats["myhost:3306"].OpenConnections, 0)
+
+	// the previous pool is retired once unused for the grace period
+	for _, pool := range manager.pools {
+		if pool.db == db {
+			pool.lastUsedAt = time.Now().Add(-time.Hour)
+		}
+	}
+	evicted := manager.EvictPools(map[InstanceKey]bool{probe.Key: true})
+	test.S(t).ExpectEquals(len(evicted), 0)
+	test.S(t).ExpectEquals(len(manager.pools), 1)
+	test.S(t).ExpectNotNil(db.Ping())
+	sameDB, err = manager.GetDB(probe)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(sameDB == rotatedDB)
+	test.S(t).ExpectEquals(manager.Stats()["myhost:3306"].DialErrors, int64(1))
+}
+
+func TestConnectionManagerGetDBPerCredentials(t *testing.T) {
+	manager := NewConnectionManager()
+	key := InstanceKey{Hostname: "myhost", Port: 3306}
+	probe1 := &Probe{Key: key, User: "gromit", Password: "penguin"}
+	probe2 := &Probe{Key: key, User: "wallace", Password: "cheese"}
+
+	// two clusters probing the same server with different credentials each keep their pool
+	db1, err := manager.GetDB(probe1)
+	test.S(t).ExpectNil(err)
+	db2, err := manager.GetDB(probe2)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(db1 != db2)
+	sameDB1, err := manager.GetDB(probe1)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(db1 == sameDB1)
+	test.S(t).ExpectEquals(len(manager.Stats()), 1)
+}
+
+func TestConnectionManagerEvictPools(t *testing.T) {
+	manager := NewConnectionManager()
+	keys := []InstanceKey{
+		{Hostname: "replica1", Port: 3306},
+		{Hostname: "replica2", Port: 3306},
+		{Hostname: "primary", Port: 3306},
+	}
+	for _, key := range keys {
+		probe := NewProbe()
+		probe.Key = key
+		_, err := manager.GetDB(probe)
+		test.S(t).ExpectNil(err)
+	}
+	// replica2 is decommissioned; the primary is not probed, but recently used
+	setLastUsedAt(manager, keys[1], time.Now().Add(-time.Hour))
+	activeKeys := map[InstanceKey]bool{keys[0]: true}
+
+	evicted := manager.EvictPools(activeKeys)
+	test.S(t).ExpectEquals(len(evicted), 1)
+	test.S(t).ExpectTrue(evicted[0].Equals(&keys[1]))
+	test.S(t).ExpectEquals(len(manager.Stats()), 2)
+
+	setLastUsedAt(manager, keys[2], time.Now().Add(-time.Hour))
+	setLastUsedAt(manager, keys[0], time.Now().Add(-time.Hour))
+	evicted = manager.EvictPools(activeKeys)
+	test.S(t).ExpectEquals(len(evicted), 1)
+	test.S(t).ExpectTrue(evicted[0].Equals(&keys[2]))
+	test.S(t).ExpectEquals(len(manager.Stats()), 1)
+}
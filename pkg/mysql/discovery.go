@@ -0,0 +1,289 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/consul"
+	"github.com/github/freno/pkg/dns"
+	"github.com/github/freno/pkg/filehosts"
+	"github.com/github/freno/pkg/haproxy"
+	"github.com/github/freno/pkg/kubernetes"
+	"github.com/github/freno/pkg/orchestrator"
+	"github.com/github/freno/pkg/store"
+	"github.com/github/freno/pkg/vitess"
+
+	"github.com/outbrain/golib/log"
+)
+
+// discoverySource lists hosts of a cluster, e.g. off HAProxy or static settings
+type discoverySource struct {
+	name string
+	read func() ([]InstanceKey, error)
+}
+
+// discoveredKey is a discovered host, along with the names of the sources listing it
+type discoveredKey struct {
+	Key     InstanceKey
+	Sources []string
+}
+
+// discoverySources returns the cluster's configured sources, by precedence: HAProxy, ProxySQL, Vitess,
+// orchestrator, Kubernetes, Consul, DNS, group replication, topology crawl, hosts file, static settings
+func (driver *storeDriver) discoverySources(clusterName string, clusterSettings *config.MySQLClusterConfigurationSettings, user, password string) (sources []discoverySource) {
+	if !clusterSettings.HAProxySettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "haproxy", read: func() (keys []InstanceKey, err error) {
+			hosts, err := haproxy.ReadHosts(&clusterSettings.HAProxySettings)
+			if err != nil {
+				return keys, err
+			}
+			for _, host := range hosts {
+				keys = append(keys, InstanceKey{Hostname: host, Port: clusterSettings.Port})
+			}
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.ProxySQLSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "proxysql", read: func() (keys []InstanceKey, err error) {
+			proxysqlClient := driver.getProxySQLClient()
+			db, addr, err := proxysqlClient.GetDB(clusterSettings.ProxySQLSettings)
+			if err != nil {
+				log.Debugf("Unable to connect to ProxySQL: %v", err)
+				return keys, err
+			}
+
+			dsn := clusterSettings.ProxySQLSettings.AddressToDSN(addr)
+			log.Debugf("getting ProxySQL data from %s, hostgroup id: %d (%s)", dsn, clusterSettings.ProxySQLSettings.HostgroupID, clusterName)
+			servers, err := proxysqlClient.GetServers(db, clusterSettings.ProxySQLSettings)
+			if err != nil {
+				proxysqlClient.MarkFailed(addr)
+				return keys, fmt.Errorf("Unable to get hosts from ProxySQL %s: %+v", dsn, err)
+			}
+			log.Debugf("Read %+v hosts from ProxySQL %s, hostgroup id: %d (%s)", len(servers), dsn, clusterSettings.ProxySQLSettings.HostgroupID, clusterName)
+			for _, server := range servers {
+				keys = append(keys, InstanceKey{Hostname: server.Host, Port: int(server.Port)})
+			}
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.VitessSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "vitess", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("getting vitess data from %s", clusterSettings.VitessSettings.API)
+			keyspace := clusterSettings.VitessSettings.Keyspace
+			shard := clusterSettings.VitessSettings.Shard
+			tablets, err := vitess.ParseTablets(clusterSettings.VitessSettings)
+			if err != nil {
+				return keys, fmt.Errorf("Unable to get vitess hosts from %s, %s/%s: %+v", clusterSettings.VitessSettings.API, keyspace, shard, err)
+			}
+			log.Debugf("Read %+v hosts from vitess %s, %s/%s, cells=%s", len(tablets), clusterSettings.VitessSettings.API,
+				keyspace, shard, strings.Join(vitess.ParseCells(clusterSettings.VitessSettings), ","),
+			)
+			for _, tablet := range tablets {
+				keys = append(keys, InstanceKey{Hostname: tablet.MysqlHostname, Port: int(tablet.MysqlPort)})
+			}
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.OrchestratorSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "orchestrator", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("getting orchestrator topology from %s", clusterSettings.OrchestratorSettings.URL)
+			replicas, err := orchestrator.ReadReplicas(&clusterSettings.OrchestratorSettings)
+			if err != nil {
+				return keys, fmt.Errorf("Unable to get orchestrator hosts from %s: %+v", clusterSettings.OrchestratorSettings.URL, err)
+			}
+			log.Debugf("Read %+v hosts from orchestrator %s (%s)", len(replicas), clusterSettings.OrchestratorSettings.URL, clusterName)
+			for _, replica := range replicas {
+				keys = append(keys, InstanceKey{Hostname: replica.Key.Hostname, Port: replica.Key.Port})
+			}
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.KubernetesSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "kubernetes", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("getting kubernetes %s hosts (%s)", clusterSettings.KubernetesSettings.Resource, clusterName)
+			hosts, err := kubernetes.ReadHosts(&clusterSettings.KubernetesSettings)
+			if err != nil {
+				return keys, fmt.Errorf("Unable to get kubernetes %s hosts: %+v", clusterSettings.KubernetesSettings.Resource, err)
+			}
+			log.Debugf("Read %+v hosts from kubernetes %s (%s)", len(hosts), clusterSettings.KubernetesSettings.Resource, clusterName)
+			return hostPortsKeys(hosts, clusterSettings.Port), nil
+		}})
+	}
+
+	if !clusterSettings.ConsulSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "consul", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("getting consul service %s from %s", clusterSettings.ConsulSettings.Service, clusterSettings.ConsulSettings.Address)
+			hosts, err := consul.ReadHosts(&clusterSettings.ConsulSettings, func() { requestConsulClustersRefresh(&clusterSettings.ConsulSettings) })
+			if err != nil {
+				return keys, fmt.Errorf("Unable to get consul service %s hosts from %s: %+v", clusterSettings.ConsulSettings.Service, clusterSettings.ConsulSettings.Address, err)
+			}
+			log.Debugf("Read %+v hosts from consul service %s (%s)", len(hosts), clusterSettings.ConsulSettings.Service, clusterName)
+			return hostPortsKeys(hosts, clusterSettings.Port), nil
+		}})
+	}
+
+	if !clusterSettings.DNSSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "dns", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("resolving %s records of %s", clusterSettings.DNSSettings.RecordType, clusterSettings.DNSSettings.Name)
+			hosts, err := dns.ReadHosts(&clusterSettings.DNSSettings)
+			if err != nil {
+				return keys, fmt.Errorf("Unable to resolve %s records of %s: %+v", clusterSettings.DNSSettings.RecordType, clusterSettings.DNSSettings.Name, err)
+			}
+			log.Debugf("Read %+v hosts from DNS %s (%s)", len(hosts), clusterSettings.DNSSettings.Name, clusterName)
+			return hostPortsKeys(hosts, clusterSettings.Port), nil
+		}})
+	}
+
+	if !clusterSettings.GroupReplicationSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "group-replication", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("getting group replication members from %+v", clusterSettings.GroupReplicationSettings.SeedHosts)
+			keys, err = ReadGroupReplicationMembers(&clusterSettings.GroupReplicationSettings, user, password, &clusterSettings.TLSSettings, clusterSettings.Port)
+			if err != nil {
+				return keys, err
+			}
+			log.Debugf("Read %+v group replication members (%s)", len(keys), clusterName)
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.TopologySettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "topology", read: func() (keys []InstanceKey, err error) {
+			log.Debugf("crawling replicas from %+v", clusterSettings.TopologySettings.SeedHosts)
+			keys, err = ReadTopologyReplicas(&clusterSettings.TopologySettings, user, password, &clusterSettings.TLSSettings, clusterSettings.Port)
+			if err != nil {
+				return keys, err
+			}
+			log.Debugf("Read %+v replicas by topology crawl (%s)", len(keys), clusterName)
+			return keys, nil
+		}})
+	}
+
+	if !clusterSettings.FileHostsSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "file", read: func() (keys []InstanceKey, err error) {
+			path := clusterSettings.FileHostsSettings.Path
+			hosts, err := filehosts.ReadHosts(&clusterSettings.FileHostsSettings, func() { requestFileHostsClustersRefresh(path) })
+			if err != nil {
+				return keys, fmt.Errorf("Unable to read hosts file %s: %+v", path, err)
+			}
+			return parseHostsKeys(hosts, clusterSettings.Port)
+		}})
+	}
+
+	if !clusterSettings.StaticHostsSettings.IsEmpty() {
+		sources = append(sources, discoverySource{name: "static", read: func() (keys []InstanceKey, err error) {
+			return parseHostsKeys(clusterSettings.StaticHostsSettings.Hosts, clusterSettings.Port)
+		}})
+	}
+	return sources
+}
+
+// hostPortsKeys converts discovered hosts to instance keys, where a zero port stands for defaultPort
+func hostPortsKeys(hosts []config.HostPort, defaultPort int) (keys []InstanceKey) {
+	for _, host := range hosts {
+		key := InstanceKey{Hostname: host.Host, Port: host.Port}
+		if key.Port == 0 {
+			key.Port = defaultPort
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// parseHostsKeys parses "hostname" or "hostname:port" hosts into instance keys
+func parseHostsKeys(hosts []string, defaultPort int) (keys []InstanceKey, err error) {
+	for _, host := range hosts {
+		key, err := ParseInstanceKey(host, defaultPort)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// mergeDiscoveredKeys combines the keys read off several sources, in order of first appearance. A key listed
+// more than once is returned once, along with all the sources listing it. With intersect, only keys listed by
+// all sources are returned.
+func mergeDiscoveredKeys(sourcesNames []string, sourcesKeys [][]InstanceKey, intersect bool) (keys []discoveredKey) {
+	indexes := make(map[InstanceKey]int)
+	for i, sourceKeys := range sourcesKeys {
+		for _, key := range sourceKeys {
+			index, found := indexes[key]
+			if !found {
+				index = len(keys)
+				indexes[key] = index
+				keys = append(keys, discoveredKey{Key: key})
+			}
+			if sources := keys[index].Sources; len(sources) == 0 || sources[len(sources)-1] != sourcesNames[i] {
+				keys[index].Sources = append(keys[index].Sources, sourcesNames[i])
+			}
+		}
+	}
+	if !intersect {
+		return keys
+	}
+	var intersection []discoveredKey
+	for _, key := range keys {
+		if len(key.Sources) == len(sourcesNames) {
+			intersection = append(intersection, key)
+		}
+	}
+	return intersection
+}
+
+// discoverInstanceKeys reads the cluster's hosts off its configured sources. By default, the first source
+// by precedence applies. DiscoveryMode "union" or "intersection" combines the hosts of all sources, in which
+// case failing to read any of the sources fails the discovery.
+func (driver *storeDriver) discoverInstanceKeys(clusterName string, clusterSettings *config.MySQLClusterConfigurationSettings, user, password string) (keys []discoveredKey, err error) {
+	sources := driver.discoverySources(clusterName, clusterSettings, user, password)
+	if len(sources) == 0 {
+		return keys, fmt.Errorf("Could not find any hosts definition for cluster %s", clusterName)
+	}
+	if clusterSettings.DiscoveryMode != config.DiscoveryModeUnion && clusterSettings.DiscoveryMode != config.DiscoveryModeIntersection {
+		sources = sources[:1]
+	}
+	var sourcesNames []string
+	var sourcesKeys [][]InstanceKey
+	for _, source := range sources {
+		sourceKeys, err := source.read()
+		if err != nil {
+			return keys, err
+		}
+		sourcesNames = append(sourcesNames, source.name)
+		sourcesKeys = append(sourcesKeys, sourceKeys)
+	}
+	keys = mergeDiscoveredKeys(sourcesNames, sourcesKeys, clusterSettings.DiscoveryMode == config.DiscoveryModeIntersection)
+	if len(sources) > 1 {
+		log.Debugf("Merged %+v hosts off %s (%s, %s)", len(keys), strings.Join(sourcesNames, ","), clusterSettings.DiscoveryMode, clusterName)
+	}
+	return keys, nil
+}
+
+// requestFileHostsClustersRefresh asks for an early rediscovery of the clusters listing their hosts in given file
+func requestFileHostsClustersRefresh(path string) {
+	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
+		if clusterSettings.FileHostsSettings.Path == path {
+			store.RequestClusterRefresh(StoreType, clusterName)
+		}
+	}
+}
+
+// requestConsulClustersRefresh asks for an early rediscovery of the clusters sharing the Consul watcher of given settings
+func requestConsulClustersRefresh(settings *config.ConsulConfigurationSettings) {
+	watcherKey := consul.WatcherKey(settings)
+	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
+		if !clusterSettings.ConsulSettings.IsEmpty() && consul.WatcherKey(&clusterSettings.ConsulSettings) == watcherKey {
+			store.RequestClusterRefresh(StoreType, clusterName)
+		}
+	}
+}
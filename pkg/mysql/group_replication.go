@@ -0,0 +1,164 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+)
+
+const (
+	GroupMemberStateOnline   = "ONLINE"
+	GroupMemberRolePrimary   = "PRIMARY"
+	GroupMemberRoleSecondary = "SECONDARY"
+)
+
+// GroupMember is a row in performance_schema.replication_group_members
+type GroupMember struct {
+	ID    string
+	Key   InstanceKey
+	State string
+	Role  string // derived from group_replication_primary_member on MySQL 5.7, which has no MEMBER_ROLE
+}
+
+// groupReplicationQueueQuery returns the query reading the local member's backlog: transactions waiting for
+// certification, and, as of MySQL 8.0.2, remote transactions waiting to be applied
+func groupReplicationQueueQuery(version *ServerVersion) string {
+	if version.AtLeast(8, 0, 2) {
+		return `
+			select
+				count_transactions_in_queue + count_transactions_remote_in_applier_queue
+			from
+				performance_schema.replication_group_member_stats
+			where
+				member_id = @@global.server_uuid
+		`
+	}
+	return `
+		select
+			count_transactions_in_queue
+		from
+			performance_schema.replication_group_member_stats
+		where
+			member_id = @@global.server_uuid
+	`
+}
+
+// readGroupReplicationQueue reads the given member's flow control backlog
+func readGroupReplicationQueue(ctx context.Context, db *sql.DB, key *InstanceKey) (backlog float64, err error) {
+	version, err := readServerVersion(ctx, db, key)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.QueryRowContext(ctx, groupReplicationQueueQuery(version)).Scan(&backlog); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%+v is not a group replication member", *key)
+		}
+		return 0, err
+	}
+	return backlog, nil
+}
+
+func parseGroupMember(m sqlutils.RowMap) *GroupMember {
+	member := &GroupMember{
+		ID:    getRowMapString(m, "MEMBER_ID"),
+		State: getRowMapString(m, "MEMBER_STATE"),
+		Role:  getRowMapString(m, "MEMBER_ROLE"),
+	}
+	member.Key.Hostname = getRowMapString(m, "MEMBER_HOST")
+	member.Key.Port, _ = strconv.Atoi(getRowMapString(m, "MEMBER_PORT"))
+	return member
+}
+
+// filterGroupMembers returns the keys of ONLINE members, optionally only the SECONDARY ones
+func filterGroupMembers(members [](*GroupMember), onlySecondaries bool) (keys []InstanceKey) {
+	for _, member := range members {
+		if member.State != GroupMemberStateOnline {
+			continue
+		}
+		if onlySecondaries && member.Role != GroupMemberRoleSecondary {
+			continue
+		}
+		keys = append(keys, member.Key)
+	}
+	return keys
+}
+
+// readGroupPrimaryMember returns the MEMBER_ID of the group's primary, or an empty string in multi-primary mode
+func readGroupPrimaryMember(ctx context.Context, db *sql.DB) (primaryMemberID string, err error) {
+	err = queryRowsMap(ctx, db, `show global status like 'group_replication_primary_member'`, func(m sqlutils.RowMap) error {
+		primaryMemberID = m.GetString("Value")
+		return nil
+	})
+	return primaryMemberID, err
+}
+
+// setGroupMemberRoles sets the roles of members read off MySQL 5.7, which has no MEMBER_ROLE: in single-primary
+// mode, the primary is the given member, and all others are secondaries. In multi-primary mode, all are primaries.
+func setGroupMemberRoles(members [](*GroupMember), primaryMemberID string) {
+	for _, member := range members {
+		if primaryMemberID == "" || member.ID == primaryMemberID {
+			member.Role = GroupMemberRolePrimary
+		} else {
+			member.Role = GroupMemberRoleSecondary
+		}
+	}
+}
+
+func readGroupMembers(ctx context.Context, db *sql.DB) (members [](*GroupMember), err error) {
+	hasRoles := true
+	err = queryRowsMap(ctx, db, `select * from performance_schema.replication_group_members`, func(m sqlutils.RowMap) error {
+		if _, ok := m["MEMBER_ROLE"]; !ok {
+			hasRoles = false
+		}
+		members = append(members, parseGroupMember(m))
+		return nil
+	})
+	if err != nil || hasRoles || len(members) == 0 {
+		return members, err
+	}
+	primaryMemberID, err := readGroupPrimaryMember(ctx, db)
+	if err != nil {
+		return members, err
+	}
+	setGroupMemberRoles(members, primaryMemberID)
+	return members, nil
+}
+
+// ReadGroupReplicationMembers reads group membership from the first responsive seed host, and returns
+// the group's ONLINE members
+func ReadGroupReplicationMembers(settings *config.GroupReplicationConfigurationSettings, user, password string, tlsSettings *config.TLSConfigurationSettings, defaultPort int) (keys []InstanceKey, err error) {
+	for _, seedHost := range settings.SeedHosts {
+		seedKey, err := ParseInstanceKey(seedHost, defaultPort)
+		if err != nil {
+			return keys, err
+		}
+		db, err := getProbeDB(&Probe{Key: *seedKey, User: user, Password: password, TLSSettings: tlsSettings})
+		if err != nil {
+			log.Errorf("group replication: unable to connect to seed %+v: %+v", *seedKey, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		members, err := readGroupMembers(ctx, db)
+		cancel()
+		if err != nil {
+			log.Errorf("group replication: unable to read members from seed %+v: %+v", *seedKey, err)
+			continue
+		}
+		if len(members) == 0 {
+			continue
+		}
+		return filterGroupMembers(members, settings.OnlySecondaries), nil
+	}
+	return keys, fmt.Errorf("Unable to read group replication members from any of %+v", settings.SeedHosts)
+}
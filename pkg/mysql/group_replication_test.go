@@ -0,0 +1,104 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestReadGroupMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	mock.ExpectQuery(`select \* from performance_schema.replication_group_members`).WillReturnRows(
+		sqlmock.NewRows([]string{"CHANNEL_NAME", "MEMBER_ID", "MEMBER_HOST", "MEMBER_PORT", "MEMBER_STATE", "MEMBER_ROLE"}).
+			AddRow("group_replication_applier", "uuid-1", "gr1", 3306, "ONLINE", "PRIMARY").
+			AddRow("group_replication_applier", "uuid-2", "gr2", 3306, "ONLINE", "SECONDARY").
+			AddRow("group_replication_applier", "uuid-3", "gr3", 3306, "RECOVERING", "SECONDARY").
+			AddRow("group_replication_applier", "uuid-4", "gr4", 3307, "ONLINE", "SECONDARY"),
+	)
+	members, err := readGroupMembers(context.Background(), db)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(members), 4)
+	test.S(t).ExpectEquals(members[3].Key.StringCode(), "gr4:3307")
+	{
+		keys := filterGroupMembers(members, false)
+		test.S(t).ExpectEquals(len(keys), 3)
+		test.S(t).ExpectEquals(keys[0].StringCode(), "gr1:3306")
+		test.S(t).ExpectEquals(keys[1].StringCode(), "gr2:3306")
+		test.S(t).ExpectEquals(keys[2].StringCode(), "gr4:3307")
+	}
+	{
+		keys := filterGroupMembers(members, true)
+		test.S(t).ExpectEquals(len(keys), 2)
+		test.S(t).ExpectEquals(keys[0].StringCode(), "gr2:3306")
+		test.S(t).ExpectEquals(keys[1].StringCode(), "gr4:3307")
+	}
+}
+
+func TestReadGroupMembersMySQL57(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	columns := []string{"CHANNEL_NAME", "MEMBER_ID", "MEMBER_HOST", "MEMBER_PORT", "MEMBER_STATE"}
+	{
+		// single-primary mode: the primary is known via group_replication_primary_member
+		mock.ExpectQuery(`select \* from performance_schema.replication_group_members`).WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow("group_replication_applier", "uuid-1", "gr1", 3306, "ONLINE").
+				AddRow("group_replication_applier", "uuid-2", "gr2", 3306, "ONLINE"),
+		)
+		mock.ExpectQuery(`group_replication_primary_member`).WillReturnRows(
+			sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("group_replication_primary_member", "uuid-1"),
+		)
+		members, err := readGroupMembers(context.Background(), db)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(members[0].Role, GroupMemberRolePrimary)
+		test.S(t).ExpectEquals(members[1].Role, GroupMemberRoleSecondary)
+
+		keys := filterGroupMembers(members, true)
+		test.S(t).ExpectEquals(len(keys), 1)
+		test.S(t).ExpectEquals(keys[0].StringCode(), "gr2:3306")
+	}
+	{
+		// multi-primary mode: no secondaries
+		mock.ExpectQuery(`select \* from performance_schema.replication_group_members`).WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow("group_replication_applier", "uuid-1", "gr1", 3306, "ONLINE").
+				AddRow("group_replication_applier", "uuid-2", "gr2", 3306, "ONLINE"),
+		)
+		mock.ExpectQuery(`group_replication_primary_member`).WillReturnRows(
+			sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("group_replication_primary_member", ""),
+		)
+		members, err := readGroupMembers(context.Background(), db)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(filterGroupMembers(members, true)), 0)
+		test.S(t).ExpectEquals(len(filterGroupMembers(members, false)), 2)
+	}
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestReadGroupReplicationQueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	key := &InstanceKey{Hostname: "gr2", Port: 3306}
+	serverVersionCache.Delete(key.StringCode())
+	mock.ExpectQuery(`select @@global.version`).WillReturnRows(sqlmock.NewRows([]string{"@@global.version"}).AddRow("8.0.28"))
+	mock.ExpectQuery(`count_transactions_in_queue \+ count_transactions_remote_in_applier_queue`).WillReturnRows(sqlmock.NewRows([]string{"backlog"}).AddRow(17))
+
+	backlog, err := readGroupReplicationQueue(context.Background(), db, key)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(backlog, 17.0)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+)
+
+// heartbeatTimestampFormat is the pt-heartbeat compatible timestamp format, e.g. 2017-11-02T09:15:24.012345
+const heartbeatTimestampFormat = "%Y-%m-%dT%H:%i:%s.%f"
+
+// primaryRediscoverInterval is the time after which a discovered primary is looked up again
+const primaryRediscoverInterval = 10 * time.Second
+
+func buildHeartbeatLagQuery(settings *config.MySQLHeartbeatConfigurationSettings) (query string, args []interface{}) {
+	query = fmt.Sprintf(`select timestampdiff(microsecond, ts, utc_timestamp(6)) / 1000000 as lag from %s`, settings.TableName())
+	if settings.ServerId > 0 {
+		query = fmt.Sprintf("%s where server_id = ?", query)
+		args = append(args, settings.ServerId)
+	}
+	query = fmt.Sprintf("%s order by ts desc limit 1", query)
+	return query, args
+}
+
+// readHeartbeatLag reads sub-second replication lag off a pt-heartbeat style table
+func readHeartbeatLag(ctx context.Context, db *sql.DB, settings *config.MySQLHeartbeatConfigurationSettings) (lag float64, err error) {
+	if settings == nil {
+		return 0, fmt.Errorf("no heartbeat settings found")
+	}
+	query, args := buildHeartbeatLagQuery(settings)
+	var nullLag sql.NullFloat64
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&nullLag); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("no heartbeat found in %s", settings.TableName())
+		}
+		return 0, err
+	}
+	if !nullLag.Valid {
+		return 0, fmt.Errorf("invalid heartbeat timestamp in %s", settings.TableName())
+	}
+	return nullLag.Float64, nil
+}
+
+func buildHeartbeatWriteQuery(settings *config.MySQLHeartbeatConfigurationSettings) (query string, args []interface{}) {
+	query = fmt.Sprintf(`
+		insert into %s (ts, server_id) values (date_format(utc_timestamp(6), ?), @@global.server_id)
+		on duplicate key update ts=values(ts)
+	`, settings.TableName())
+	return query, []interface{}{heartbeatTimestampFormat}
+}
+
+// readPrimaryKey reads the replication source of given server
+func readPrimaryKey(ctx context.Context, db *sql.DB, key *InstanceKey) (primaryKey *InstanceKey, err error) {
+	statuses, err := readReplicationStatus(ctx, db, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.SourceKey.IsValid() {
+			return &status.SourceKey, nil
+		}
+	}
+	return nil, fmt.Errorf("no replication source found")
+}
+
+// HeartbeatWriter writes heartbeats onto a cluster's primary
+type HeartbeatWriter struct {
+	ClusterName       string
+	User              string
+	Password          string
+	HeartbeatSettings *config.MySQLHeartbeatConfigurationSettings
+	TLSSettings       *config.TLSConfigurationSettings
+	WriteInProgress   int64
+
+	primaryKey          *InstanceKey
+	primaryDiscoveredAt time.Time
+	lastWrittenAt       time.Time
+}
+
+func NewHeartbeatWriter(clusterName string) *HeartbeatWriter {
+	return &HeartbeatWriter{ClusterName: clusterName}
+}
+
+// getPrimaryKey returns the configured primary, or otherwise discovers the primary by asking
+// any of the given replicas for their replication source
+func (writer *HeartbeatWriter) getPrimaryKey(replicas *Probes) (*InstanceKey, error) {
+	if writer.HeartbeatSettings.PrimaryHost != "" {
+		return ParseInstanceKey(writer.HeartbeatSettings.PrimaryHost, DefaultMySQLPort)
+	}
+	if writer.primaryKey != nil && time.Since(writer.primaryDiscoveredAt) < primaryRediscoverInterval {
+		return writer.primaryKey, nil
+	}
+	if replicas == nil {
+		return nil, fmt.Errorf("no replicas to discover primary from")
+	}
+	for _, replica := range *replicas {
+		db, err := getProbeDB(replica)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		primaryKey, err := readPrimaryKey(ctx, db, &replica.Key)
+		cancel()
+		if err != nil {
+			continue
+		}
+		log.Debugf("heartbeat: discovered primary %+v for cluster %s via %+v", *primaryKey, writer.ClusterName, replica.Key)
+		writer.primaryKey = primaryKey
+		writer.primaryDiscoveredAt = time.Now()
+		return primaryKey, nil
+	}
+	return nil, fmt.Errorf("unable to discover primary for cluster %s", writer.ClusterName)
+}
+
+// Write writes a heartbeat onto the primary, unless one was written within the configured interval.
+// It is not safe for concurrent use; callers are expected to use WriteInProgress as guard.
+func (writer *HeartbeatWriter) Write(replicas *Probes) error {
+	interval := time.Duration(writer.HeartbeatSettings.IntervalMillis) * time.Millisecond
+	if time.Since(writer.lastWrittenAt) < interval {
+		return nil
+	}
+	primaryKey, err := writer.getPrimaryKey(replicas)
+	if err != nil {
+		return err
+	}
+	probe := &Probe{Key: *primaryKey, User: writer.User, Password: writer.Password, TLSSettings: writer.TLSSettings}
+	db, err := getProbeDB(probe)
+	if err != nil {
+		return err
+	}
+	query, args := buildHeartbeatWriteQuery(writer.HeartbeatSettings)
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		// primary may have changed
+		writer.primaryKey = nil
+		return err
+	}
+	writer.lastWrittenAt = time.Now()
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestBuildHeartbeatLagQuery(t *testing.T) {
+	{
+		settings := &config.MySQLHeartbeatConfigurationSettings{Schema: "meta", Table: "heartbeat"}
+		query, args := buildHeartbeatLagQuery(settings)
+		test.S(t).ExpectEquals(query, "select timestampdiff(microsecond, ts, utc_timestamp(6)) / 1000000 as lag from `meta`.`heartbeat` order by ts desc limit 1")
+		test.S(t).ExpectEquals(len(args), 0)
+	}
+	{
+		settings := &config.MySQLHeartbeatConfigurationSettings{Schema: "percona", Table: "hb", ServerId: 17}
+		query, args := buildHeartbeatLagQuery(settings)
+		test.S(t).ExpectEquals(query, "select timestampdiff(microsecond, ts, utc_timestamp(6)) / 1000000 as lag from `percona`.`hb` where server_id = ? order by ts desc limit 1")
+		test.S(t).ExpectEquals(len(args), 1)
+		test.S(t).ExpectEquals(args[0], uint(17))
+	}
+}
+
+func TestHeartbeatWriterConfiguredPrimary(t *testing.T) {
+	writer := NewHeartbeatWriter("c0")
+	writer.HeartbeatSettings = &config.MySQLHeartbeatConfigurationSettings{PrimaryHost: "my-primary:3307"}
+	key, err := writer.getPrimaryKey(nil)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(key.StringCode(), "my-primary:3307")
+}
+
+func TestHeartbeatWriterNoReplicas(t *testing.T) {
+	writer := NewHeartbeatWriter("c0")
+	writer.HeartbeatSettings = &config.MySQLHeartbeatConfigurationSettings{}
+	_, err := writer.getPrimaryKey(nil)
+	test.S(t).ExpectNotNil(err)
+}
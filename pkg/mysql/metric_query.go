@@ -0,0 +1,150 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// rateMinSampleInterval is the minimal time between two counter samples from which a rate is computed.
+// Probes are frequent; computing rates over very short intervals would make for a noisy metric.
+const rateMinSampleInterval = time.Second
+
+// rateSamplesCache keeps the latest counter sample per probe. Samples of probes no longer collected expire.
+var rateSamplesCache = cache.New(time.Minute, time.Minute)
+
+type rateSample struct {
+	Value     float64
+	SampledAt time.Time
+	Rate      float64
+	HasRate   bool
+}
+
+// isSupportedMetricQuery returns true for queries freno knows how to read a value from
+func isSupportedMetricQuery(metricQuery string) bool {
+	metricQuery = strings.ToLower(strings.TrimSpace(metricQuery))
+	return strings.HasPrefix(metricQuery, "select") || strings.HasPrefix(metricQuery, "show global")
+}
+
+// aggregateMetricValues aggregates values by given aggregation. No aggregation means the first value applies.
+func aggregateMetricValues(values []float64, aggregation string) (float64, error) {
+	if len(values) == 0 {
+		return 0, sql.ErrNoRows
+	}
+	switch aggregation {
+	case config.MySQLMetricAggregationNone:
+		return values[0], nil
+	case config.MySQLMetricAggregationMax:
+		result := values[0]
+		for _, value := range values {
+			if value > result {
+				result = value
+			}
+		}
+		return result, nil
+	case config.MySQLMetricAggregationSum, config.MySQLMetricAggregationAvg:
+		result := 0.0
+		for _, value := range values {
+			result += value
+		}
+		if aggregation == config.MySQLMetricAggregationAvg {
+			result = result / float64(len(values))
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("Unsupported metric aggregation: %s", aggregation)
+}
+
+// readMetricQueryValues runs given query and returns the numeric value in the last column of each row.
+// For `select` queries this is typically the only column; for `show global ...` queries this is the `Value` column.
+// Without aggregation only the first row is read.
+func readMetricQueryValues(ctx context.Context, db *sql.DB, metricQuery string, aggregation string) (values []float64, err error) {
+	rows, err := db.QueryContext(ctx, metricQuery)
+	if err != nil {
+		return values, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return values, err
+	}
+	if len(columns) == 0 {
+		return values, fmt.Errorf("metric query returned no columns: %s", metricQuery)
+	}
+	for rows.Next() {
+		cells := make([]sql.NullString, len(columns))
+		cellPointers := make([]interface{}, len(columns))
+		for i := range cells {
+			cellPointers[i] = &cells[i]
+		}
+		if err := rows.Scan(cellPointers...); err != nil {
+			return values, err
+		}
+		valueCell := cells[len(cells)-1]
+		if !valueCell.Valid {
+			return values, fmt.Errorf("metric query returned NULL: %s", metricQuery)
+		}
+		value, err := strconv.ParseFloat(valueCell.String, 64)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+		if aggregation == config.MySQLMetricAggregationNone {
+			break
+		}
+	}
+	return values, rows.Err()
+}
+
+// readMetricQuery reads a value via given query, aggregated across rows
+func readMetricQuery(ctx context.Context, db *sql.DB, metricQuery string, aggregation string) (float64, error) {
+	values, err := readMetricQueryValues(ctx, db, metricQuery, aggregation)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateMetricValues(values, aggregation)
+}
+
+// errNoRateYet indicates a rate metric has no rate to report yet: on first sample, or after a counter reset
+var errNoRateYet = errors.New("rate: no rate computed yet")
+
+// computeRate returns the per-second rate of a monotonically increasing counter, based on the previous sample
+// kept under given key. A rate is only computed once enough time has passed since the previous sample; until
+// then, the previously computed rate is returned, or errNoRateYet if there is none.
+func computeRate(key string, value float64, now time.Time) (rate float64, err error) {
+	object, found := rateSamplesCache.Get(key)
+	if !found {
+		rateSamplesCache.SetDefault(key, &rateSample{Value: value, SampledAt: now})
+		return 0, errNoRateYet
+	}
+	previous := object.(*rateSample)
+	elapsed := now.Sub(previous.SampledAt)
+	if elapsed < rateMinSampleInterval {
+		if previous.HasRate {
+			return previous.Rate, nil
+		}
+		return 0, errNoRateYet
+	}
+	if value < previous.Value {
+		// counter reset, e.g. server restart
+		rateSamplesCache.SetDefault(key, &rateSample{Value: value, SampledAt: now})
+		return 0, errNoRateYet
+	}
+	rate = (value - previous.Value) / elapsed.Seconds()
+	rateSamplesCache.SetDefault(key, &rateSample{Value: value, SampledAt: now, Rate: rate, HasRate: true})
+	return rate, nil
+}
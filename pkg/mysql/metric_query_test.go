@@ -0,0 +1,141 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/github/freno/pkg/config"
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestIsSupportedMetricQuery(t *testing.T) {
+	test.S(t).ExpectTrue(isSupportedMetricQuery("select 1"))
+	test.S(t).ExpectTrue(isSupportedMetricQuery("  SELECT 1"))
+	test.S(t).ExpectTrue(isSupportedMetricQuery("show global status like 'Threads_running'"))
+	test.S(t).ExpectFalse(isSupportedMetricQuery("show slave status"))
+	test.S(t).ExpectFalse(isSupportedMetricQuery("delete from t"))
+}
+
+func TestAggregateMetricValues(t *testing.T) {
+	values := []float64{3, 7, 2}
+	{
+		value, err := aggregateMetricValues(values, config.MySQLMetricAggregationNone)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 3.0)
+	}
+	{
+		value, err := aggregateMetricValues(values, config.MySQLMetricAggregationMax)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 7.0)
+	}
+	{
+		value, err := aggregateMetricValues(values, config.MySQLMetricAggregationSum)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 12.0)
+	}
+	{
+		value, err := aggregateMetricValues(values, config.MySQLMetricAggregationAvg)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 4.0)
+	}
+	{
+		_, err := aggregateMetricValues(values, "median")
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := aggregateMetricValues(nil, config.MySQLMetricAggregationMax)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadMetricQueryShowGlobal(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	query := `show global status like 'Innodb_rows_%'`
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"Variable_name", "Value"}).
+			AddRow("Innodb_rows_deleted", "10").
+			AddRow("Innodb_rows_inserted", "25").
+			AddRow("Innodb_rows_updated", "5")
+	}
+	mock.ExpectQuery(`show global status`).WillReturnRows(rows())
+	value, err := readMetricQuery(context.Background(), db, query, config.MySQLMetricAggregationNone)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 10.0)
+
+	mock.ExpectQuery(`show global status`).WillReturnRows(rows())
+	value, err = readMetricQuery(context.Background(), db, query, config.MySQLMetricAggregationSum)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 40.0)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestReadMetricQuerySelect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	mock.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow("0.5").AddRow("1.5"))
+	value, err := readMetricQuery(context.Background(), db, "select lag from meta.lags", config.MySQLMetricAggregationMax)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 1.5)
+
+	mock.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"lag"}))
+	_, err = readMetricQuery(context.Background(), db, "select lag from meta.lags", config.MySQLMetricAggregationMax)
+	test.S(t).ExpectNotNil(err)
+
+	mock.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(nil))
+	_, err = readMetricQuery(context.Background(), db, "select lag from meta.lags", config.MySQLMetricAggregationNone)
+	test.S(t).ExpectNotNil(err)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestComputeRate(t *testing.T) {
+	key := "TestComputeRate"
+	rateSamplesCache.Delete(key)
+	now := time.Now()
+	{
+		_, err := computeRate(key, 100, now)
+		test.S(t).ExpectEquals(err, errNoRateYet)
+	}
+	{
+		// too soon, no rate computed yet
+		_, err := computeRate(key, 150, now.Add(500*time.Millisecond))
+		test.S(t).ExpectEquals(err, errNoRateYet)
+	}
+	{
+		rate, err := computeRate(key, 300, now.Add(2*time.Second))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(rate, 100.0)
+	}
+	{
+		// too soon, previous rate applies
+		rate, err := computeRate(key, 900, now.Add(2500*time.Millisecond))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(rate, 100.0)
+	}
+	{
+		rate, err := computeRate(key, 350, now.Add(4*time.Second))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(rate, 25.0)
+	}
+	{
+		// counter reset
+		_, err := computeRate(key, 10, now.Add(5*time.Second))
+		test.S(t).ExpectEquals(err, errNoRateYet)
+	}
+	{
+		rate, err := computeRate(key, 20, now.Add(6*time.Second))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(rate, 10.0)
+	}
+}
@@ -6,21 +6,59 @@
 package mysql
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/github/freno/pkg/config"
+
 	metrics "github.com/rcrowley/go-metrics"
 )
 
-var httpClient = http.Client{
-	Timeout: 1 * time.Second,
+// maxHttpCheckBodySize limits the size of a response body read for matching
+const maxHttpCheckBodySize = 1024 * 1024
+
+var defaultHttpCheckSettings = &config.HttpCheckConfigurationSettings{
+	Scheme:        "http",
+	TimeoutMillis: config.DefaultHttpCheckTimeoutMillis,
+	OnFail:        config.HttpCheckActionExclude,
+	OnError:       config.HttpCheckActionInclude,
+}
+
+// httpClients caches HTTP clients per timeout & TLS verification setting
+var httpClients = make(map[string](*http.Client))
+var httpClientsMutex sync.Mutex
+
+func getHttpClient(settings *config.HttpCheckConfigurationSettings) *http.Client {
+	clientKey := fmt.Sprintf("%d:%t", settings.TimeoutMillis, settings.SkipVerify)
+
+	httpClientsMutex.Lock()
+	defer httpClientsMutex.Unlock()
+
+	if client, found := httpClients[clientKey]; found {
+		return client
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: settings.SkipVerify}
+	httpClients[clientKey] = &http.Client{
+		Timeout:   time.Duration(settings.TimeoutMillis) * time.Millisecond,
+		Transport: transport,
+	}
+	return httpClients[clientKey]
 }
 
 type MySQLHttpCheck struct {
 	ClusterName string
 	Key         InstanceKey
-	CheckResult int
+	CheckResult int    // HTTP status, or http.StatusInternalServerError if the check could not complete
+	Action      string // one of config.HttpCheckAction*
+	Err         error  // reason for a failing or incomplete check
 }
 
 func NewMySQLHttpCheck(clusterName string, instanceKey *InstanceKey, checkResult int) *MySQLHttpCheck {
@@ -28,6 +66,7 @@ func NewMySQLHttpCheck(clusterName string, instanceKey *InstanceKey, checkResult
 		ClusterName: clusterName,
 		Key:         *instanceKey,
 		CheckResult: checkResult,
+		Action:      config.HttpCheckActionInclude,
 	}
 }
 
@@ -39,17 +78,87 @@ func MySQLHttpCheckHashKey(clusterName string, key *InstanceKey) string {
 	return fmt.Sprintf("%s:%s", clusterName, key.StringCode())
 }
 
+// lookupJSONPath returns the value found in given decoded JSON document by dotted path, e.g. "replication.running"
+func lookupJSONPath(document interface{}, path string) (value interface{}, found bool) {
+	value = document
+	for _, token := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, found = object[token]; !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// matchHttpCheckBody returns nil when given JSON body has all expected values
+func matchHttpCheckBody(body []byte, bodyMatch map[string]interface{}) error {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fmt.Errorf("invalid JSON body: %+v", err)
+	}
+	for path, expected := range bodyMatch {
+		value, found := lookupJSONPath(document, path)
+		if !found {
+			return fmt.Errorf("%s not found in body", path)
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return fmt.Errorf("%s: expected %+v, found %+v", path, expected, value)
+		}
+	}
+	return nil
+}
+
+// evaluateHttpCheck returns nil when given response passes the check
+func evaluateHttpCheck(settings *config.HttpCheckConfigurationSettings, status int, body io.Reader) error {
+	if !settings.IsExpectedStatus(status) {
+		return fmt.Errorf("unexpected status: %d", status)
+	}
+	if len(settings.BodyMatch) == 0 {
+		return nil
+	}
+	content, err := io.ReadAll(io.LimitReader(body, maxHttpCheckBodySize))
+	if err != nil {
+		return err
+	}
+	return matchHttpCheckBody(content, settings.BodyMatch)
+}
+
 func CheckHttp(clusterName string, probe *Probe) (httpCheckResult *MySQLHttpCheck) {
 	if probe.HttpCheckPort <= 0 {
 		go func() { metrics.GetOrRegisterCounter("httpcheck.skip", nil).Inc(1) }()
 		return NewMySQLHttpCheck(clusterName, &probe.Key, http.StatusOK)
 	}
-	url := fmt.Sprintf("http://%s:%d/%s", probe.Key.Hostname, probe.HttpCheckPort, probe.HttpCheckPath)
-	resp, err := httpClient.Get(url)
+	settings := probe.HttpCheckSettings
+	if settings == nil {
+		settings = defaultHttpCheckSettings
+	}
+	url := fmt.Sprintf("%s://%s:%d/%s", settings.Scheme, probe.Key.Hostname, probe.HttpCheckPort, probe.HttpCheckPath)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		httpCheckResult = NewMySQLHttpCheck(clusterName, &probe.Key, http.StatusInternalServerError)
+		httpCheckResult.Action, httpCheckResult.Err = settings.OnError, err
+		return httpCheckResult
+	}
+	for name, value := range settings.Headers {
+		request.Header.Set(name, value)
+	}
+	resp, err := getHttpClient(settings).Do(request)
 	if err != nil {
 		go func() { metrics.GetOrRegisterCounter("httpcheck.error", nil).Inc(1) }()
-		return NewMySQLHttpCheck(clusterName, &probe.Key, http.StatusInternalServerError)
+		httpCheckResult = NewMySQLHttpCheck(clusterName, &probe.Key, http.StatusInternalServerError)
+		httpCheckResult.Action, httpCheckResult.Err = settings.OnError, err
+		return httpCheckResult
 	}
+	defer resp.Body.Close()
 	go func() { metrics.GetOrRegisterCounter(fmt.Sprintf("httpcheck.%d", resp.StatusCode), nil).Inc(1) }()
-	return NewMySQLHttpCheck(clusterName, &probe.Key, resp.StatusCode)
+
+	httpCheckResult = NewMySQLHttpCheck(clusterName, &probe.Key, resp.StatusCode)
+	if err := evaluateHttpCheck(settings, resp.StatusCode, resp.Body); err != nil {
+		go func() { metrics.GetOrRegisterCounter("httpcheck.fail", nil).Inc(1) }()
+		httpCheckResult.Action, httpCheckResult.Err = settings.OnFail, err
+	}
+	return httpCheckResult
 }
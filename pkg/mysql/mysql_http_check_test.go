@@ -0,0 +1,147 @@
+/*
+   Copyright 2018 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+	test "github.com/outbrain/golib/tests"
+)
+
+func newHttpCheckTestProbe(t *testing.T, server *httptest.Server, settings *config.HttpCheckConfigurationSettings) *Probe {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	test.S(t).ExpectNil(err)
+	httpCheckPort, err := strconv.Atoi(port)
+	test.S(t).ExpectNil(err)
+	return &Probe{
+		Key:               InstanceKey{Hostname: host, Port: 3306},
+		HttpCheckPort:     httpCheckPort,
+		HttpCheckPath:     "status",
+		HttpCheckSettings: settings,
+	}
+}
+
+func TestMatchHttpCheckBody(t *testing.T) {
+	body := []byte(`{"role": "replica", "serving": true, "replication": {"running": true, "lag": 2}}`)
+	test.S(t).ExpectNil(matchHttpCheckBody(body, map[string]interface{}{"role": "replica", "serving": true}))
+	test.S(t).ExpectNil(matchHttpCheckBody(body, map[string]interface{}{"replication.running": true, "replication.lag": 2.0}))
+	test.S(t).ExpectNotNil(matchHttpCheckBody(body, map[string]interface{}{"serving": false}))
+	test.S(t).ExpectNotNil(matchHttpCheckBody(body, map[string]interface{}{"replication.io_running": true}))
+	test.S(t).ExpectNotNil(matchHttpCheckBody(body, map[string]interface{}{"role.name": "replica"}))
+	test.S(t).ExpectNotNil(matchHttpCheckBody([]byte("OK"), map[string]interface{}{"role": "replica"}))
+}
+
+func TestCheckHttpDefaultSettings(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.S(t).ExpectEquals(r.URL.Path, "/status")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	probe := newHttpCheckTestProbe(t, server, nil)
+	{
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusOK)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionInclude)
+	}
+	{
+		status = http.StatusServiceUnavailable
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusServiceUnavailable)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionInclude)
+	}
+	{
+		status = http.StatusNotFound
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionExclude)
+		test.S(t).ExpectNotNil(httpCheck.Err)
+	}
+	{
+		probe.HttpCheckPort = 0
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusOK)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionInclude)
+	}
+}
+
+func TestCheckHttpSettings(t *testing.T) {
+	body := `{"role": "replica", "serving": true}`
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	settings := &config.HttpCheckConfigurationSettings{
+		Scheme:           "https",
+		SkipVerify:       true,
+		Headers:          map[string]string{"Authorization": "Bearer token"},
+		TimeoutMillis:    500,
+		ExpectedStatuses: []int{http.StatusOK},
+		BodyMatch:        map[string]interface{}{"role": "replica", "serving": true},
+		OnFail:           config.HttpCheckActionExclude,
+		OnError:          config.HttpCheckActionError,
+	}
+	probe := newHttpCheckTestProbe(t, server, settings)
+	{
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectNil(httpCheck.Err)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionInclude)
+	}
+	{
+		// draining replica
+		body = `{"role": "replica", "serving": false}`
+		httpCheck := CheckHttp("c0", probe)
+		test.S(t).ExpectNotNil(httpCheck.Err)
+		test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusOK)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionExclude)
+	}
+	{
+		// missing header: unexpected status
+		unauthorizedSettings := *settings
+		unauthorizedSettings.Headers = nil
+		unauthorizedSettings.OnFail = config.HttpCheckActionError
+		httpCheck := CheckHttp("c0", newHttpCheckTestProbe(t, server, &unauthorizedSettings))
+		test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusUnauthorized)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionError)
+	}
+	{
+		// certificate not verified
+		verifySettings := *settings
+		verifySettings.SkipVerify = false
+		httpCheck := CheckHttp("c0", newHttpCheckTestProbe(t, server, &verifySettings))
+		test.S(t).ExpectNotNil(httpCheck.Err)
+		test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionError)
+	}
+}
+
+func TestCheckHttpTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	settings := &config.HttpCheckConfigurationSettings{
+		Scheme:        "http",
+		TimeoutMillis: 50,
+		OnFail:        config.HttpCheckActionExclude,
+		OnError:       config.HttpCheckActionExclude,
+	}
+	httpCheck := CheckHttp("c0", newHttpCheckTestProbe(t, server, settings))
+	test.S(t).ExpectNotNil(httpCheck.Err)
+	test.S(t).ExpectEquals(httpCheck.CheckResult, http.StatusInternalServerError)
+	test.S(t).ExpectEquals(httpCheck.Action, config.HttpCheckActionExclude)
+}
@@ -7,7 +7,6 @@ package mysql
 
 import (
 	"fmt"
-	"github.com/github/freno/pkg/base"
 )
 
 type ClusterInstanceKey struct {
@@ -23,24 +22,4 @@ func (c ClusterInstanceKey) HashCode() string {
 	return fmt.Sprintf("%s:%s", c.ClusterName, c.Key.StringCode())
 }
 
-type InstanceMetricResultMap map[ClusterInstanceKey]base.MetricResult
-type ClusterInstanceHttpCheckResultMap map[string]int
-
-type MySQLInventory struct {
-	ClustersProbes            map[string](*Probes)
-	IgnoreHostsCount          map[string]int
-	IgnoreHostsThreshold      map[string]float64
-	InstanceKeyMetrics        InstanceMetricResultMap
-	ClusterInstanceHttpChecks ClusterInstanceHttpCheckResultMap
-}
-
-func NewMySQLInventory() *MySQLInventory {
-	inventory := &MySQLInventory{
-		ClustersProbes:            make(map[string](*Probes)),
-		IgnoreHostsCount:          make(map[string]int),
-		IgnoreHostsThreshold:      make(map[string]float64),
-		InstanceKeyMetrics:        make(map[ClusterInstanceKey]base.MetricResult),
-		ClusterInstanceHttpChecks: make(map[string]int),
-	}
-	return inventory
-}
+type ClusterInstanceHttpCheckResultMap map[string](*MySQLHttpCheck)
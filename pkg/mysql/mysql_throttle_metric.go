@@ -6,11 +6,12 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 
-	"github.com/outbrain/golib/sqlutils"
+	"github.com/github/freno/pkg/config"
 	"github.com/patrickmn/go-cache"
 	metrics "github.com/rcrowley/go-metrics"
 )
@@ -18,7 +19,7 @@ import (
 var mysqlMetricCache = cache.New(cache.NoExpiration, 10*time.Millisecond)
 
 func getMySQLMetricCacheKey(probe *Probe) string {
-	return fmt.Sprintf("%s:%s", probe.Key, probe.MetricQuery)
+	return fmt.Sprintf("%s:%s:%s", probe.Key, probe.MetricType, probe.MetricQuery)
 }
 
 func cacheMySQLThrottleMetric(probe *Probe, mySQLThrottleMetric *MySQLThrottleMetric) *MySQLThrottleMetric {
@@ -42,6 +43,11 @@ func getCachedMySQLThrottleMetric(probe *Probe) *MySQLThrottleMetric {
 	return nil
 }
 
+// getProbeDB returns a (pooled) connection to the probe's server
+func getProbeDB(probe *Probe) (*sql.DB, error) {
+	return connectionManager.GetDB(probe)
+}
+
 type MySQLThrottleMetric struct {
 	ClusterName string
 	Key         InstanceKey
@@ -65,19 +71,52 @@ func (metric *MySQLThrottleMetric) Get() (float64, error) {
 	return metric.Value, metric.Err
 }
 
+// readProxySQLReplicationLag returns a server's replication lag as last checked by ProxySQL's monitor
+func readProxySQLReplicationLag(probe *Probe) (float64, error) {
+	if probe.ProxySQLClient == nil || probe.ProxySQLSettings == nil {
+		return 0, fmt.Errorf("No ProxySQL to read replication lag of %s off", probe.Key.DisplayString())
+	}
+	db, addr, err := probe.ProxySQLClient.GetDB(*probe.ProxySQLSettings)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	lag, err := probe.ProxySQLClient.GetServerReplicationLag(ctx, db, probe.Key.Hostname, int32(probe.Key.Port))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to read replication lag of %s off ProxySQL %s: %+v", probe.Key.DisplayString(), probe.ProxySQLSettings.AddressToDSN(addr), err)
+	}
+	return lag.Value(probe.ProxySQLSettings.GetReplicationLagMaxAge())
+}
+
 // ReadThrottleMetric returns replication lag for a given connection config; either by explicit query
-// or via SHOW SLAVE STATUS
+// (optionally aggregated across rows, optionally as a per-second rate),
+// by reading a heartbeat table, as monitored by ProxySQL, or via SHOW SLAVE STATUS / SHOW REPLICA STATUS
 func ReadThrottleMetric(probe *Probe, clusterName string) (mySQLThrottleMetric *MySQLThrottleMetric) {
 	if mySQLThrottleMetric := getCachedMySQLThrottleMetric(probe); mySQLThrottleMetric != nil {
 		return mySQLThrottleMetric
 		// On cached results we avoid taking latency metrics
 	}
 
-	started := time.Now()
 	mySQLThrottleMetric = NewMySQLThrottleMetric()
 	mySQLThrottleMetric.ClusterName = clusterName
 	mySQLThrottleMetric.Key = probe.Key
 
+	if probe.MetricType == config.MySQLMetricTypeProxySQLReplicationLag {
+		// The server itself is not connected to
+		mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readProxySQLReplicationLag(probe)
+		return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
+	}
+
+	if err := connectionManager.allow(&probe.Key); err != nil {
+		// Host known to be unreachable; avoid re-dialing it until backoff expires
+		go metrics.GetOrRegisterCounter("probes.circuit_open", nil).Inc(1)
+		mySQLThrottleMetric.Err = err
+		return mySQLThrottleMetric
+	}
+
+	started := time.Now()
+
 	defer func(metric *MySQLThrottleMetric, started time.Time) {
 		go func() {
 			metrics.GetOrRegisterTimer("probes.latency", nil).Update(time.Since(started))
@@ -88,44 +127,46 @@ func ReadThrottleMetric(probe *Probe, clusterName string) (mySQLThrottleMetric *
 		}()
 	}(mySQLThrottleMetric, started)
 
-	dbUri := probe.GetDBUri("information_schema")
-	db, fromCache, err := sqlutils.GetDB(dbUri)
+	defer func(metric *MySQLThrottleMetric) {
+		connectionManager.recordResult(&probe.Key, metric.Err)
+	}(mySQLThrottleMetric)
 
+	db, err := getProbeDB(probe)
 	if err != nil {
 		mySQLThrottleMetric.Err = err
 		return mySQLThrottleMetric
 	}
-	if !fromCache {
-		db.SetMaxOpenConns(maxPoolConnections)
-		db.SetMaxIdleConns(maxIdleConnections)
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	if probe.MetricType == config.MySQLMetricTypeHeartbeat {
+		mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readHeartbeatLag(ctx, db, probe.HeartbeatSettings)
+		return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
 	}
-	if strings.HasPrefix(strings.ToLower(probe.MetricQuery), "select") {
-		mySQLThrottleMetric.Err = db.QueryRow(probe.MetricQuery).Scan(&mySQLThrottleMetric.Value)
+	if probe.MetricQuery != "" {
+		if !isSupportedMetricQuery(probe.MetricQuery) {
+			mySQLThrottleMetric.Err = fmt.Errorf("Unsupported metrics query type: %s", probe.MetricQuery)
+			return mySQLThrottleMetric
+		}
+		mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readMetricQuery(ctx, db, probe.MetricQuery, probe.MetricAggregation)
+		if mySQLThrottleMetric.Err == nil && probe.MetricRate {
+			mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = computeRate(getMySQLMetricCacheKey(probe), mySQLThrottleMetric.Value, time.Now())
+		}
 		return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
 	}
 
-	if strings.HasPrefix(strings.ToLower(probe.MetricQuery), "show global") {
-		var variableName string // just a placeholder
-		mySQLThrottleMetric.Err = db.QueryRow(probe.MetricQuery).Scan(&variableName, &mySQLThrottleMetric.Value)
+	if probe.MetricType == config.MySQLMetricTypeGroupReplicationQueue {
+		mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readGroupReplicationQueue(ctx, db, &probe.Key)
 		return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
 	}
-
-	if probe.MetricQuery != "" {
-		mySQLThrottleMetric.Err = fmt.Errorf("Unsupported metrics query type: %s", probe.MetricQuery)
-		return mySQLThrottleMetric
+	if probe.MetricType == config.MySQLMetricTypeReplicationApplierLag {
+		mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readReplicationApplierLag(ctx, db, &probe.Key, probe.ReplicationChannels)
+		return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
 	}
 
-	// No metric query? By default we look at replication lag as output of SHOW SLAVE STATUS
+	// No metric query? By default we look at replication lag as output of SHOW SLAVE STATUS (or SHOW REPLICA STATUS),
+	// aggregated across replication channels
 
-	mySQLThrottleMetric.Err = sqlutils.QueryRowsMap(db, `show slave status`, func(m sqlutils.RowMap) error {
-		slaveIORunning := m.GetString("Slave_IO_Running")
-		slaveSQLRunning := m.GetString("Slave_SQL_Running")
-		secondsBehindMaster := m.GetNullInt64("Seconds_Behind_Master")
-		if !secondsBehindMaster.Valid {
-			return fmt.Errorf("replication not running; Slave_IO_Running=%+v, Slave_SQL_Running=%+v", slaveIORunning, slaveSQLRunning)
-		}
-		mySQLThrottleMetric.Value = float64(secondsBehindMaster.Int64)
-		return nil
-	})
+	mySQLThrottleMetric.Value, mySQLThrottleMetric.Err = readReplicationLag(ctx, db, &probe.Key, probe.ReplicationChannels)
 	return cacheMySQLThrottleMetric(probe, mySQLThrottleMetric)
 }
@@ -8,36 +8,44 @@ package mysql
 import (
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/github/freno/pkg/base"
 	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/proxysql"
 )
 
 const maxPoolConnections = 3
 const maxIdleConnections = 3
 const timeoutMillis = 1000
 
+// queryTimeout is the deadline for a single probe query, including obtaining a connection
+const queryTimeout = timeoutMillis * time.Millisecond
+
 // Probe is the minimal configuration required to connect to a MySQL server
 type Probe struct {
 	Key                 InstanceKey
+	Sources             []string // names of the discovery sources listing this server, e.g. "haproxy", "static"
 	User                string
 	Password            string
 	MetricQuery         string
+	MetricType          string
+	MetricAggregation   string
+	MetricRate          bool
+	HeartbeatSettings   *config.MySQLHeartbeatConfigurationSettings
+	ReplicationChannels []string
+	ProxySQLSettings    *config.ProxySQLConfigurationSettings // applies when MetricType is "proxysql_replication_lag"
+	ProxySQLClient      *proxysql.Client
+	TLSSettings         *config.TLSConfigurationSettings
 	CacheMillis         int
-	QueryInProgress     int64
 	HttpCheckPort       int
 	HttpCheckPath       string
+	HttpCheckSettings   *config.HttpCheckConfigurationSettings
 	HttpCheckInProgress int64
 }
 
 type Probes map[InstanceKey](*Probe)
 
-type ClusterProbes struct {
-	ClusterName          string
-	IgnoreHostsCount     int
-	IgnoreHostsThreshold float64
-	InstanceProbes       *Probes
-}
-
 func NewProbes() *Probes {
 	return &Probes{}
 }
@@ -52,9 +60,10 @@ func NewProbe() *Probe {
 // DuplicateCredentials creates a new connection config with given key and with same credentials as this config
 func (probe *Probe) DuplicateCredentials(key InstanceKey) *Probe {
 	config := &Probe{
-		Key:      key,
-		User:     probe.User,
-		Password: probe.Password,
+		Key:         key,
+		User:        probe.User,
+		Password:    probe.Password,
+		TLSSettings: probe.TLSSettings,
 	}
 	return config
 }
@@ -67,6 +76,20 @@ func (probe *Probe) String() string {
 	return fmt.Sprintf("%s, user=%s", probe.Key.DisplayString(), probe.User)
 }
 
+// ProbeKey identifies the probed server within its cluster
+func (probe *Probe) ProbeKey() string {
+	return probe.Key.StringCode()
+}
+
+func (probe *Probe) ProbeHostname() string {
+	return probe.Key.Hostname
+}
+
+// ReadMetric reads the server's throttle metric, see ReadThrottleMetric
+func (probe *Probe) ReadMetric(clusterName string) base.MetricResult {
+	return ReadThrottleMetric(probe, clusterName)
+}
+
 func (probe *Probe) Equals(other *Probe) bool {
 	return probe.Key.Equals(&other.Key)
 }
@@ -83,7 +106,7 @@ func (probe *Probe) GetDBUri(databaseName string) string {
 		// Set collation instead of charset, if Stores.MySQL.Collation is specified
 		dsnCharsetCollation = fmt.Sprintf("collation=%s", config.Settings().Stores.MySQL.Collation)
 	}
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?interpolateParams=true&%s&timeout=%dms",
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?interpolateParams=true&%s&timeout=%dms%s",
 		probe.User,
 		probe.Password,
 		hostname,
@@ -91,5 +114,6 @@ func (probe *Probe) GetDBUri(databaseName string) string {
 		databaseName,
 		dsnCharsetCollation,
 		timeoutMillis,
+		probe.TLSSettings.DSNParams(),
 	)
 }
@@ -65,4 +65,14 @@ func TestGetDBUri(t *testing.T) {
 	config.Settings().Stores.MySQL.Collation = "utf8mb4_unicode_ci"
 	dbUri = c.GetDBUri("test_database")
 	test.S(t).ExpectEquals(dbUri, "gromit:penguin@tcp(myhost:3306)/test_database?interpolateParams=true&collation=utf8mb4_unicode_ci&timeout=1000ms")
+
+	// test TLS
+	c.TLSSettings = &config.TLSConfigurationSettings{SkipVerify: true}
+	dbUri = c.GetDBUri("test_database")
+	test.S(t).ExpectEquals(dbUri, "gromit:penguin@tcp(myhost:3306)/test_database?interpolateParams=true&collation=utf8mb4_unicode_ci&timeout=1000ms&tls="+c.TLSSettings.ConfigName())
+
+	// TLS settings are shared by duplicates
+	dup := c.DuplicateCredentials(InstanceKey{Hostname: "otherhost", Port: 3306})
+	test.S(t).ExpectTrue(dup.TLSSettings == c.TLSSettings)
+	config.Settings().Stores.MySQL.Collation = ""
 }
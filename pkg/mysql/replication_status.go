@@ -0,0 +1,256 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/patrickmn/go-cache"
+)
+
+var serverVersionRegexp = regexp.MustCompile(`^([0-9]+)[.]([0-9]+)[.]([0-9]+)`)
+
+// serverVersionCache caches server versions per instance; a version only changes upon restart
+var serverVersionCache = cache.New(time.Minute, time.Minute)
+
+// ServerVersion is a MySQL server's numeric version, e.g. 8.0.23. MariaDB versions, e.g. 10.5.8, are numbered
+// independently of MySQL's, and are flagged as such.
+type ServerVersion struct {
+	Major   int
+	Minor   int
+	Patch   int
+	MariaDB bool
+}
+
+// ParseServerVersion parses a version such as "8.0.23", "5.7.31-log", "8.0.22-13" or "10.5.8-MariaDB-log"
+func ParseServerVersion(version string) (*ServerVersion, error) {
+	submatch := serverVersionRegexp.FindStringSubmatch(version)
+	if len(submatch) == 0 {
+		return nil, fmt.Errorf("Unable to parse server version: %s", version)
+	}
+	serverVersion := &ServerVersion{MariaDB: strings.Contains(version, "MariaDB")}
+	serverVersion.Major, _ = strconv.Atoi(submatch[1])
+	serverVersion.Minor, _ = strconv.Atoi(submatch[2])
+	serverVersion.Patch, _ = strconv.Atoi(submatch[3])
+	return serverVersion, nil
+}
+
+// AtLeast returns true when this version is equal to or newer than given version
+func (version *ServerVersion) AtLeast(major, minor, patch int) bool {
+	if version.Major != major {
+		return version.Major > major
+	}
+	if version.Minor != minor {
+		return version.Minor > minor
+	}
+	return version.Patch >= patch
+}
+
+// SupportsReplicaStatus returns true for versions supporting SHOW REPLICA STATUS (MySQL 8.0.22 and above,
+// MariaDB 10.5.1 and above)
+func (version *ServerVersion) SupportsReplicaStatus() bool {
+	if version.MariaDB {
+		return version.AtLeast(10, 5, 1)
+	}
+	return version.AtLeast(8, 0, 22)
+}
+
+func (version *ServerVersion) String() string {
+	if version.MariaDB {
+		return fmt.Sprintf("%d.%d.%d-MariaDB", version.Major, version.Minor, version.Patch)
+	}
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// readServerVersion returns the (cached) version of given server
+func readServerVersion(ctx context.Context, db *sql.DB, key *InstanceKey) (*ServerVersion, error) {
+	if version, found := serverVersionCache.Get(key.StringCode()); found {
+		return version.(*ServerVersion), nil
+	}
+	var versionString string
+	if err := db.QueryRowContext(ctx, `select @@global.version`).Scan(&versionString); err != nil {
+		return nil, err
+	}
+	version, err := ParseServerVersion(versionString)
+	if err != nil {
+		return nil, err
+	}
+	serverVersionCache.Set(key.StringCode(), version, cache.DefaultExpiration)
+	return version, nil
+}
+
+func replicationStatusQuery(version *ServerVersion) string {
+	if version.SupportsReplicaStatus() {
+		return `show replica status`
+	}
+	return `show slave status`
+}
+
+// ReplicationChannelStatus is the replication status of a single replication channel
+type ReplicationChannelStatus struct {
+	ChannelName         string
+	SourceKey           InstanceKey
+	IORunning           string
+	SQLRunning          string
+	SecondsBehindSource sql.NullInt64
+}
+
+// getRowMapString returns the value of the first existing column out of given names. This allows reading
+// both pre MySQL 8.0.22 (e.g. `Slave_IO_Running`) and newer (e.g. `Replica_IO_Running`) column names.
+func getRowMapString(m sqlutils.RowMap, names ...string) string {
+	for _, name := range names {
+		if _, ok := m[name]; ok {
+			return m.GetString(name)
+		}
+	}
+	return ""
+}
+
+// queryRowsMap runs given query within given context, invoking onRow per row
+func queryRowsMap(ctx context.Context, db *sql.DB, query string, onRow func(sqlutils.RowMap) error, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if err := sqlutils.ScanRowsToMaps(rows, onRow); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+func parseReplicationChannelStatus(m sqlutils.RowMap) *ReplicationChannelStatus {
+	status := &ReplicationChannelStatus{
+		ChannelName: getRowMapString(m, "Channel_Name"),
+		IORunning:   getRowMapString(m, "Replica_IO_Running", "Slave_IO_Running"),
+		SQLRunning:  getRowMapString(m, "Replica_SQL_Running", "Slave_SQL_Running"),
+	}
+	status.SourceKey.Hostname = getRowMapString(m, "Source_Host", "Master_Host")
+	status.SourceKey.Port, _ = strconv.Atoi(getRowMapString(m, "Source_Port", "Master_Port"))
+	if secondsBehind, err := strconv.ParseInt(getRowMapString(m, "Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64); err == nil {
+		status.SecondsBehindSource = sql.NullInt64{Int64: secondsBehind, Valid: true}
+	}
+	return status
+}
+
+// readReplicationStatus returns the replication status of all replication channels of given server
+func readReplicationStatus(ctx context.Context, db *sql.DB, key *InstanceKey) (statuses [](*ReplicationChannelStatus), err error) {
+	version, err := readServerVersion(ctx, db, key)
+	if err != nil {
+		return nil, err
+	}
+	err = queryRowsMap(ctx, db, replicationStatusQuery(version), func(m sqlutils.RowMap) error {
+		statuses = append(statuses, parseReplicationChannelStatus(m))
+		return nil
+	})
+	return statuses, err
+}
+
+// isChannelIncluded returns true when given channel passes the channels filter. An empty filter includes all channels
+func isChannelIncluded(channelName string, channels []string) bool {
+	if len(channels) == 0 {
+		return true
+	}
+	for _, channel := range channels {
+		if channel == channelName {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregateReplicationLag returns the maximum lag across given replication channels, filtered by given channels.
+// Any filtered channel where replication is not running results in an error. A server with no replication
+// channels, e.g. a primary, has no lag, unless specific channels were requested.
+func aggregateReplicationLag(statuses [](*ReplicationChannelStatus), channels []string) (lag float64, err error) {
+	countChannels := 0
+	for _, status := range statuses {
+		if !isChannelIncluded(status.ChannelName, channels) {
+			continue
+		}
+		countChannels++
+		if !status.SecondsBehindSource.Valid {
+			return 0, fmt.Errorf("replication not running on channel '%s'; IO_Running=%+v, SQL_Running=%+v", status.ChannelName, status.IORunning, status.SQLRunning)
+		}
+		if value := float64(status.SecondsBehindSource.Int64); value > lag {
+			lag = value
+		}
+	}
+	if countChannels == 0 && len(channels) > 0 {
+		return 0, fmt.Errorf("replication channels %+v not found", channels)
+	}
+	return lag, nil
+}
+
+// readReplicationLag reads replication lag via SHOW SLAVE STATUS or SHOW REPLICA STATUS, depending on server version
+func readReplicationLag(ctx context.Context, db *sql.DB, key *InstanceKey, channels []string) (lag float64, err error) {
+	statuses, err := readReplicationStatus(ctx, db, key)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateReplicationLag(statuses, channels)
+}
+
+const replicationApplierLagQuery = `
+	select
+		channel_name,
+		connection_status.service_state as receiver_state,
+		applier_status.service_state as applier_state,
+		coalesce(max(if(applying_transaction = '', 0, timestampdiff(microsecond, applying_transaction_original_commit_timestamp, now(6)) / 1000000)), 0) as lag
+	from
+		performance_schema.replication_applier_status as applier_status
+		left join performance_schema.replication_connection_status as connection_status using (channel_name)
+		left join performance_schema.replication_applier_status_by_worker using (channel_name)
+	group by
+		channel_name, connection_status.service_state, applier_status.service_state
+`
+
+// readReplicationApplierLag reads sub-second replication lag off performance_schema.replication_applier_status_by_worker,
+// based on the original commit timestamp of transactions being applied. Any filtered channel where the receiver or
+// the applier is not running results in an error. As with aggregateReplicationLag, a server with no replication
+// channels has no lag, unless specific channels were requested. Requires MySQL 8.0
+func readReplicationApplierLag(ctx context.Context, db *sql.DB, key *InstanceKey, channels []string) (lag float64, err error) {
+	version, err := readServerVersion(ctx, db, key)
+	if err != nil {
+		return 0, err
+	}
+	if version.MariaDB || !version.AtLeast(8, 0, 1) {
+		return 0, fmt.Errorf("replication applier lag requires MySQL 8.0; found %s", version.String())
+	}
+	countChannels := 0
+	err = queryRowsMap(ctx, db, replicationApplierLagQuery, func(m sqlutils.RowMap) error {
+		if !isChannelIncluded(m.GetString("channel_name"), channels) {
+			return nil
+		}
+		countChannels++
+		// An idle applier has no lag only as long as both replication threads are running
+		if m.GetString("receiver_state") != "ON" || m.GetString("applier_state") != "ON" {
+			return fmt.Errorf("replication not running on channel '%s'; receiver=%s, applier=%s", m.GetString("channel_name"), m.GetString("receiver_state"), m.GetString("applier_state"))
+		}
+		channelLag, err := strconv.ParseFloat(m.GetString("lag"), 64)
+		if err != nil {
+			return fmt.Errorf("unable to read applier lag on channel '%s'", m.GetString("channel_name"))
+		}
+		if channelLag > lag {
+			lag = channelLag
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if countChannels == 0 && len(channels) > 0 {
+		return 0, fmt.Errorf("replication channels %+v not found", channels)
+	}
+	return lag, nil
+}
@@ -0,0 +1,215 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/outbrain/golib/sqlutils"
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	{
+		version, err := ParseServerVersion("8.0.23-log")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(version.String(), "8.0.23")
+		test.S(t).ExpectTrue(version.SupportsReplicaStatus())
+	}
+	{
+		version, err := ParseServerVersion("8.0.21")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectFalse(version.SupportsReplicaStatus())
+		test.S(t).ExpectTrue(version.AtLeast(8, 0, 1))
+	}
+	{
+		version, err := ParseServerVersion("5.7.31-34-log")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(version.Major, 5)
+		test.S(t).ExpectEquals(version.Minor, 7)
+		test.S(t).ExpectEquals(version.Patch, 31)
+		test.S(t).ExpectFalse(version.SupportsReplicaStatus())
+		test.S(t).ExpectFalse(version.AtLeast(8, 0, 1))
+	}
+	{
+		// MariaDB versions are not comparable with MySQL's
+		version, err := ParseServerVersion("10.4.12-MariaDB-log")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(version.MariaDB)
+		test.S(t).ExpectEquals(version.String(), "10.4.12-MariaDB")
+		test.S(t).ExpectFalse(version.SupportsReplicaStatus())
+		test.S(t).ExpectEquals(replicationStatusQuery(version), "show slave status")
+	}
+	{
+		version, err := ParseServerVersion("10.5.8-MariaDB")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(version.SupportsReplicaStatus())
+		test.S(t).ExpectEquals(replicationStatusQuery(version), "show replica status")
+		test.S(t).ExpectEquals(showReplicasQuery(version), "show slave hosts")
+	}
+	{
+		version, err := ParseServerVersion("8.0.23")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectFalse(version.MariaDB)
+		test.S(t).ExpectEquals(showReplicasQuery(version), "show replicas")
+	}
+	{
+		_, err := ParseServerVersion("invalid")
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func newRowMap(values map[string]string) sqlutils.RowMap {
+	m := sqlutils.RowMap{}
+	for column, value := range values {
+		m[column] = sqlutils.CellData{String: value, Valid: true}
+	}
+	return m
+}
+
+func TestParseReplicationChannelStatus(t *testing.T) {
+	{
+		status := parseReplicationChannelStatus(newRowMap(map[string]string{
+			"Master_Host":           "primary",
+			"Master_Port":           "3306",
+			"Slave_IO_Running":      "Yes",
+			"Slave_SQL_Running":     "Yes",
+			"Seconds_Behind_Master": "3",
+		}))
+		test.S(t).ExpectEquals(status.SourceKey.StringCode(), "primary:3306")
+		test.S(t).ExpectEquals(status.IORunning, "Yes")
+		test.S(t).ExpectTrue(status.SecondsBehindSource.Valid)
+		test.S(t).ExpectEquals(status.SecondsBehindSource.Int64, int64(3))
+	}
+	{
+		status := parseReplicationChannelStatus(newRowMap(map[string]string{
+			"Source_Host":           "primary8",
+			"Source_Port":           "3307",
+			"Replica_IO_Running":    "No",
+			"Replica_SQL_Running":   "Yes",
+			"Seconds_Behind_Source": "",
+			"Channel_Name":          "ch1",
+		}))
+		test.S(t).ExpectEquals(status.ChannelName, "ch1")
+		test.S(t).ExpectEquals(status.SourceKey.StringCode(), "primary8:3307")
+		test.S(t).ExpectEquals(status.IORunning, "No")
+		test.S(t).ExpectFalse(status.SecondsBehindSource.Valid)
+	}
+}
+
+func TestAggregateReplicationLag(t *testing.T) {
+	statuses := [](*ReplicationChannelStatus){
+		{ChannelName: "a", SecondsBehindSource: sql.NullInt64{Int64: 2, Valid: true}},
+		{ChannelName: "b", SecondsBehindSource: sql.NullInt64{Int64: 7, Valid: true}},
+		{ChannelName: "c", SecondsBehindSource: sql.NullInt64{Valid: false}},
+	}
+	{
+		_, err := aggregateReplicationLag(statuses, nil)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		lag, err := aggregateReplicationLag(statuses, []string{"a", "b"})
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 7.0)
+	}
+	{
+		lag, err := aggregateReplicationLag(statuses, []string{"a"})
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 2.0)
+	}
+	{
+		_, err := aggregateReplicationLag(statuses, []string{"no-such-channel"})
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		// A server which is not a replica, e.g. a primary listed with its replicas, has no lag
+		lag, err := aggregateReplicationLag(nil, nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 0.0)
+	}
+	{
+		// ...unless specific channels were requested
+		_, err := aggregateReplicationLag(nil, []string{"a"})
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadReplicationLag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	key := &InstanceKey{Hostname: "replica8", Port: 3306}
+	serverVersionCache.Delete(key.StringCode())
+	mock.ExpectQuery(`select @@global.version`).WillReturnRows(sqlmock.NewRows([]string{"@@global.version"}).AddRow("8.0.23"))
+	mock.ExpectQuery(`show replica status`).WillReturnRows(
+		sqlmock.NewRows([]string{"Replica_IO_Running", "Replica_SQL_Running", "Seconds_Behind_Source", "Channel_Name"}).
+			AddRow("Yes", "Yes", "1", "east").
+			AddRow("Yes", "Yes", "4", "west"),
+	)
+	lag, err := readReplicationLag(context.Background(), db, key, nil)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(lag, 4.0)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestReadReplicationApplierLag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	key := &InstanceKey{Hostname: "replica8", Port: 3306}
+	serverVersionCache.Delete(key.StringCode())
+	mock.ExpectQuery(`select @@global.version`).WillReturnRows(sqlmock.NewRows([]string{"@@global.version"}).AddRow("8.0.23"))
+	columns := []string{"channel_name", "receiver_state", "applier_state", "lag"}
+	{
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(
+			sqlmock.NewRows(columns).AddRow("east", "ON", "ON", "0.25").AddRow("west", "ON", "ON", "0"),
+		)
+		lag, err := readReplicationApplierLag(context.Background(), db, key, nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 0.25)
+	}
+	{
+		// An idle applier whose receiver is disconnected is not lag free
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(
+			sqlmock.NewRows(columns).AddRow("east", "ON", "ON", "0.25").AddRow("west", "CONNECTING", "ON", "0"),
+		)
+		_, err := readReplicationApplierLag(context.Background(), db, key, nil)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(
+			sqlmock.NewRows(columns).AddRow("east", "ON", "OFF", "0").AddRow("west", "ON", "ON", "0"),
+		)
+		_, err := readReplicationApplierLag(context.Background(), db, key, nil)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		// A stopped channel which is filtered out is of no concern
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(
+			sqlmock.NewRows(columns).AddRow("east", "ON", "OFF", "0").AddRow("west", "ON", "ON", "1.5"),
+		)
+		lag, err := readReplicationApplierLag(context.Background(), db, key, []string{"west"})
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 1.5)
+	}
+	{
+		// Not a replica
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(sqlmock.NewRows(columns))
+		lag, err := readReplicationApplierLag(context.Background(), db, key, nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, 0.0)
+
+		mock.ExpectQuery(`replication_applier_status_by_worker`).WillReturnRows(sqlmock.NewRows(columns))
+		_, err = readReplicationApplierLag(context.Background(), db, key, []string{"west"})
+		test.S(t).ExpectNotNil(err)
+	}
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,271 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/proxysql"
+	"github.com/github/freno/pkg/store"
+
+	"github.com/outbrain/golib/log"
+)
+
+const StoreType = "mysql"
+
+const httpCheckInterval = 5 * time.Second
+const heartbeatInterval = 50 * time.Millisecond
+const proxySQLIgnoreServerTTL = 10 * time.Second
+
+func init() {
+	store.Register(newStoreDriver())
+}
+
+// storeDriver is the "mysql" store driver. Other than probing replication lag, it runs HTTP checks against
+// the probed servers and optionally writes heartbeats onto the clusters' primaries.
+type storeDriver struct {
+	clustersProbes   map[string](*Probes) // as last discovered, by cluster name
+	httpChecks       ClusterInstanceHttpCheckResultMap
+	heartbeatWriters map[string](*HeartbeatWriter)
+	proxysqlClient   *proxysql.Client
+	connections      *ConnectionManager
+	mutex            sync.Mutex
+}
+
+func newStoreDriver() *storeDriver {
+	return &storeDriver{
+		clustersProbes:   make(map[string](*Probes)),
+		httpChecks:       make(ClusterInstanceHttpCheckResultMap),
+		heartbeatWriters: make(map[string](*HeartbeatWriter)),
+		connections:      connectionManager,
+	}
+}
+
+func (driver *storeDriver) StoreType() string {
+	return StoreType
+}
+
+func (driver *storeDriver) Clusters() (clusters [](*store.Cluster)) {
+	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
+		clusterName := clusterName
+		clusterSettings := clusterSettings
+		// config may dynamically change, but internal structure (config.Settings().Stores.MySQL.Clusters in our case)
+		// is immutable and can only be _replaced_. Hence, it's safe to read in a goroutine:
+		clusters = append(clusters, &store.Cluster{
+			Name:                 clusterName,
+			ThrottleThreshold:    clusterSettings.ThrottleThreshold,
+			IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
+			IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
+			IgnoreHosts:          clusterSettings.IgnoreHosts,
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return driver.discover(clusterName, clusterSettings)
+			}),
+		})
+	}
+	return clusters
+}
+
+// discover returns probes of a cluster's servers, as listed by HAProxy, ProxySQL, Vitess, orchestrator,
+// Kubernetes, Consul, DNS, group replication, a topology crawl, a hosts file or static settings, or by
+// several of these combined. See discoverInstanceKeys
+func (driver *storeDriver) discover(clusterName string, clusterSettings *config.MySQLClusterConfigurationSettings) (probes []store.Prober, err error) {
+	user, password, err := clusterSettings.Credentials()
+	if err != nil {
+		return probes, fmt.Errorf("Unable to read credentials for cluster %s: %+v", clusterName, err)
+	}
+	keys, err := driver.discoverInstanceKeys(clusterName, clusterSettings, user, password)
+	if err != nil {
+		return probes, err
+	}
+	for _, discovered := range keys {
+		key := discovered.Key
+		if !key.IsValid() {
+			log.Debugf("read invalid instance key: [%+v] for cluster %+v", key, clusterName)
+			continue
+		}
+		log.Debugf("read instance key: %+v (%s)", key, strings.Join(discovered.Sources, ","))
+		probe := &Probe{
+			Key:                 key,
+			Sources:             discovered.Sources,
+			User:                user,
+			Password:            password,
+			MetricQuery:         clusterSettings.MetricQuery,
+			MetricType:          clusterSettings.MetricType,
+			MetricAggregation:   clusterSettings.MetricAggregation,
+			MetricRate:          clusterSettings.MetricRate,
+			HeartbeatSettings:   &clusterSettings.HeartbeatSettings,
+			ReplicationChannels: clusterSettings.ReplicationChannels,
+			TLSSettings:         &clusterSettings.TLSSettings,
+			CacheMillis:         clusterSettings.CacheMillis,
+			HttpCheckPath:       clusterSettings.HttpCheckPath,
+			HttpCheckPort:       clusterSettings.HttpCheckPort,
+			HttpCheckSettings:   &clusterSettings.HttpCheckSettings,
+		}
+		if clusterSettings.MetricType == config.MySQLMetricTypeProxySQLReplicationLag {
+			probe.ProxySQLSettings = &clusterSettings.ProxySQLSettings
+			probe.ProxySQLClient = driver.getProxySQLClient()
+		}
+		probes = append(probes, probe)
+	}
+	return probes, nil
+}
+
+func (driver *storeDriver) getProxySQLClient() *proxysql.Client {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	if driver.proxysqlClient == nil {
+		driver.proxysqlClient = proxysql.NewClient(proxySQLIgnoreServerTTL)
+	}
+	return driver.proxysqlClient
+}
+
+// FilterMetric applies HTTP check results: a failing host is excluded or considered erroneous, as configured.
+// Also, hosts which cannot be dialed, or whose circuit is open on dial errors, are ignored if so configured.
+func (driver *storeDriver) FilterMetric(clusterName string, prober store.Prober, metric base.MetricResult) (base.MetricResult, bool) {
+	probe, ok := prober.(*Probe)
+	if !ok {
+		return metric, true
+	}
+	driver.mutex.Lock()
+	httpCheck, found := driver.httpChecks[MySQLHttpCheckHashKey(clusterName, &probe.Key)]
+	driver.mutex.Unlock()
+	if found {
+		switch httpCheck.Action {
+		case config.HttpCheckActionExclude:
+			return nil, false
+		case config.HttpCheckActionError:
+			metric = &MySQLThrottleMetric{ClusterName: clusterName, Key: probe.Key, Err: fmt.Errorf("http check: %+v", httpCheck.Err)}
+		}
+	}
+	if metric != nil && config.Settings().Stores.MySQL.IgnoreDialTcpErrors {
+		if driver.connections.isUnreachable(&probe.Key) {
+			return nil, false
+		}
+		if _, err := metric.Get(); base.IsDialTcpError(err) {
+			return nil, false
+		}
+	}
+	return metric, true
+}
+
+// UpdateClusterProbes keeps track of probed servers, for HTTP checks and heartbeats, and closes connection
+// pools of servers no longer probed, e.g. decommissioned replicas
+func (driver *storeDriver) UpdateClusterProbes(clusterProbes *store.ClusterProbes) {
+	probes := NewProbes()
+	for _, prober := range clusterProbes.Probes {
+		if probe, ok := prober.(*Probe); ok {
+			(*probes)[probe.Key] = probe
+		}
+	}
+
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	driver.clustersProbes[clusterProbes.ClusterName] = probes
+	activeKeys := make(map[InstanceKey]bool)
+	for _, probes := range driver.clustersProbes {
+		for key := range *probes {
+			activeKeys[key] = true
+		}
+	}
+	go EvictConnectionPools(activeKeys)
+}
+
+func (driver *storeDriver) clustersProbesSnapshot() map[string](*Probes) {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	snapshot := make(map[string](*Probes))
+	for clusterName, probes := range driver.clustersProbes {
+		snapshot[clusterName] = probes
+	}
+	return snapshot
+}
+
+// Operate runs HTTP checks and writes heartbeats
+func (driver *storeDriver) Operate(isLeader func() bool) {
+	httpCheckTick := time.Tick(httpCheckInterval)
+	heartbeatTick := time.Tick(heartbeatInterval)
+	for {
+		select {
+		case <-httpCheckTick:
+			if isLeader() {
+				driver.collectHttpChecks()
+			}
+		case <-heartbeatTick:
+			if isLeader() {
+				driver.writeHeartbeats()
+			}
+		}
+	}
+}
+
+func (driver *storeDriver) collectHttpChecks() {
+	for clusterName, probes := range driver.clustersProbesSnapshot() {
+		clusterName := clusterName
+		// probes is known not to change. It can be *replaced*, but not changed.
+		// so it's safe to iterate it
+		for _, probe := range *probes {
+			probe := probe
+			go func() {
+				// Avoid checking the same server twice at the same time. If previous check is still there,
+				// we avoid re-checking it.
+				if !atomic.CompareAndSwapInt64(&probe.HttpCheckInProgress, 0, 1) {
+					return
+				}
+				defer atomic.StoreInt64(&probe.HttpCheckInProgress, 0)
+				httpCheckResult := CheckHttp(clusterName, probe)
+
+				driver.mutex.Lock()
+				defer driver.mutex.Unlock()
+				driver.httpChecks[httpCheckResult.HashKey()] = httpCheckResult
+			}()
+		}
+	}
+}
+
+// writeHeartbeats writes heartbeats onto the primaries of clusters configured to do so
+func (driver *storeDriver) writeHeartbeats() {
+	clustersProbes := driver.clustersProbesSnapshot()
+	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
+		if clusterSettings.MetricType != config.MySQLMetricTypeHeartbeat || !clusterSettings.HeartbeatSettings.WriteEnabled() {
+			continue
+		}
+		writer, ok := driver.heartbeatWriters[clusterName]
+		if !ok {
+			writer = NewHeartbeatWriter(clusterName)
+			driver.heartbeatWriters[clusterName] = writer
+		}
+		clusterSettings := clusterSettings
+		probes := clustersProbes[clusterName]
+		go func() {
+			// Avoid writing twice at the same time.
+			if !atomic.CompareAndSwapInt64(&writer.WriteInProgress, 0, 1) {
+				return
+			}
+			defer atomic.StoreInt64(&writer.WriteInProgress, 0)
+			user, password, err := clusterSettings.Credentials()
+			if err != nil {
+				log.Errorf("Unable to write heartbeat for cluster %s: %+v", writer.ClusterName, err)
+				return
+			}
+			writer.User = user
+			writer.Password = password
+			writer.HeartbeatSettings = &clusterSettings.HeartbeatSettings
+			writer.TLSSettings = &clusterSettings.TLSSettings
+			if err := writer.Write(probes); err != nil {
+				log.Errorf("Unable to write heartbeat for cluster %s: %+v", writer.ClusterName, err)
+			}
+		}()
+	}
+}
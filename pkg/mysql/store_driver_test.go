@@ -0,0 +1,545 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/store"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+var (
+	key1 = InstanceKey{Hostname: "10.0.0.1", Port: 3306}
+	key2 = InstanceKey{Hostname: "10.0.0.2", Port: 3306}
+	key3 = InstanceKey{Hostname: "10.0.0.3", Port: 3306}
+	key4 = InstanceKey{Hostname: "10.0.0.4", Port: 3306}
+	key5 = InstanceKey{Hostname: "10.0.0.5", Port: 3306}
+)
+
+// newTestHttpCheck returns a check result as CheckHttp would with default settings: only 404 excludes a host
+func newTestHttpCheck(clusterName string, key *InstanceKey, status int) *MySQLHttpCheck {
+	httpCheck := NewMySQLHttpCheck(clusterName, key, status)
+	if status == http.StatusNotFound {
+		httpCheck.Action = config.HttpCheckActionExclude
+	}
+	return httpCheck
+}
+
+// newTestClusterProbes returns probes of the hosts of given metrics
+func newTestClusterProbes(clusterName string, probeMetrics store.ProbeMetrics) *store.ClusterProbes {
+	clusterProbes := &store.ClusterProbes{ClusterName: clusterName}
+	for hostPort := range probeMetrics {
+		key, _ := ParseInstanceKey(hostPort, 0)
+		clusterProbes.Probes = append(clusterProbes.Probes, &Probe{Key: *key})
+	}
+	return clusterProbes
+}
+
+// aggregateMySQLProbes aggregates given probes as the throttler does, applying given HTTP check results
+func aggregateMySQLProbes(
+	clusterProbes *store.ClusterProbes,
+	probeMetrics store.ProbeMetrics,
+	httpChecks ClusterInstanceHttpCheckResultMap,
+	ignoreHostsCount int,
+	ignoreHostsThreshold float64,
+) base.MetricResult {
+	driver := newStoreDriver()
+	driver.httpChecks = httpChecks
+	clusterProbes.IgnoreHostsCount = ignoreHostsCount
+	clusterProbes.IgnoreHostsThreshold = ignoreHostsThreshold
+	return store.AggregateProbes(clusterProbes, probeMetrics, driver)
+}
+
+func TestAggregateMySQLProbesNoRateYet(t *testing.T) {
+	clusterName := "c0"
+	instanceResultsMap := store.ProbeMetrics{
+		key1.StringCode(): &MySQLThrottleMetric{ClusterName: clusterName, Key: key1, Value: 120},
+		key2.StringCode(): &MySQLThrottleMetric{ClusterName: clusterName, Key: key2, Err: errNoRateYet},
+		key3.StringCode(): &MySQLThrottleMetric{ClusterName: clusterName, Key: key3, Value: 30},
+	}
+	clusterProbes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(clusterProbes, instanceResultsMap, nil, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectEquals(err, errNoRateYet)
+	}
+	{
+		// a host with no rate yet, e.g. a restarted replica, is tolerated; the other hosts still aggregate
+		worstMetric := aggregateMySQLProbes(clusterProbes, instanceResultsMap, nil, 1, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 120.0)
+	}
+}
+
+func TestAggregateMySQLProbesNoErrors(t *testing.T) {
+	clusterName := "c0"
+	key1cluster := key1.StringCode()
+	key2cluster := key2.StringCode()
+	key3cluster := key3.StringCode()
+	key4cluster := key4.StringCode()
+	key5cluster := key5.StringCode()
+	instanceResultsMap := store.ProbeMetrics{
+		key1cluster: base.NewSimpleMetricResult(1.2),
+		key2cluster: base.NewSimpleMetricResult(1.7),
+		key3cluster: base.NewSimpleMetricResult(0.3),
+		key4cluster: base.NewSimpleMetricResult(0.6),
+		key5cluster: base.NewSimpleMetricResult(1.1),
+	}
+	clusterInstanceHttpCheckResultMap := ClusterInstanceHttpCheckResultMap{
+		MySQLHttpCheckHashKey(clusterName, &key1): newTestHttpCheck(clusterName, &key1, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key2): newTestHttpCheck(clusterName, &key2, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key3): newTestHttpCheck(clusterName, &key3, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key4): newTestHttpCheck(clusterName, &key4, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key5): newTestHttpCheck(clusterName, &key5, http.StatusOK),
+	}
+	probes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+		test.S(t).ExpectEquals(worstMetric.(base.HostMetricResult).GetHost(), key2.StringCode())
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 2, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.1)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 3, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.6)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 4, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.3)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 5, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.3)
+	}
+}
+
+func TestAggregateMySQLProbesNoErrorsIgnoreHostsThreshold(t *testing.T) {
+	clusterName := "c0"
+	key1cluster := key1.StringCode()
+	key2cluster := key2.StringCode()
+	key3cluster := key3.StringCode()
+	key4cluster := key4.StringCode()
+	key5cluster := key5.StringCode()
+	instanceResultsMap := store.ProbeMetrics{
+		key1cluster: base.NewSimpleMetricResult(1.2),
+		key2cluster: base.NewSimpleMetricResult(1.7),
+		key3cluster: base.NewSimpleMetricResult(0.3),
+		key4cluster: base.NewSimpleMetricResult(0.6),
+		key5cluster: base.NewSimpleMetricResult(1.1),
+	}
+	clusterInstanceHttpCheckResultMap := ClusterInstanceHttpCheckResultMap{
+		MySQLHttpCheckHashKey(clusterName, &key1): newTestHttpCheck(clusterName, &key1, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key2): newTestHttpCheck(clusterName, &key2, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key3): newTestHttpCheck(clusterName, &key3, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key4): newTestHttpCheck(clusterName, &key4, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key5): newTestHttpCheck(clusterName, &key5, http.StatusOK),
+	}
+	probes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 2, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.1)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 3, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.6)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 4, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.6)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 5, 1.0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.6)
+	}
+}
+
+func TestAggregateMySQLProbesWithErrors(t *testing.T) {
+	clusterName := "c0"
+	key1cluster := key1.StringCode()
+	key2cluster := key2.StringCode()
+	key3cluster := key3.StringCode()
+	key4cluster := key4.StringCode()
+	key5cluster := key5.StringCode()
+	instanceResultsMap := store.ProbeMetrics{
+		key1cluster: base.NewSimpleMetricResult(1.2),
+		key2cluster: base.NewSimpleMetricResult(1.7),
+		key3cluster: base.NewSimpleMetricResult(0.3),
+		key4cluster: base.NoSuchMetric,
+		key5cluster: base.NewSimpleMetricResult(1.1),
+	}
+	clusterInstanceHttpCheckResultMap := ClusterInstanceHttpCheckResultMap{
+		MySQLHttpCheckHashKey(clusterName, &key1): newTestHttpCheck(clusterName, &key1, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key2): newTestHttpCheck(clusterName, &key2, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key3): newTestHttpCheck(clusterName, &key3, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key4): newTestHttpCheck(clusterName, &key4, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key5): newTestHttpCheck(clusterName, &key5, http.StatusOK),
+	}
+	probes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectEquals(err, base.NoSuchMetricError)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 2, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+
+	instanceResultsMap[key1cluster] = base.NoSuchMetric
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectEquals(err, base.NoSuchMetricError)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectEquals(err, base.NoSuchMetricError)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 2, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+}
+
+func TestAggregateMySQLProbesWithHttpChecks(t *testing.T) {
+	clusterName := "c0"
+	key1cluster := key1.StringCode()
+	key2cluster := key2.StringCode()
+	key3cluster := key3.StringCode()
+	key4cluster := key4.StringCode()
+	key5cluster := key5.StringCode()
+	instanceResultsMap := store.ProbeMetrics{
+		key1cluster: base.NewSimpleMetricResult(1.2),
+		key2cluster: base.NewSimpleMetricResult(1.7),
+		key3cluster: base.NewSimpleMetricResult(0.3),
+		key4cluster: base.NoSuchMetric,
+		key5cluster: base.NewSimpleMetricResult(1.1),
+	}
+	clusterInstanceHttpCheckResultMap := ClusterInstanceHttpCheckResultMap{
+		MySQLHttpCheckHashKey(clusterName, &key1): newTestHttpCheck(clusterName, &key1, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key2): newTestHttpCheck(clusterName, &key2, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key3): newTestHttpCheck(clusterName, &key3, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key4): newTestHttpCheck(clusterName, &key4, http.StatusNotFound),
+		MySQLHttpCheckHashKey(clusterName, &key5): newTestHttpCheck(clusterName, &key5, http.StatusOK),
+	}
+	probes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+	}
+	{
+		clusterInstanceHttpCheckResultMap[MySQLHttpCheckHashKey(clusterName, &key2)] = newTestHttpCheck(clusterName, &key2, http.StatusNotFound)
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+	{
+		for hashKey := range clusterInstanceHttpCheckResultMap {
+			clusterInstanceHttpCheckResultMap[hashKey].Action = config.HttpCheckActionExclude
+		}
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestAggregateMySQLProbesWithHttpCheckErrors(t *testing.T) {
+	clusterName := "c0"
+	key1cluster := key1.StringCode()
+	key2cluster := key2.StringCode()
+	key3cluster := key3.StringCode()
+	instanceResultsMap := store.ProbeMetrics{
+		key1cluster: base.NewSimpleMetricResult(1.2),
+		key2cluster: base.NewSimpleMetricResult(1.7),
+		key3cluster: base.NewSimpleMetricResult(0.3),
+	}
+	clusterInstanceHttpCheckResultMap := ClusterInstanceHttpCheckResultMap{
+		MySQLHttpCheckHashKey(clusterName, &key1): newTestHttpCheck(clusterName, &key1, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key2): newTestHttpCheck(clusterName, &key2, http.StatusOK),
+		MySQLHttpCheckHashKey(clusterName, &key3): newTestHttpCheck(clusterName, &key3, http.StatusServiceUnavailable),
+	}
+	clusterInstanceHttpCheckResultMap[MySQLHttpCheckHashKey(clusterName, &key3)].Action = config.HttpCheckActionError
+
+	probes := newTestClusterProbes(clusterName, instanceResultsMap)
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 0, 0)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+	{
+		clusterInstanceHttpCheckResultMap[MySQLHttpCheckHashKey(clusterName, &key2)].Action = config.HttpCheckActionExclude
+		worstMetric := aggregateMySQLProbes(probes, instanceResultsMap, clusterInstanceHttpCheckResultMap, 1, 0)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+}
+
+func TestStoreDriverDiscoverStaticHosts(t *testing.T) {
+	driver, found := store.GetDriver(StoreType)
+	test.S(t).ExpectTrue(found)
+	test.S(t).ExpectEquals(driver.StoreType(), "mysql")
+	{
+		clusterSettings := &config.MySQLClusterConfigurationSettings{
+			User:                "freno",
+			Port:                3306,
+			MetricType:          config.MySQLMetricTypeHeartbeat,
+			StaticHostsSettings: config.StaticHostsConfigurationSettings{Hosts: []string{"10.0.0.1", "10.0.0.2:3307"}},
+		}
+		probes, err := newStoreDriver().discover("c0", clusterSettings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(probes), 2)
+		test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.1:3306")
+		test.S(t).ExpectEquals(probes[0].ProbeHostname(), "10.0.0.1")
+		test.S(t).ExpectEquals(probes[0].(*Probe).User, "freno")
+		test.S(t).ExpectEquals(probes[0].(*Probe).MetricType, config.MySQLMetricTypeHeartbeat)
+		test.S(t).ExpectEquals(probes[1].ProbeKey(), "10.0.0.2:3307")
+	}
+	{
+		_, err := newStoreDriver().discover("c0", &config.MySQLClusterConfigurationSettings{Port: 3306})
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestStoreDriverDiscoverConsulHosts(t *testing.T) {
+	consulAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"Node": {"Node": "db1", "Address": "10.0.0.1"}, "Service": {"Service": "mysql", "Port": 3307}},
+			{"Node": {"Node": "db2", "Address": "10.0.0.2"}, "Service": {"Service": "mysql", "Address": "10.0.1.2"}}
+		]`)
+	}))
+	defer consulAPI.Close()
+
+	clusterSettings := &config.MySQLClusterConfigurationSettings{
+		Port: 3306,
+		ConsulSettings: config.ConsulConfigurationSettings{
+			Address:       consulAPI.URL,
+			Service:       "mysql",
+			TimeoutMillis: 1000,
+		},
+	}
+	probes, err := newStoreDriver().discover("c0", clusterSettings)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(probes), 2)
+	test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.1:3307")
+	test.S(t).ExpectEquals(probes[1].ProbeKey(), "10.0.1.2:3306")
+}
+
+func TestStoreDriverDiscoverFileHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	os.WriteFile(path, []byte("Hosts:\n  - 10.0.0.1\n  - 10.0.0.2:3307\n"), 0644)
+
+	clusterSettings := &config.MySQLClusterConfigurationSettings{
+		Port:              3306,
+		FileHostsSettings: config.FileHostsConfigurationSettings{Path: path, Format: config.FileHostsFormatYAML},
+	}
+	probes, err := newStoreDriver().discover("c0", clusterSettings)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(probes), 2)
+	test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.1:3306")
+	test.S(t).ExpectEquals(probes[1].ProbeKey(), "10.0.0.2:3307")
+}
+
+func TestStoreDriverDiscoverMergedSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	os.WriteFile(path, []byte("10.0.0.2\n10.0.0.3\n"), 0644)
+
+	newClusterSettings := func(discoveryMode string) *config.MySQLClusterConfigurationSettings {
+		return &config.MySQLClusterConfigurationSettings{
+			Port:                3306,
+			DiscoveryMode:       discoveryMode,
+			FileHostsSettings:   config.FileHostsConfigurationSettings{Path: path, Format: config.FileHostsFormatLines},
+			StaticHostsSettings: config.StaticHostsConfigurationSettings{Hosts: []string{"10.0.0.1", "10.0.0.2:3306"}},
+		}
+	}
+	{
+		probes, err := newStoreDriver().discover("c0", newClusterSettings(config.DiscoveryModeFirst))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(probes), 2)
+		test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.2:3306")
+		test.S(t).ExpectEquals(strings.Join(probes[0].(*Probe).Sources, ","), "file")
+	}
+	{
+		probes, err := newStoreDriver().discover("c0", newClusterSettings(config.DiscoveryModeUnion))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(probes), 3)
+		test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.2:3306")
+		test.S(t).ExpectEquals(strings.Join(probes[0].(*Probe).Sources, ","), "file,static")
+		test.S(t).ExpectEquals(probes[1].ProbeKey(), "10.0.0.3:3306")
+		test.S(t).ExpectEquals(strings.Join(probes[1].(*Probe).Sources, ","), "file")
+		test.S(t).ExpectEquals(probes[2].ProbeKey(), "10.0.0.1:3306")
+		test.S(t).ExpectEquals(strings.Join(probes[2].(*Probe).Sources, ","), "static")
+	}
+	{
+		probes, err := newStoreDriver().discover("c0", newClusterSettings(config.DiscoveryModeIntersection))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(probes), 1)
+		test.S(t).ExpectEquals(probes[0].ProbeKey(), "10.0.0.2:3306")
+	}
+	{
+		clusterSettings := newClusterSettings(config.DiscoveryModeUnion)
+		clusterSettings.StaticHostsSettings.Hosts = []string{"10.0.0.1:abc"}
+		_, err := newStoreDriver().discover("c0", clusterSettings)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestMergeDiscoveredKeys(t *testing.T) {
+	sourcesNames := []string{"haproxy", "static"}
+	sourcesKeys := [][]InstanceKey{{key1, key2, key1}, {key3, key2}}
+	{
+		keys := mergeDiscoveredKeys(sourcesNames, sourcesKeys, false)
+		test.S(t).ExpectEquals(len(keys), 3)
+		test.S(t).ExpectEquals(keys[0].Key, key1)
+		test.S(t).ExpectEquals(strings.Join(keys[0].Sources, ","), "haproxy")
+		test.S(t).ExpectEquals(keys[1].Key, key2)
+		test.S(t).ExpectEquals(strings.Join(keys[1].Sources, ","), "haproxy,static")
+		test.S(t).ExpectEquals(keys[2].Key, key3)
+	}
+	{
+		keys := mergeDiscoveredKeys(sourcesNames, sourcesKeys, true)
+		test.S(t).ExpectEquals(len(keys), 1)
+		test.S(t).ExpectEquals(keys[0].Key, key2)
+	}
+}
+
+func TestStoreDriverUpdateClusterProbes(t *testing.T) {
+	driver := newStoreDriver()
+	driver.UpdateClusterProbes(&store.ClusterProbes{ClusterName: "c0", Probes: []store.Prober{&Probe{Key: key1}, &Probe{Key: key2}}})
+	driver.UpdateClusterProbes(&store.ClusterProbes{ClusterName: "c1", Probes: []store.Prober{&Probe{Key: key3}}})
+
+	clustersProbes := driver.clustersProbesSnapshot()
+	test.S(t).ExpectEquals(len(clustersProbes), 2)
+	test.S(t).ExpectEquals(len(*clustersProbes["c0"]), 2)
+	test.S(t).ExpectNotNil((*clustersProbes["c0"])[key2])
+	test.S(t).ExpectEquals(len(*clustersProbes["c1"]), 1)
+}
+
+func TestStoreDriverFilterMetricIgnoreDialTcpErrors(t *testing.T) {
+	config.Settings().Stores.MySQL.IgnoreDialTcpErrors = true
+	defer func() { config.Settings().Stores.MySQL.IgnoreDialTcpErrors = false }()
+
+	driver := newStoreDriver()
+	probe := &Probe{Key: key1}
+	filter := func(err error) bool {
+		_, included := driver.FilterMetric("c0", probe, &MySQLThrottleMetric{ClusterName: "c0", Key: key1, Err: err})
+		return included
+	}
+	// hosts which cannot be dialed are ignored
+	test.S(t).ExpectFalse(filter(dialError))
+	test.S(t).ExpectFalse(filter(&CircuitOpenError{LastErr: dialError}))
+	// a host too busy to answer within the deadline is not ignored: it is what throttling is for
+	test.S(t).ExpectTrue(filter(context.DeadlineExceeded))
+	test.S(t).ExpectTrue(filter(fmt.Errorf("read: %w", context.DeadlineExceeded)))
+	test.S(t).ExpectTrue(filter(&CircuitOpenError{LastErr: context.DeadlineExceeded}))
+	test.S(t).ExpectTrue(filter(nil))
+	// an error which merely reads like a dial error is not one
+	test.S(t).ExpectTrue(filter(fmt.Errorf("dial tcp 10.0.0.1:3306: connect: connection refused")))
+}
+
+func TestStoreDriverFilterMetricIgnoreDialTcpErrorsCircuitState(t *testing.T) {
+	config.Settings().Stores.MySQL.IgnoreDialTcpErrors = true
+	defer func() { config.Settings().Stores.MySQL.IgnoreDialTcpErrors = false }()
+
+	driver := newStoreDriver()
+	driver.connections = NewConnectionManager()
+	probe := &Probe{Key: key1}
+	_, err := driver.connections.GetDB(probe)
+	test.S(t).ExpectNil(err)
+	filter := func(err error) bool {
+		_, included := driver.FilterMetric("c0", probe, &MySQLThrottleMetric{ClusterName: "c0", Key: key1, Err: err})
+		return included
+	}
+	// a circuit open on timeouts: the host was reached, and its errors apply
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		driver.connections.recordResult(&probe.Key, context.DeadlineExceeded)
+	}
+	test.S(t).ExpectTrue(filter(errors.New("driver: bad connection")))
+
+	// a circuit open on dial errors: the host is ignored whatever its latest error
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		driver.connections.recordResult(&probe.Key, dialError)
+	}
+	test.S(t).ExpectFalse(filter(errors.New("driver: bad connection")))
+
+	// the circuit closes once the host is reached
+	driver.connections.recordResult(&probe.Key, nil)
+	test.S(t).ExpectTrue(filter(errors.New("driver: bad connection")))
+}
@@ -0,0 +1,173 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/github/freno/pkg/config"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+)
+
+// topologyReplica is a replica as seen from its source
+type topologyReplica struct {
+	Key      InstanceKey
+	ServerID string // empty when found by its binlog dump thread
+}
+
+// topologyNode is a crawled server: its own server_id, and the replicas it knows of
+type topologyNode struct {
+	ServerID string
+	Replicas []topologyReplica
+}
+
+// showReplicasQuery returns the statement listing a source's replicas, as of MySQL 8.0.22 SHOW REPLICAS. MariaDB
+// has no SHOW REPLICAS.
+func showReplicasQuery(version *ServerVersion) string {
+	if version.SupportsReplicaStatus() && !version.MariaDB {
+		return `show replicas`
+	}
+	return `show slave hosts`
+}
+
+// readReplicaHosts lists the replicas registered with given source. Replicas not setting report_host
+// are listed with an empty host, and are skipped
+func readReplicaHosts(ctx context.Context, db *sql.DB, version *ServerVersion, defaultPort int) (replicas []topologyReplica, err error) {
+	err = queryRowsMap(ctx, db, showReplicasQuery(version), func(m sqlutils.RowMap) error {
+		replica := topologyReplica{ServerID: getRowMapString(m, "Server_Id", "Server_id")}
+		replica.Key.Hostname = getRowMapString(m, "Host")
+		if replica.Key.Hostname == "" {
+			return nil
+		}
+		replica.Key.Port, _ = strconv.Atoi(getRowMapString(m, "Port"))
+		if replica.Key.Port == 0 {
+			replica.Key.Port = defaultPort
+		}
+		replicas = append(replicas, replica)
+		return nil
+	})
+	return replicas, err
+}
+
+// readBinlogDumpHosts lists the client hosts of given source's binlog dump threads. The port of such a
+// thread is the replica's outgoing port, hence replicas are assumed to listen on defaultPort
+func readBinlogDumpHosts(ctx context.Context, db *sql.DB, defaultPort int) (replicas []topologyReplica, err error) {
+	query := `
+		select
+			host
+		from
+			information_schema.processlist
+		where
+			command in ('Binlog Dump', 'Binlog Dump GTID')
+	`
+	err = queryRowsMap(ctx, db, query, func(m sqlutils.RowMap) error {
+		host := m.GetString("host")
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if host != "" {
+			replicas = append(replicas, topologyReplica{Key: InstanceKey{Hostname: host, Port: defaultPort}})
+		}
+		return nil
+	})
+	return replicas, err
+}
+
+// readTopologyNode reads given server's server_id and replicas
+func readTopologyNode(ctx context.Context, db *sql.DB, key *InstanceKey, processlistThreads bool, defaultPort int) (node *topologyNode, err error) {
+	version, err := readServerVersion(ctx, db, key)
+	if err != nil {
+		return nil, err
+	}
+	node = &topologyNode{}
+	if err := db.QueryRowContext(ctx, `select @@global.server_id`).Scan(&node.ServerID); err != nil {
+		return nil, err
+	}
+	if node.Replicas, err = readReplicaHosts(ctx, db, version, defaultPort); err != nil {
+		return nil, err
+	}
+	if processlistThreads {
+		dumpHosts, err := readBinlogDumpHosts(ctx, db, defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		node.Replicas = append(node.Replicas, dumpHosts...)
+	}
+	return node, nil
+}
+
+// crawlReplicas walks replicas breadth first from given seed, down to maxDepth levels. A server listed under
+// several names (e.g. by report_host and by IP of its binlog dump thread) is identified by its server_id and
+// only returned once. A replica which cannot be read is still returned, so that probing it reports the error.
+func crawlReplicas(seedKey InstanceKey, seed *topologyNode, maxDepth int, readNode func(key InstanceKey) (*topologyNode, error)) (keys []InstanceKey) {
+	seenKeys := map[string]bool{seedKey.StringCode(): true}
+	seenServerIDs := map[string]bool{seed.ServerID: true}
+	level := [](*topologyNode){seed}
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		var nextLevel [](*topologyNode)
+		for _, node := range level {
+			for _, replica := range node.Replicas {
+				if seenKeys[replica.Key.StringCode()] || (replica.ServerID != "" && seenServerIDs[replica.ServerID]) {
+					continue
+				}
+				seenKeys[replica.Key.StringCode()] = true
+				replicaNode, err := readNode(replica.Key)
+				if err != nil {
+					log.Errorf("topology: unable to read replica %+v: %+v", replica.Key, err)
+					if replica.ServerID != "" {
+						seenServerIDs[replica.ServerID] = true
+					}
+					keys = append(keys, replica.Key)
+					continue
+				}
+				if seenServerIDs[replicaNode.ServerID] {
+					continue
+				}
+				seenServerIDs[replicaNode.ServerID] = true
+				keys = append(keys, replica.Key)
+				nextLevel = append(nextLevel, replicaNode)
+			}
+		}
+		level = nextLevel
+	}
+	return keys
+}
+
+// ReadTopologyReplicas crawls replicas from the first responsive seed host, connecting to each server with
+// given credentials, and returns the replicas found down to the configured depth
+func ReadTopologyReplicas(settings *config.TopologyConfigurationSettings, user, password string, tlsSettings *config.TLSConfigurationSettings, defaultPort int) (keys []InstanceKey, err error) {
+	readNode := func(key InstanceKey) (*topologyNode, error) {
+		db, err := getProbeDB(&Probe{Key: key, User: user, Password: password, TLSSettings: tlsSettings})
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		defer cancel()
+		return readTopologyNode(ctx, db, &key, settings.ProcesslistThreads, defaultPort)
+	}
+	for _, seedHost := range settings.SeedHosts {
+		seedKey, err := ParseInstanceKey(seedHost, defaultPort)
+		if err != nil {
+			return keys, err
+		}
+		seed, err := readNode(*seedKey)
+		if err != nil {
+			log.Errorf("topology: unable to read seed %+v: %+v", *seedKey, err)
+			continue
+		}
+		if settings.IncludeSeed {
+			keys = append(keys, *seedKey)
+		}
+		return append(keys, crawlReplicas(*seedKey, seed, settings.MaxDepth, readNode)...), nil
+	}
+	return keys, fmt.Errorf("Unable to crawl topology from any of %+v", settings.SeedHosts)
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestReadTopologyNode(t *testing.T) {
+	{
+		db, mock, err := sqlmock.New()
+		test.S(t).ExpectNil(err)
+		defer db.Close()
+
+		key := &InstanceKey{Hostname: "topology-node-8.0", Port: 3306}
+		mock.ExpectQuery(`select @@global.version`).WillReturnRows(sqlmock.NewRows([]string{"@@global.version"}).AddRow("8.0.28"))
+		mock.ExpectQuery(`select @@global.server_id`).WillReturnRows(sqlmock.NewRows([]string{"@@global.server_id"}).AddRow(1))
+		mock.ExpectQuery(`show replicas`).WillReturnRows(
+			sqlmock.NewRows([]string{"Server_Id", "Host", "Port", "Source_Id", "Replica_UUID"}).
+				AddRow(2, "replica1", 3306, 1, "uuid-2").
+				AddRow(3, "", 3306, 1, "uuid-3").
+				AddRow(4, "replica4", 3307, 1, "uuid-4"),
+		)
+		mock.ExpectQuery(`information_schema.processlist`).WillReturnRows(
+			sqlmock.NewRows([]string{"host"}).
+				AddRow("10.0.0.2:51234").
+				AddRow("10.0.0.3:40022"),
+		)
+		node, err := readTopologyNode(context.Background(), db, key, true, 3306)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(node.ServerID, "1")
+		test.S(t).ExpectEquals(len(node.Replicas), 4)
+		test.S(t).ExpectEquals(node.Replicas[0].Key.StringCode(), "replica1:3306")
+		test.S(t).ExpectEquals(node.Replicas[0].ServerID, "2")
+		test.S(t).ExpectEquals(node.Replicas[1].Key.StringCode(), "replica4:3307")
+		test.S(t).ExpectEquals(node.Replicas[2].Key.StringCode(), "10.0.0.2:3306")
+		test.S(t).ExpectEquals(node.Replicas[2].ServerID, "")
+		test.S(t).ExpectEquals(node.Replicas[3].Key.StringCode(), "10.0.0.3:3306")
+		test.S(t).ExpectNil(mock.ExpectationsWereMet())
+	}
+	{
+		db, mock, err := sqlmock.New()
+		test.S(t).ExpectNil(err)
+		defer db.Close()
+
+		key := &InstanceKey{Hostname: "topology-node-5.7", Port: 3306}
+		mock.ExpectQuery(`select @@global.version`).WillReturnRows(sqlmock.NewRows([]string{"@@global.version"}).AddRow("5.7.40-log"))
+		mock.ExpectQuery(`select @@global.server_id`).WillReturnRows(sqlmock.NewRows([]string{"@@global.server_id"}).AddRow(1))
+		mock.ExpectQuery(`show slave hosts`).WillReturnRows(
+			sqlmock.NewRows([]string{"Server_id", "Host", "Port", "Master_id", "Slave_UUID"}).
+				AddRow(2, "replica1", 0, 1, "uuid-2"),
+		)
+		node, err := readTopologyNode(context.Background(), db, key, false, 3307)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(node.Replicas), 1)
+		test.S(t).ExpectEquals(node.Replicas[0].Key.StringCode(), "replica1:3307")
+		test.S(t).ExpectEquals(node.Replicas[0].ServerID, "2")
+		test.S(t).ExpectNil(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCrawlReplicas(t *testing.T) {
+	replica := func(hostname string, serverID string) topologyReplica {
+		return topologyReplica{Key: InstanceKey{Hostname: hostname, Port: 3306}, ServerID: serverID}
+	}
+	// primary -> r1 -> r1a -> r1a1; primary -> r2, also seen by IP 10.0.0.2; r3 is down
+	nodes := map[string](*topologyNode){
+		"r1:3306":       {ServerID: "11", Replicas: []topologyReplica{replica("r1a", "111")}},
+		"r1a:3306":      {ServerID: "111", Replicas: []topologyReplica{replica("r1a1", "1111")}},
+		"r1a1:3306":     {ServerID: "1111"},
+		"r2:3306":       {ServerID: "12"},
+		"10.0.0.2:3306": {ServerID: "12"},
+	}
+	readNode := func(key InstanceKey) (*topologyNode, error) {
+		if node, found := nodes[key.StringCode()]; found {
+			return node, nil
+		}
+		return nil, fmt.Errorf("unreachable: %s", key.StringCode())
+	}
+	seedKey := InstanceKey{Hostname: "primary", Port: 3306}
+	seed := &topologyNode{ServerID: "1", Replicas: []topologyReplica{
+		replica("r1", "11"), replica("r2", "12"), replica("r3", "13"), replica("10.0.0.2", ""), replica("primary", ""),
+	}}
+	codes := func(keys []InstanceKey) (codes []string) {
+		for _, key := range keys {
+			codes = append(codes, key.StringCode())
+		}
+		return codes
+	}
+	test.S(t).ExpectEquals(fmt.Sprintf("%v", codes(crawlReplicas(seedKey, seed, 1, readNode))), "[r1:3306 r2:3306 r3:3306]")
+	test.S(t).ExpectEquals(fmt.Sprintf("%v", codes(crawlReplicas(seedKey, seed, 2, readNode))), "[r1:3306 r2:3306 r3:3306 r1a:3306]")
+	test.S(t).ExpectEquals(fmt.Sprintf("%v", codes(crawlReplicas(seedKey, seed, 3, readNode))), "[r1:3306 r2:3306 r3:3306 r1a:3306 r1a1:3306]")
+}
@@ -0,0 +1,119 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+)
+
+// InstanceKey identifies a MySQL server, as reported by orchestrator
+type InstanceKey struct {
+	Hostname string
+	Port     int
+}
+
+// Instance is a MySQL server of a replication topology, as reported by orchestrator. Orchestrator has renamed some
+// fields over its versions; both namings are read.
+type Instance struct {
+	Key                        InstanceKey
+	MasterKey                  InstanceKey
+	ReplicationDepth           uint
+	ReplicationSQLThreadRuning bool
+	ReplicationIOThreadRuning  bool
+	Slave_SQL_Running          bool
+	Slave_IO_Running           bool
+	SQLDelay                   uint
+	IsDowntimed                bool
+	Replicas                   []InstanceKey
+	SlaveHosts                 []InstanceKey
+}
+
+// IsReplicating returns true when both replication threads of the instance are running
+func (instance *Instance) IsReplicating() bool {
+	sqlThreadRunning := instance.ReplicationSQLThreadRuning || instance.Slave_SQL_Running
+	ioThreadRunning := instance.ReplicationIOThreadRuning || instance.Slave_IO_Running
+	return sqlThreadRunning && ioThreadRunning
+}
+
+// IsIntermediateMaster returns true when the instance is a replica which has replicas of its own
+func (instance *Instance) IsIntermediateMaster() bool {
+	return instance.MasterKey.Hostname != "" && (len(instance.Replicas) > 0 || len(instance.SlaveHosts) > 0)
+}
+
+// IsDelayed returns true when the instance is configured to replicate with a delay
+func (instance *Instance) IsDelayed() bool {
+	return instance.SQLDelay > 0
+}
+
+func constructAPIURL(settings *config.OrchestratorConfigurationSettings) string {
+	api := strings.TrimRight(settings.URL, "/")
+	if !strings.HasSuffix(api, "/api") {
+		api = fmt.Sprintf("%s/api", api)
+	}
+	if settings.Alias != "" {
+		return fmt.Sprintf("%s/cluster/alias/%s", api, url.PathEscape(settings.Alias))
+	}
+	return fmt.Sprintf("%s/cluster/%s", api, url.PathEscape(settings.ClusterName))
+}
+
+// filterReplicas returns the instances to be probed: replicating, not downtimed replicas, optionally excluding
+// intermediate masters and delayed replicas
+func filterReplicas(settings *config.OrchestratorConfigurationSettings, instances []Instance) (replicas []Instance) {
+	for _, instance := range instances {
+		if instance.MasterKey.Hostname == "" {
+			// topology master
+			continue
+		}
+		if !instance.IsReplicating() || instance.IsDowntimed {
+			continue
+		}
+		if settings.ExcludeIntermediateMasters && instance.IsIntermediateMaster() {
+			continue
+		}
+		if settings.ExcludeDelayedReplicas && instance.IsDelayed() {
+			continue
+		}
+		replicas = append(replicas, instance)
+	}
+	return replicas
+}
+
+// ReadReplicas reads a cluster's instances off orchestrator's /api/cluster/alias/<alias> or /api/cluster/<cluster name>,
+// and returns the replicas to be probed
+func ReadReplicas(settings *config.OrchestratorConfigurationSettings) (replicas []Instance, err error) {
+	request, err := http.NewRequest(http.MethodGet, constructAPIURL(settings), nil)
+	if err != nil {
+		return replicas, err
+	}
+	if settings.User != "" {
+		password, err := config.ResolveSecret(settings.Password)
+		if err != nil {
+			return replicas, err
+		}
+		user, err := config.ResolveSecret(settings.User)
+		if err != nil {
+			return replicas, err
+		}
+		request.SetBasicAuth(user, password)
+	}
+	httpClient := &http.Client{Timeout: time.Duration(settings.TimeoutMillis) * time.Millisecond}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return replicas, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return replicas, fmt.Errorf("%v", resp.Status)
+	}
+	var instances []Instance
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		return replicas, err
+	}
+	return filterReplicas(settings, instances), nil
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+const clusterInstancesJSON = `[
+	{"Key": {"Hostname": "db-primary", "Port": 3306}, "MasterKey": {"Hostname": "", "Port": 0}, "ReplicationDepth": 0,
+		"Replicas": [{"Hostname": "db-replica1", "Port": 3306}, {"Hostname": "db-intermediate", "Port": 3306}]},
+	{"Key": {"Hostname": "db-replica1", "Port": 3306}, "MasterKey": {"Hostname": "db-primary", "Port": 3306}, "ReplicationDepth": 1,
+		"ReplicationSQLThreadRuning": true, "ReplicationIOThreadRuning": true},
+	{"Key": {"Hostname": "db-intermediate", "Port": 3306}, "MasterKey": {"Hostname": "db-primary", "Port": 3306}, "ReplicationDepth": 1,
+		"ReplicationSQLThreadRuning": true, "ReplicationIOThreadRuning": true, "Replicas": [{"Hostname": "db-replica2", "Port": 3307}]},
+	{"Key": {"Hostname": "db-replica2", "Port": 3307}, "MasterKey": {"Hostname": "db-intermediate", "Port": 3306}, "ReplicationDepth": 2,
+		"Slave_SQL_Running": true, "Slave_IO_Running": true},
+	{"Key": {"Hostname": "db-delayed", "Port": 3306}, "MasterKey": {"Hostname": "db-primary", "Port": 3306}, "ReplicationDepth": 1,
+		"ReplicationSQLThreadRuning": true, "ReplicationIOThreadRuning": true, "SQLDelay": 3600},
+	{"Key": {"Hostname": "db-stopped", "Port": 3306}, "MasterKey": {"Hostname": "db-primary", "Port": 3306}, "ReplicationDepth": 1,
+		"ReplicationSQLThreadRuning": false, "ReplicationIOThreadRuning": true},
+	{"Key": {"Hostname": "db-downtimed", "Port": 3306}, "MasterKey": {"Hostname": "db-primary", "Port": 3306}, "ReplicationDepth": 1,
+		"ReplicationSQLThreadRuning": true, "ReplicationIOThreadRuning": true, "IsDowntimed": true}
+]`
+
+func replicaHostnames(replicas []Instance) string {
+	hostnames := []string{}
+	for _, replica := range replicas {
+		hostnames = append(hostnames, replica.Key.Hostname)
+	}
+	return strings.Join(hostnames, ",")
+}
+
+func TestConstructAPIURL(t *testing.T) {
+	{
+		settings := &config.OrchestratorConfigurationSettings{URL: "http://orchestrator.example.com:3000/", Alias: "prod4"}
+		test.S(t).ExpectEquals(constructAPIURL(settings), "http://orchestrator.example.com:3000/api/cluster/alias/prod4")
+	}
+	{
+		settings := &config.OrchestratorConfigurationSettings{URL: "http://orchestrator.example.com:3000/api", ClusterName: "db-primary:3306"}
+		test.S(t).ExpectEquals(constructAPIURL(settings), "http://orchestrator.example.com:3000/api/cluster/db-primary:3306")
+	}
+}
+
+func TestReadReplicas(t *testing.T) {
+	orchestratorAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "freno" || password != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/cluster/alias/prod4", "/api/cluster/db-primary:3306":
+			w.Write([]byte(clusterInstancesJSON))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer orchestratorAPI.Close()
+
+	settings := &config.OrchestratorConfigurationSettings{
+		URL:           orchestratorAPI.URL,
+		User:          "freno",
+		Password:      "s3cr3t",
+		Alias:         "prod4",
+		TimeoutMillis: 1000,
+	}
+	{
+		replicas, err := ReadReplicas(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(replicaHostnames(replicas), "db-replica1,db-intermediate,db-replica2,db-delayed")
+		test.S(t).ExpectEquals(replicas[2].Key.Port, 3307)
+	}
+	{
+		settings.ExcludeIntermediateMasters = true
+		settings.ExcludeDelayedReplicas = true
+		replicas, err := ReadReplicas(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(replicaHostnames(replicas), "db-replica1,db-replica2")
+	}
+	{
+		settings.Alias = ""
+		settings.ClusterName = "db-primary:3306"
+		replicas, err := ReadReplicas(settings)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(replicas), 2)
+	}
+	{
+		settings.ClusterName = "no-such-cluster"
+		_, err := ReadReplicas(settings)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		settings.ClusterName = "db-primary:3306"
+		settings.Password = "wrong"
+		_, err := ReadReplicas(settings)
+		test.S(t).ExpectNotNil(err)
+	}
+}
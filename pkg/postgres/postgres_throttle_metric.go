@@ -0,0 +1,119 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/mysql"
+
+	_ "github.com/lib/pq"
+	"github.com/outbrain/golib/log"
+	"github.com/patrickmn/go-cache"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+var postgresMetricCache = cache.New(cache.NoExpiration, 10*time.Millisecond)
+
+// connectionManager owns the connection pools to probed servers, with the same pooling and eviction as MySQL's
+var connectionManager = mysql.NewConnectionManager()
+
+func getPostgreSQLMetricCacheKey(probe *Probe) string {
+	return fmt.Sprintf("%s:%s:%s", probe.Key, probe.MetricType, probe.MetricQuery)
+}
+
+func cachePostgreSQLThrottleMetric(probe *Probe, throttleMetric *PostgreSQLThrottleMetric) *PostgreSQLThrottleMetric {
+	if throttleMetric.Err != nil {
+		return throttleMetric
+	}
+	if probe.CacheMillis > 0 {
+		postgresMetricCache.Set(getPostgreSQLMetricCacheKey(probe), throttleMetric, time.Duration(probe.CacheMillis)*time.Millisecond)
+	}
+	return throttleMetric
+}
+
+func getCachedPostgreSQLThrottleMetric(probe *Probe) *PostgreSQLThrottleMetric {
+	if probe.CacheMillis == 0 {
+		return nil
+	}
+	if metric, found := postgresMetricCache.Get(getPostgreSQLMetricCacheKey(probe)); found {
+		throttleMetric, _ := metric.(*PostgreSQLThrottleMetric)
+		return throttleMetric
+	}
+	return nil
+}
+
+// getProbeDB returns a (pooled) connection to the probe's server
+func getProbeDB(probe *Probe) (*sql.DB, error) {
+	return connectionManager.GetDriverDB("postgres", probe.Key, probe.GetDBUri())
+}
+
+// EvictConnectionPools closes the pools of servers which are no longer probed
+func EvictConnectionPools(activeKeys map[mysql.InstanceKey]bool) {
+	for _, key := range connectionManager.EvictPools(activeKeys) {
+		log.Debugf("closed connection pool to %+v", key)
+	}
+}
+
+type PostgreSQLThrottleMetric struct {
+	ClusterName string
+	Key         mysql.InstanceKey
+	Value       float64
+	Err         error
+}
+
+func (metric *PostgreSQLThrottleMetric) GetClusterInstanceKey() mysql.ClusterInstanceKey {
+	return mysql.GetClusterInstanceKey(metric.ClusterName, &metric.Key)
+}
+
+func (metric *PostgreSQLThrottleMetric) Get() (float64, error) {
+	return metric.Value, metric.Err
+}
+
+// ReadThrottleMetric returns replication lag for a given probe; either by explicit query, via
+// pg_stat_replication on a primary, or via pg_last_xact_replay_timestamp() on a replica
+func ReadThrottleMetric(probe *Probe, clusterName string) (throttleMetric *PostgreSQLThrottleMetric) {
+	if throttleMetric := getCachedPostgreSQLThrottleMetric(probe); throttleMetric != nil {
+		return throttleMetric
+		// On cached results we avoid taking latency metrics
+	}
+
+	throttleMetric = &PostgreSQLThrottleMetric{ClusterName: clusterName, Key: probe.Key}
+
+	started := time.Now()
+	defer func(metric *PostgreSQLThrottleMetric, started time.Time) {
+		go func() {
+			metrics.GetOrRegisterTimer("postgres.probes.latency", nil).Update(time.Since(started))
+			metrics.GetOrRegisterCounter("postgres.probes.total", nil).Inc(1)
+			if metric.Err != nil {
+				metrics.GetOrRegisterCounter("postgres.probes.error", nil).Inc(1)
+			}
+		}()
+	}(throttleMetric, started)
+
+	db, err := getProbeDB(probe)
+	if err != nil {
+		throttleMetric.Err = err
+		return throttleMetric
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	if probe.MetricQuery != "" {
+		throttleMetric.Value, throttleMetric.Err = readMetricQuery(ctx, db, probe.MetricQuery)
+		return cachePostgreSQLThrottleMetric(probe, throttleMetric)
+	}
+	if probe.MetricType == config.PostgreSQLMetricTypeStatReplication {
+		throttleMetric.Value, throttleMetric.Err = readStatReplicationLag(ctx, db, &probe.Key)
+		return cachePostgreSQLThrottleMetric(probe, throttleMetric)
+	}
+	throttleMetric.Value, throttleMetric.Err = readReplayLag(ctx, db, &probe.Key)
+	return cachePostgreSQLThrottleMetric(probe, throttleMetric)
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package postgres
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/mysql"
+)
+
+const timeoutSeconds = 1
+
+// queryTimeout is the deadline for a single probe query, including obtaining a connection
+const queryTimeout = timeoutSeconds * time.Second
+
+// Probe is the minimal configuration required to connect to a PostgreSQL server
+type Probe struct {
+	Key         mysql.InstanceKey
+	User        string
+	Password    string
+	Database    string
+	SSLMode     string
+	MetricQuery string
+	MetricType  string
+	CacheMillis int
+}
+
+func (probe *Probe) String() string {
+	return fmt.Sprintf("%s, user=%s", probe.Key.DisplayString(), probe.User)
+}
+
+// ProbeKey identifies the probed server within its cluster
+func (probe *Probe) ProbeKey() string {
+	return probe.Key.StringCode()
+}
+
+func (probe *Probe) ProbeHostname() string {
+	return probe.Key.Hostname
+}
+
+// ReadMetric reads the server's throttle metric, see ReadThrottleMetric
+func (probe *Probe) ReadMetric(clusterName string) base.MetricResult {
+	return ReadThrottleMetric(probe, clusterName)
+}
+
+// GetDBUri returns a libpq connection URL for the probe's server
+func (probe *Probe) GetDBUri() string {
+	query := url.Values{}
+	query.Set("sslmode", probe.SSLMode)
+	query.Set("connect_timeout", strconv.Itoa(timeoutSeconds))
+	query.Set("application_name", "freno")
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(probe.User, probe.Password),
+		Host:     net.JoinHostPort(probe.Key.Hostname, strconv.Itoa(probe.Key.Port)),
+		Path:     "/" + probe.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
@@ -0,0 +1,85 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/github/freno/pkg/mysql"
+)
+
+// replayLagQuery reads, on a replica, the WAL receiver status and the time since the last replayed transaction.
+// A streaming replica which has replayed all WAL it has received is not lagging, even if the primary has been
+// idle for a while. A replica which is not streaming cannot tell whether it has received all WAL.
+const replayLagQuery = `
+	with wal_receiver as (
+		select coalesce((select status from pg_stat_wal_receiver), '') as status
+	)
+	select
+		pg_is_in_recovery(),
+		wal_receiver.status,
+		case
+			when wal_receiver.status = 'streaming' and pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+			else extract(epoch from now() - pg_last_xact_replay_timestamp())
+		end
+	from
+		wal_receiver
+`
+
+// statReplicationLagQuery reads, on a primary, the worst replay lag of its connected replicas
+const statReplicationLagQuery = `
+	select
+		count(*),
+		coalesce(max(extract(epoch from replay_lag)), 0)
+	from
+		pg_stat_replication
+`
+
+// readReplayLag reads replication lag on a replica, in seconds
+func readReplayLag(ctx context.Context, db *sql.DB, key *mysql.InstanceKey) (lag float64, err error) {
+	var isInRecovery bool
+	var walReceiverStatus string
+	var replayLag sql.NullFloat64
+	if err := db.QueryRowContext(ctx, replayLagQuery).Scan(&isInRecovery, &walReceiverStatus, &replayLag); err != nil {
+		return 0, err
+	}
+	if !isInRecovery {
+		return 0, fmt.Errorf("%s is not a replica (not in recovery)", key.StringCode())
+	}
+	if walReceiverStatus != "streaming" {
+		return 0, fmt.Errorf("%s is not streaming WAL (WAL receiver status: %q)", key.StringCode(), walReceiverStatus)
+	}
+	if !replayLag.Valid {
+		return 0, fmt.Errorf("%s has not replayed any transaction", key.StringCode())
+	}
+	return replayLag.Float64, nil
+}
+
+// readStatReplicationLag reads the worst replication lag of a primary's replicas, in seconds
+func readStatReplicationLag(ctx context.Context, db *sql.DB, key *mysql.InstanceKey) (lag float64, err error) {
+	var numReplicas int
+	if err := db.QueryRowContext(ctx, statReplicationLagQuery).Scan(&numReplicas, &lag); err != nil {
+		return 0, err
+	}
+	if numReplicas == 0 {
+		return 0, fmt.Errorf("%s has no connected replicas", key.StringCode())
+	}
+	return lag, nil
+}
+
+// readMetricQuery reads a single numeric value via given query
+func readMetricQuery(ctx context.Context, db *sql.DB, query string) (value float64, err error) {
+	var nullableValue sql.NullFloat64
+	if err := db.QueryRowContext(ctx, query).Scan(&nullableValue); err != nil {
+		return 0, err
+	}
+	if !nullableValue.Valid {
+		return 0, fmt.Errorf("NULL value returned by: %s", query)
+	}
+	return nullableValue.Float64, nil
+}
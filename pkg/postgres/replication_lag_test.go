@@ -0,0 +1,82 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/github/freno/pkg/mysql"
+	test "github.com/outbrain/golib/tests"
+)
+
+var testKey = mysql.InstanceKey{Hostname: "pg-replica", Port: 5432}
+
+func TestReadReplayLag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	columns := []string{"pg_is_in_recovery", "status", "lag"}
+	mock.ExpectQuery(`pg_last_xact_replay_timestamp`).WillReturnRows(sqlmock.NewRows(columns).AddRow(true, "streaming", 1.5))
+	lag, err := readReplayLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(lag, 1.5)
+
+	// primary
+	mock.ExpectQuery(`pg_last_xact_replay_timestamp`).WillReturnRows(sqlmock.NewRows(columns).AddRow(false, "", nil))
+	_, err = readReplayLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNotNil(err)
+
+	// replica yet to replay any transaction
+	mock.ExpectQuery(`pg_last_xact_replay_timestamp`).WillReturnRows(sqlmock.NewRows(columns).AddRow(true, "streaming", nil))
+	_, err = readReplayLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNotNil(err)
+
+	// replica whose WAL receiver is down: having replayed all received WAL says nothing of its lag
+	mock.ExpectQuery(`pg_last_xact_replay_timestamp`).WillReturnRows(sqlmock.NewRows(columns).AddRow(true, "", 0))
+	_, err = readReplayLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNotNil(err)
+
+	// replica whose WAL receiver is yet to connect
+	mock.ExpectQuery(`pg_last_xact_replay_timestamp`).WillReturnRows(sqlmock.NewRows(columns).AddRow(true, "startup", 0))
+	_, err = readReplayLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNotNil(err)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestReadStatReplicationLag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	mock.ExpectQuery(`pg_stat_replication`).WillReturnRows(sqlmock.NewRows([]string{"count", "lag"}).AddRow(2, 0.25))
+	lag, err := readStatReplicationLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(lag, 0.25)
+
+	mock.ExpectQuery(`pg_stat_replication`).WillReturnRows(sqlmock.NewRows([]string{"count", "lag"}).AddRow(0, 0))
+	_, err = readStatReplicationLag(context.Background(), db, &testKey)
+	test.S(t).ExpectNotNil(err)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
+
+func TestReadMetricQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	test.S(t).ExpectNil(err)
+	defer db.Close()
+
+	mock.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(7))
+	value, err := readMetricQuery(context.Background(), db, "select count(*) from pg_stat_activity")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 7.0)
+
+	mock.ExpectQuery(`select`).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(nil))
+	_, err = readMetricQuery(context.Background(), db, "select max(lag) from meta.lags")
+	test.S(t).ExpectNotNil(err)
+	test.S(t).ExpectNil(mock.ExpectationsWereMet())
+}
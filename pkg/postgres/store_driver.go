@@ -0,0 +1,93 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/mysql"
+	"github.com/github/freno/pkg/store"
+)
+
+const StoreType = "postgres"
+
+func init() {
+	store.Register(newStoreDriver())
+}
+
+// storeDriver is the "postgres" store driver
+type storeDriver struct {
+	activeProbes *store.ActiveProbes
+}
+
+func newStoreDriver() *storeDriver {
+	return &storeDriver{
+		activeProbes: store.NewActiveProbes(),
+	}
+}
+
+func (driver *storeDriver) StoreType() string {
+	return StoreType
+}
+
+func (driver *storeDriver) Clusters() (clusters [](*store.Cluster)) {
+	for clusterName, clusterSettings := range config.Settings().Stores.PostgreSQL.Clusters {
+		clusterName := clusterName
+		clusterSettings := clusterSettings
+		clusters = append(clusters, &store.Cluster{
+			Name:                 clusterName,
+			ThrottleThreshold:    clusterSettings.ThrottleThreshold,
+			IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
+			IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
+			IgnoreHosts:          clusterSettings.IgnoreHosts,
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return discover(clusterName, clusterSettings)
+			}),
+		})
+	}
+	return clusters
+}
+
+// discover returns probes of a cluster's servers, as listed by HAProxy or static settings
+func discover(clusterName string, clusterSettings *config.PostgreSQLClusterConfigurationSettings) (probes []store.Prober, err error) {
+	user, password, err := clusterSettings.Credentials()
+	if err != nil {
+		return probes, fmt.Errorf("Unable to read credentials for PostgreSQL cluster %s: %+v", clusterName, err)
+	}
+	hosts, err := store.ReadHosts(clusterName, &store.HostsSettings{
+		HAProxySettings:     &clusterSettings.HAProxySettings,
+		StaticHostsSettings: &clusterSettings.StaticHostsSettings,
+		Port:                clusterSettings.Port,
+	})
+	if err != nil {
+		return probes, err
+	}
+	for _, host := range hosts {
+		probes = append(probes, &Probe{
+			Key:         mysql.InstanceKey{Hostname: host.Host, Port: host.Port},
+			User:        user,
+			Password:    password,
+			Database:    clusterSettings.Database,
+			SSLMode:     clusterSettings.SSLMode,
+			MetricQuery: clusterSettings.MetricQuery,
+			MetricType:  clusterSettings.MetricType,
+			CacheMillis: clusterSettings.CacheMillis,
+		})
+	}
+	return probes, nil
+}
+
+// UpdateClusterProbes closes connection pools of servers no longer probed
+func (driver *storeDriver) UpdateClusterProbes(clusterProbes *store.ClusterProbes) {
+	activeKeys := make(map[mysql.InstanceKey]bool)
+	for _, prober := range driver.activeProbes.Update(clusterProbes) {
+		if probe, ok := prober.(*Probe); ok {
+			activeKeys[probe.Key] = true
+		}
+	}
+	go EvictConnectionPools(activeKeys)
+}
@@ -0,0 +1,121 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sample is a single sample of the Prometheus text exposition format, e.g.
+// mysql_slave_status_seconds_behind_master{channel_name="",master_host="db-0001"} 0
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// ParseExposition parses samples of the Prometheus text exposition format. Comments, including HELP and TYPE
+// lines, are skipped. Timestamps are ignored.
+func ParseExposition(body []byte) (samples []*Sample, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseSampleLine(line)
+		if err != nil {
+			return samples, fmt.Errorf("line %d: %+v", lineNumber, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+func parseSampleLine(line string) (*Sample, error) {
+	sample := &Sample{Labels: map[string]string{}}
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return nil, fmt.Errorf("cannot parse sample: %s", line)
+	}
+	sample.Name = line[:nameEnd]
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		labels, remainder, err := parseLabels(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		sample.Labels = labels
+		rest = remainder
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("cannot parse value of sample: %s", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of sample: %s", line)
+	}
+	sample.Value = value
+	return sample, nil
+}
+
+// parseLabels parses label pairs up to and including the closing brace, and returns the remainder of the line
+func parseLabels(s string) (labels map[string]string, remainder string, err error) {
+	labels = map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+		equals := strings.Index(s, "=")
+		if equals <= 0 {
+			return labels, s, fmt.Errorf("cannot parse labels: %s", s)
+		}
+		name := strings.TrimSpace(s[:equals])
+		value, valueRemainder, err := parseQuotedString(strings.TrimLeft(s[equals+1:], " \t"))
+		if err != nil {
+			return labels, s, err
+		}
+		labels[name] = value
+		s = valueRemainder
+	}
+}
+
+// parseQuotedString parses a double quoted label value, handling \\, \" and \n escapes
+func parseQuotedString(s string) (value string, remainder string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, fmt.Errorf("expected quoted label value: %s", s)
+	}
+	var builder strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return builder.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", s, fmt.Errorf("unterminated label value: %s", s)
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			default:
+				builder.WriteByte(s[i])
+			}
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return "", s, fmt.Errorf("unterminated label value: %s", s)
+}
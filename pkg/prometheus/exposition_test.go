@@ -0,0 +1,63 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	body, err := os.ReadFile("testdata/" + name)
+	test.S(t).ExpectNil(err)
+	return body
+}
+
+func TestParseExposition(t *testing.T) {
+	samples, err := ParseExposition(readFixture(t, "mysqld_exporter.txt"))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(samples), 9)
+
+	test.S(t).ExpectEquals(samples[0].Name, "mysql_up")
+	test.S(t).ExpectEquals(len(samples[0].Labels), 0)
+	test.S(t).ExpectEquals(samples[0].Value, 1.0)
+
+	test.S(t).ExpectEquals(samples[2].Name, "mysql_slave_status_seconds_behind_master")
+	test.S(t).ExpectEquals(samples[2].Labels["channel_name"], "archive")
+	test.S(t).ExpectEquals(samples[2].Labels["master_host"], "db-archive.example.com")
+	test.S(t).ExpectEquals(samples[2].Value, 17.0)
+
+	// with timestamp
+	test.S(t).ExpectEquals(samples[7].Value, 0.001843)
+	// escaped label value
+	test.S(t).ExpectEquals(samples[8].Labels["collector"], `escaped "label" \ value`)
+	test.S(t).ExpectTrue(math.IsNaN(samples[8].Value))
+}
+
+func TestParseExpositionSpecialValues(t *testing.T) {
+	samples, err := ParseExposition(readFixture(t, "node_exporter.txt"))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(samples), 5)
+	test.S(t).ExpectEquals(samples[3].Value, 1.5e+11)
+	test.S(t).ExpectTrue(math.IsInf(samples[4].Value, 1))
+}
+
+func TestParseExpositionErrors(t *testing.T) {
+	for _, body := range []string{
+		"node_load1",
+		"node_load1 high",
+		`node_load1{cpu="0} 1`,
+		`node_load1{cpu=0} 1`,
+		"{cpu=\"0\"} 1",
+		"node_load1 1 2 3",
+	} {
+		_, err := ParseExposition([]byte(body))
+		test.S(t).ExpectNotNil(err)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/github/freno/pkg/config"
+)
+
+// Extractor reads the value of the samples selected by a selector, off a Prometheus text exposition.
+// Multiple selected samples are aggregated.
+type Extractor struct {
+	Selector    *Selector
+	Aggregation string
+}
+
+func NewExtractor(selector string, aggregation string) (*Extractor, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Extractor{Selector: parsed, Aggregation: aggregation}, nil
+}
+
+func (extractor *Extractor) Extract(body []byte) (float64, error) {
+	samples, err := ParseExposition(body)
+	if err != nil {
+		return 0, err
+	}
+	values := []float64{}
+	for _, sample := range samples {
+		if extractor.Selector.Matches(sample) && !math.IsNaN(sample.Value) {
+			values = append(values, sample.Value)
+		}
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no samples found for %s", extractor.Selector.Name)
+	}
+	return aggregate(values, extractor.Aggregation)
+}
+
+func aggregate(values []float64, aggregation string) (float64, error) {
+	result := values[0]
+	for _, value := range values[1:] {
+		switch aggregation {
+		case config.PrometheusAggregationMax:
+			result = math.Max(result, value)
+		case config.PrometheusAggregationMin:
+			result = math.Min(result, value)
+		case config.PrometheusAggregationSum, config.PrometheusAggregationAvg:
+			result += value
+		default:
+			return 0, fmt.Errorf("unsupported aggregation: %s", aggregation)
+		}
+	}
+	if aggregation == config.PrometheusAggregationAvg {
+		result = result / float64(len(values))
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/httpstore"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func newFixturesServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mysqld/metrics":
+			w.Write(readFixture(t, "mysqld_exporter.txt"))
+		case "/node/metrics":
+			w.Write(readFixture(t, "node_exporter.txt"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestExtractorOverHTTP(t *testing.T) {
+	server := newFixturesServer(t)
+	defer server.Close()
+
+	readMetric := func(path string, selector string, aggregation string) (float64, error) {
+		extractor, err := NewExtractor(selector, aggregation)
+		test.S(t).ExpectNil(err)
+		probe := &httpstore.Probe{StoreType: "prometheus", URL: server.URL + path, TimeoutMillis: 1000, Extractor: extractor}
+		return httpstore.ReadMetric(probe, "TestExtractorOverHTTP").Get()
+	}
+	{
+		value, err := readMetric("/mysqld/metrics", `mysql_slave_status_seconds_behind_master{channel_name=""}`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 2.0)
+	}
+	{
+		value, err := readMetric("/mysqld/metrics", `mysql_slave_status_seconds_behind_master`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 17.0)
+	}
+	{
+		value, err := readMetric("/mysqld/metrics", `mysql_info_schema_processlist_threads{command="query"}`, config.PrometheusAggregationSum)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 8.0)
+	}
+	{
+		value, err := readMetric("/node/metrics", `node_load1`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 3.47)
+	}
+	{
+		value, err := readMetric("/node/metrics", `node_filesystem_avail_bytes{fstype!="tmpfs"}`, config.PrometheusAggregationMin)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 4.2e+10)
+	}
+	{
+		// NaN samples are disregarded
+		_, err := readMetric("/mysqld/metrics", `mysql_exporter_collector_duration_seconds{collector=~"escaped.*"}`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := readMetric("/node/metrics", `node_load15`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := readMetric("/postgres/metrics", `pg_up`, config.PrometheusAggregationMax)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	values := []float64{3, 1, 8}
+	for aggregation, expected := range map[string]float64{
+		config.PrometheusAggregationMax: 8,
+		config.PrometheusAggregationMin: 1,
+		config.PrometheusAggregationSum: 12,
+		config.PrometheusAggregationAvg: 4,
+	} {
+		value, err := aggregate(values, aggregation)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, expected)
+	}
+	_, err := aggregate(values, "median")
+	test.S(t).ExpectNotNil(err)
+}
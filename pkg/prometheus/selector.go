@@ -0,0 +1,112 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// LabelMatcher matches a sample's label value, as in PromQL. A missing label has an empty value.
+type LabelMatcher struct {
+	Name   string
+	Op     string
+	Value  string
+	regexp *regexp.Regexp
+}
+
+func (matcher *LabelMatcher) Matches(labels map[string]string) bool {
+	value := labels[matcher.Name]
+	switch matcher.Op {
+	case MatchEqual:
+		return value == matcher.Value
+	case MatchNotEqual:
+		return value != matcher.Value
+	case MatchRegexp:
+		return matcher.regexp.MatchString(value)
+	case MatchNotRegexp:
+		return !matcher.regexp.MatchString(value)
+	}
+	return false
+}
+
+// Selector selects samples by metric name and label matchers, e.g. mysql_slave_status_seconds_behind_master{channel_name=""}
+type Selector struct {
+	Name     string
+	Matchers [](*LabelMatcher)
+}
+
+// ParseSelector parses a PromQL-like instant vector selector: a metric name, optionally followed by
+// label matchers using =, !=, =~ or !~
+func ParseSelector(selector string) (*Selector, error) {
+	selector = strings.TrimSpace(selector)
+	braces := strings.Index(selector, "{")
+	if braces < 0 {
+		if selector == "" || strings.ContainsAny(selector, " \t}\"") {
+			return nil, fmt.Errorf("invalid selector: %s", selector)
+		}
+		return &Selector{Name: selector}, nil
+	}
+	result := &Selector{Name: strings.TrimSpace(selector[:braces])}
+	if result.Name == "" {
+		return nil, fmt.Errorf("selector requires a metric name: %s", selector)
+	}
+	s := selector[braces+1:]
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "}" {
+			return result, nil
+		}
+		opIndex := strings.IndexAny(s, "=!")
+		if opIndex <= 0 {
+			return nil, fmt.Errorf("invalid selector: %s", selector)
+		}
+		matcher := &LabelMatcher{Name: strings.TrimSpace(s[:opIndex])}
+		s = s[opIndex:]
+		for _, op := range []string{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+			if strings.HasPrefix(s, op) {
+				matcher.Op = op
+				break
+			}
+		}
+		if matcher.Op == "" {
+			return nil, fmt.Errorf("invalid selector: %s", selector)
+		}
+		value, remainder, err := parseQuotedString(strings.TrimLeft(s[len(matcher.Op):], " \t"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %s: %+v", selector, err)
+		}
+		matcher.Value = value
+		if matcher.Op == MatchRegexp || matcher.Op == MatchNotRegexp {
+			// As in PromQL, regular expressions are fully anchored
+			if matcher.regexp, err = regexp.Compile("^(?:" + value + ")$"); err != nil {
+				return nil, fmt.Errorf("invalid selector: %s: %+v", selector, err)
+			}
+		}
+		result.Matchers = append(result.Matchers, matcher)
+		s = remainder
+	}
+}
+
+func (selector *Selector) Matches(sample *Sample) bool {
+	if sample.Name != selector.Name {
+		return false
+	}
+	for _, matcher := range selector.Matchers {
+		if !matcher.Matches(sample.Labels) {
+			return false
+		}
+	}
+	return true
+}
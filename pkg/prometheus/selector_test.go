@@ -0,0 +1,65 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestParseSelector(t *testing.T) {
+	{
+		selector, err := ParseSelector("node_load1")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(selector.Name, "node_load1")
+		test.S(t).ExpectEquals(len(selector.Matchers), 0)
+	}
+	{
+		selector, err := ParseSelector(`mysql_slave_status_seconds_behind_master{channel_name=""}`)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(selector.Name, "mysql_slave_status_seconds_behind_master")
+		test.S(t).ExpectEquals(len(selector.Matchers), 1)
+		test.S(t).ExpectEquals(selector.Matchers[0].Name, "channel_name")
+		test.S(t).ExpectEquals(selector.Matchers[0].Op, MatchEqual)
+		test.S(t).ExpectEquals(selector.Matchers[0].Value, "")
+	}
+	{
+		selector, err := ParseSelector(`node_filesystem_avail_bytes{fstype!="tmpfs", mountpoint=~"/var/.*", device!~"loop[0-9]+"}`)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(selector.Matchers), 3)
+		test.S(t).ExpectEquals(selector.Matchers[0].Op, MatchNotEqual)
+		test.S(t).ExpectEquals(selector.Matchers[1].Op, MatchRegexp)
+		test.S(t).ExpectEquals(selector.Matchers[2].Op, MatchNotRegexp)
+	}
+	for _, invalid := range []string{
+		"",
+		`{channel_name=""}`,
+		`node_load1{cpu}`,
+		`node_load1{cpu="0"`,
+		`node_load1{cpu=0}`,
+		`node_load1{cpu=~"("}`,
+	} {
+		_, err := ParseSelector(invalid)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	sample := &Sample{Name: "node_filesystem_avail_bytes", Labels: map[string]string{"fstype": "xfs", "mountpoint": "/var/lib/mysql"}}
+	matches := func(selector string) bool {
+		parsed, err := ParseSelector(selector)
+		test.S(t).ExpectNil(err)
+		return parsed.Matches(sample)
+	}
+	test.S(t).ExpectTrue(matches(`node_filesystem_avail_bytes`))
+	test.S(t).ExpectTrue(matches(`node_filesystem_avail_bytes{fstype="xfs"}`))
+	test.S(t).ExpectTrue(matches(`node_filesystem_avail_bytes{fstype!="tmpfs",mountpoint=~"/var/.*"}`))
+	test.S(t).ExpectTrue(matches(`node_filesystem_avail_bytes{device=""}`))
+	test.S(t).ExpectFalse(matches(`node_filesystem_avail_bytes{mountpoint=~"/var"}`))
+	test.S(t).ExpectFalse(matches(`node_filesystem_avail_bytes{mountpoint!~"/var/.*"}`))
+	test.S(t).ExpectFalse(matches(`node_filesystem_size_bytes`))
+}
@@ -0,0 +1,56 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/httpstore"
+	"github.com/github/freno/pkg/store"
+)
+
+const StoreType = "prometheus"
+
+func init() {
+	store.Register(&storeDriver{})
+}
+
+// storeDriver is the "prometheus" store driver: metrics scraped off Prometheus exporters
+type storeDriver struct{}
+
+func (driver *storeDriver) StoreType() string {
+	return StoreType
+}
+
+func (driver *storeDriver) Clusters() (clusters [](*store.Cluster)) {
+	for clusterName, clusterSettings := range config.Settings().Stores.Prometheus.Clusters {
+		clusterSettings := clusterSettings
+		clusters = append(clusters, &store.Cluster{
+			Name:                 clusterName,
+			ThrottleThreshold:    clusterSettings.ThrottleThreshold,
+			IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
+			IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
+			IgnoreHosts:          clusterSettings.IgnoreHosts,
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return discover(clusterSettings)
+			}),
+		})
+	}
+	return clusters
+}
+
+// discover returns probes of a cluster's configured exporter URLs
+func discover(clusterSettings *config.PrometheusClusterConfigurationSettings) ([]store.Prober, error) {
+	extractor, err := NewExtractor(clusterSettings.Metric, clusterSettings.Aggregation)
+	if err != nil {
+		return nil, err
+	}
+	return httpstore.NewProbes(clusterSettings.URLs, extractor, httpstore.Probe{
+		StoreType:     StoreType,
+		Headers:       clusterSettings.Headers,
+		TimeoutMillis: clusterSettings.TimeoutMillis,
+		CacheMillis:   clusterSettings.CacheMillis,
+	}), nil
+}
@@ -0,0 +1,37 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestStoreDriverClusters(t *testing.T) {
+	clusters := config.Settings().Stores.Prometheus.Clusters
+	defer func() { config.Settings().Stores.Prometheus.Clusters = clusters }()
+
+	config.Settings().Stores.Prometheus.Clusters = map[string](*config.PrometheusClusterConfigurationSettings){
+		"replicas": {
+			URLs:        []string{"http://db-0002:9104/metrics"},
+			Metric:      "mysql_slave_status_seconds_behind_master",
+			Aggregation: config.PrometheusAggregationMax,
+			IgnoreHosts: []string{"db-0009"},
+		},
+	}
+	driverClusters := (&storeDriver{}).Clusters()
+	test.S(t).ExpectEquals(len(driverClusters), 1)
+	test.S(t).ExpectEquals(driverClusters[0].IgnoreHosts[0], "db-0009")
+
+	// exporters are subject to skipped hosts by their URL's hostname
+	probes, err := driverClusters[0].Discoverer.Discover()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(probes), 1)
+	test.S(t).ExpectEquals(probes[0].ProbeHostname(), "db-0002")
+}
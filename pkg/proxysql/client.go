@@ -1,10 +1,12 @@
 package proxysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/github/freno/pkg/config"
@@ -15,6 +17,12 @@ import (
 
 const ignoreServerCacheCleanupTTL = time.Duration(500) * time.Millisecond
 
+// failedAddressTTL is the time for which an address which failed to answer is only tried after all others
+const failedAddressTTL = 30 * time.Second
+
+// pingTimeout bounds the health check of a ProxySQL address
+const pingTimeout = time.Second
+
 // MySQLConnectionPoolServer represents a row in the stats_mysql_connection_pool table
 type MySQLConnectionPoolServer struct {
 	Host   string
@@ -27,46 +35,99 @@ func (ms *MySQLConnectionPoolServer) Address() string {
 	return fmt.Sprintf("%s:%d", ms.Host, ms.Port)
 }
 
-// Client is the ProxySQL admin client
+// Client is the ProxySQL admin client. It is safe for concurrent use.
 type Client struct {
 	dbs                    map[string]*sql.DB
+	failedAddresses        map[string]time.Time // addresses which failed to answer, by time of failure
 	defaultIgnoreServerTTL time.Duration
 	ignoreServerCache      *cache.Cache
+	mutex                  sync.Mutex
 }
 
 // NewClient returns a new ProxySQL admin client
 func NewClient(defaultIgnoreServerTTL time.Duration) *Client {
 	return &Client{
 		dbs:                    make(map[string]*sql.DB, 0),
+		failedAddresses:        make(map[string]time.Time),
 		defaultIgnoreServerTTL: defaultIgnoreServerTTL,
 		ignoreServerCache:      cache.New(cache.NoExpiration, ignoreServerCacheCleanupTTL),
 	}
 }
 
-// GetDB returns a configured ProxySQL admin connection
-func (c *Client) GetDB(settings config.ProxySQLConfigurationSettings) (*sql.DB, string, error) {
-	addrs := settings.Addresses
-	sort.Strings(addrs)
+// getDBUri returns the DSN of a ProxySQL admin connection
+func getDBUri(settings config.ProxySQLConfigurationSettings, addr string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?interpolateParams=true&timeout=500ms%s",
+		settings.User, settings.Password, addr, config.ProxySQLDefaultDatabase, settings.TLSSettings.DSNParams(),
+	)
+}
 
-	var lastErr error
+// preferredAddresses returns given addresses in order of preference: as configured, except that addresses which
+// recently failed come last, the earliest failed first
+func (c *Client) preferredAddresses(addrs []string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var healthy, failed []string
 	for _, addr := range addrs {
-		if db, found := c.dbs[addr]; found {
-			return db, addr, nil
+		if failedAt, found := c.failedAddresses[addr]; found && time.Since(failedAt) < failedAddressTTL {
+			failed = append(failed, addr)
+		} else {
+			healthy = append(healthy, addr)
 		}
-		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?interpolateParams=true&timeout=500ms",
-			settings.User, settings.Password, addr, config.ProxySQLDefaultDatabase,
-		))
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return c.failedAddresses[failed[i]].Before(c.failedAddresses[failed[j]])
+	})
+	return append(healthy, failed...)
+}
+
+// getOrOpenDB returns the cached connection pool of given address, opening one if there is none
+func (c *Client) getOrOpenDB(settings config.ProxySQLConfigurationSettings, addr string) (*sql.DB, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if db, found := c.dbs[addr]; found {
+		return db, nil
+	}
+	db, err := sql.Open("mysql", getDBUri(settings, addr))
+	if err != nil {
+		return nil, err
+	}
+	if c.dbs == nil {
+		c.dbs = make(map[string]*sql.DB)
+	}
+	c.dbs[addr] = db
+	return db, nil
+}
+
+// GetDB returns a configured ProxySQL admin connection, of the first address by preference which answers a ping.
+// Addresses which do not answer are avoided for a while. Their pools are not closed, as other clusters' queries
+// may be using them.
+func (c *Client) GetDB(settings config.ProxySQLConfigurationSettings) (*sql.DB, string, error) {
+	var lastErr error
+	for _, addr := range c.preferredAddresses(settings.Addresses) {
+		db, err := c.getOrOpenDB(settings, addr)
 		if err != nil {
 			lastErr = err
+			c.MarkFailed(addr)
 			continue
 		}
-		if err = db.Ping(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err = db.PingContext(ctx)
+		cancel()
+		if err != nil {
+			log.Debugf("unable to ping ProxySQL at %s: %+v", settings.AddressToDSN(addr), err)
 			lastErr = err
+			c.MarkFailed(addr)
 			continue
 		}
-		log.Debugf("connected to ProxySQL at %s", settings.AddressToDSN(addr))
-		c.dbs[addr] = db
-		return c.dbs[addr], addr, nil
+		c.mutex.Lock()
+		if _, found := c.failedAddresses[addr]; found {
+			log.Infof("ProxySQL at %s answers again", settings.AddressToDSN(addr))
+			delete(c.failedAddresses, addr)
+		}
+		c.mutex.Unlock()
+		return db, addr, nil
 	}
 	if lastErr != nil {
 		return nil, "", lastErr
@@ -74,22 +135,52 @@ func (c *Client) GetDB(settings config.ProxySQLConfigurationSettings) (*sql.DB,
 	return nil, "", errors.New("failed to get connection")
 }
 
-// CloseDB closes a ProxySQL admin connection based on an address string
+// MarkFailed considers an address failed, so that other addresses are preferred for a while
+func (c *Client) MarkFailed(addr string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.failedAddresses == nil {
+		c.failedAddresses = make(map[string]time.Time)
+	}
+	c.failedAddresses[addr] = time.Now()
+}
+
+// CloseDB closes a ProxySQL admin connection based on an address string. The address is considered failed,
+// so that other addresses are preferred for a while.
 func (c *Client) CloseDB(addr string) {
+	c.mutex.Lock()
 	if db, found := c.dbs[addr]; found {
 		db.Close()
 		delete(c.dbs, addr)
 	}
+	c.mutex.Unlock()
+
+	c.MarkFailed(addr)
+}
+
+// serversQuery returns the query listing a hostgroup's servers off given table
+func serversQuery(table string, hostgroupID uint) string {
+	if table == config.ProxySQLServersTableRuntime {
+		return fmt.Sprintf(`SELECT hostname, port, status FROM runtime_mysql_servers WHERE hostgroup_id=%d`, hostgroupID)
+	}
+	return fmt.Sprintf(`SELECT srv_host, srv_port, status FROM stats_mysql_connection_pool WHERE hostgroup=%d`, hostgroupID)
 }
 
-// GetServers returns a list of MySQLConnectionPoolServers with 'ONLINE' or 'SHUNNED_REPLICATION_LAG' status, based on hostgroup ID
+// GetServers returns a list of MySQLConnectionPoolServers of the configured hostgroup, whose status is one of the
+// configured statuses ('ONLINE' or 'SHUNNED_REPLICATION_LAG' by default). A server recently seen with any other
+// status is ignored while 'ONLINE', until the ignore-server TTL expires.
 func (c *Client) GetServers(db *sql.DB, settings config.ProxySQLConfigurationSettings) (servers []*MySQLConnectionPoolServer, err error) {
 	ignoreServerTTL := c.defaultIgnoreServerTTL
 	if settings.IgnoreServerTTLSecs > 0 {
 		ignoreServerTTL = time.Duration(settings.IgnoreServerTTLSecs) * time.Second
 	}
+	statuses := make(map[string]bool)
+	for _, status := range settings.GetStatuses() {
+		statuses[status] = true
+	}
 
-	rows, err := db.Query(fmt.Sprintf(`SELECT srv_host, srv_port, status FROM stats_mysql_connection_pool WHERE hostgroup=%d`, settings.HostgroupID))
+	rows, err := db.Query(serversQuery(settings.GetServersTable(), settings.HostgroupID))
 	if err != nil {
 		return servers, err
 	}
@@ -102,20 +193,65 @@ func (c *Client) GetServers(db *sql.DB, settings config.ProxySQLConfigurationSet
 			return nil, err
 		}
 
-		switch server.Status {
-		case "ONLINE":
+		switch {
+		case !statuses[server.Status]:
+			c.ignoreServerCache.Set(server.Address(), true, ignoreServerTTL)
+		case server.Status == "ONLINE":
 			if _, ignore := c.ignoreServerCache.Get(server.Address()); ignore {
 				log.Debugf("found %q in the proxysql ignore-server cache, ignoring ONLINE state for %s", server.Address(), ignoreServerTTL)
 				continue
 			}
 			servers = append(servers, server)
-		case "SHUNNED_REPLICATION_LAG":
-			defer c.ignoreServerCache.Delete(server.Address())
-			servers = append(servers, server)
 		default:
-			c.ignoreServerCache.Set(server.Address(), true, ignoreServerTTL)
+			c.ignoreServerCache.Delete(server.Address())
+			servers = append(servers, server)
 		}
 	}
 
 	return servers, rows.Err()
 }
+
+// ServerReplicationLag is a server's latest replication lag check by ProxySQL's monitor, as logged onto
+// monitor.mysql_server_replication_lag_log. ProxySQL only checks servers whose max_replication_lag is set.
+type ServerReplicationLag struct {
+	Host      string
+	Port      int32
+	Lag       sql.NullInt64 // seconds; NULL (or negative) when replication is not running
+	Error     sql.NullString
+	CheckedAt time.Time
+}
+
+// Value returns the lag in seconds, or an error if the check failed, found replication not running, or is older
+// than maxAge
+func (lag *ServerReplicationLag) Value(maxAge time.Duration) (float64, error) {
+	address := fmt.Sprintf("%s:%d", lag.Host, lag.Port)
+	if age := time.Since(lag.CheckedAt); age > maxAge {
+		return 0, fmt.Errorf("ProxySQL last checked replication lag of %s %s ago", address, age.Truncate(time.Second))
+	}
+	if lag.Error.Valid && lag.Error.String != "" {
+		return 0, fmt.Errorf("ProxySQL failed checking replication lag of %s: %s", address, lag.Error.String)
+	}
+	if !lag.Lag.Valid || lag.Lag.Int64 < 0 {
+		return 0, fmt.Errorf("ProxySQL found replication not running on %s", address)
+	}
+	return float64(lag.Lag.Int64), nil
+}
+
+// GetServerReplicationLag returns a server's latest replication lag check by ProxySQL's monitor. Reading the
+// monitor schema requires admin (rather than stats) credentials.
+func (c *Client) GetServerReplicationLag(ctx context.Context, db *sql.DB, host string, port int32) (*ServerReplicationLag, error) {
+	lag := &ServerReplicationLag{Host: host, Port: port}
+	var timeStartMicros int64
+	err := db.QueryRowContext(ctx,
+		`SELECT repl_lag, error, time_start_us FROM monitor.mysql_server_replication_lag_log WHERE hostname=? AND port=? ORDER BY time_start_us DESC LIMIT 1`,
+		host, port,
+	).Scan(&lag.Lag, &lag.Error, &timeStartMicros)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("No replication lag check of %s:%d logged by ProxySQL; is its max_replication_lag set?", host, port)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lag.CheckedAt = time.UnixMicro(timeStartMicros)
+	return lag, nil
+}
@@ -1,8 +1,12 @@
 package proxysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -57,6 +61,92 @@ func TestProxySQLGetDB(t *testing.T) {
 	})
 }
 
+func TestProxySQLGetDBFailover(t *testing.T) {
+	failingDb, failingMock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	failingMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	healthyDb, healthyMock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	healthyMock.ExpectPing()
+	healthyMock.ExpectPing()
+
+	c := NewClient(time.Second)
+	c.dbs["proxysql1:6032"] = failingDb
+	c.dbs["proxysql2:6032"] = healthyDb
+	settings := config.ProxySQLConfigurationSettings{Addresses: []string{"proxysql1:6032", "proxysql2:6032"}}
+
+	for i := 0; i < 2; i++ {
+		db, addr, err := c.GetDB(settings)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if addr != "proxysql2:6032" || db != healthyDb {
+			t.Fatalf("expected %q, got %q", "proxysql2:6032", addr)
+		}
+	}
+	if db, found := c.dbs["proxysql1:6032"]; !found || db != failingDb {
+		t.Fatal("expected failing address' pool to be left open, as it may be in use")
+	}
+	if _, found := c.failedAddresses["proxysql1:6032"]; !found {
+		t.Fatal("expected failing address to be marked as failed")
+	}
+	if err := healthyMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected pings: %v", err)
+	}
+	if settings.Addresses[0] != "proxysql1:6032" {
+		t.Fatalf("expected settings' addresses to remain in configured order, got %v", settings.Addresses)
+	}
+}
+
+func TestProxySQLPreferredAddresses(t *testing.T) {
+	c := NewClient(time.Second)
+	c.failedAddresses["proxysql1:6032"] = time.Now().Add(-time.Second)
+	c.failedAddresses["proxysql2:6032"] = time.Now().Add(-2 * time.Second)
+	c.failedAddresses["proxysql3:6032"] = time.Now().Add(-time.Hour)
+
+	addrs := c.preferredAddresses([]string{"proxysql1:6032", "proxysql2:6032", "proxysql3:6032", "proxysql4:6032"})
+	expected := "proxysql3:6032,proxysql4:6032,proxysql2:6032,proxysql1:6032"
+	if strings.Join(addrs, ",") != expected {
+		t.Fatalf("expected %q, got %q", expected, strings.Join(addrs, ","))
+	}
+}
+
+func TestProxySQLGetDBConcurrency(t *testing.T) {
+	c := NewClient(time.Second)
+	settings := config.ProxySQLConfigurationSettings{Addresses: []string{"proxysql1:6032", "proxysql2:6032"}}
+	for _, addr := range settings.Addresses {
+		db, _, _ := sqlmock.New()
+		c.dbs[addr] = db
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				c.CloseDB("proxysql3:6032")
+			}
+			if _, _, err := c.GetDB(settings); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestProxySQLGetDBUri(t *testing.T) {
+	settings := config.ProxySQLConfigurationSettings{User: "freno", Password: "penguin"}
+	expected := "freno:penguin@tcp(127.0.0.1:6032)/stats?interpolateParams=true&timeout=500ms"
+	if dbUri := getDBUri(settings, "127.0.0.1:6032"); dbUri != expected {
+		t.Fatalf("expected %q, got %q", expected, dbUri)
+	}
+
+	settings.TLSSettings = config.TLSConfigurationSettings{SkipVerify: true}
+	expected = "freno:penguin@tcp(127.0.0.1:6032)/stats?interpolateParams=true&timeout=500ms&tls=" + settings.TLSSettings.ConfigName()
+	if dbUri := getDBUri(settings, "127.0.0.1:6032"); dbUri != expected {
+		t.Fatalf("expected %q, got %q", expected, dbUri)
+	}
+}
+
 func TestProxySQLCloseDB(t *testing.T) {
 	db, _, _ := sqlmock.New()
 	c := &Client{
@@ -103,6 +193,38 @@ func TestProxySQLGetServers(t *testing.T) {
 		}
 	})
 
+	t.Run("statuses", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		rows := sqlmock.NewRows([]string{"hostname", "port", "status"}).
+			AddRow("replica1", 3306, "OFFLINE_SOFT").
+			AddRow("replica2", 3306, "ONLINE").
+			AddRow("replica3", 3306, "OFFLINE_HARD")
+		mock.ExpectQuery(`SELECT hostname, port, status FROM runtime_mysql_servers WHERE hostgroup_id=123`).WillReturnRows(rows)
+
+		c := &Client{
+			ignoreServerCache: cache.New(cache.NoExpiration, time.Second),
+		}
+
+		servers, err := c.GetServers(db, config.ProxySQLConfigurationSettings{
+			Addresses:    []string{"127.0.0.1:3306"},
+			HostgroupID:  123,
+			ServersTable: config.ProxySQLServersTableRuntime,
+			Statuses:     []string{"ONLINE", "OFFLINE_SOFT"},
+		})
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if len(servers) != 2 {
+			t.Fatalf("expected 2 servers, got %d", len(servers))
+		}
+		if servers[0].Host != "replica1" || servers[1].Host != "replica2" {
+			t.Fatalf("expected replica1 and replica2, got %v and %v", servers[0], servers[1])
+		}
+		if _, ignored := c.ignoreServerCache.Get("replica3:3306"); !ignored {
+			t.Fatal("expected replica3 to be ignored")
+		}
+	})
+
 	t.Run("ignored", func(t *testing.T) {
 		db, mock, _ := sqlmock.New()
 		rows := sqlmock.NewRows([]string{"srv_host", "srv_port", "status"}).
@@ -145,3 +267,70 @@ func TestProxySQLGetServers(t *testing.T) {
 		}
 	})
 }
+
+func TestProxySQLGetServerReplicationLag(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT repl_lag, error, time_start_us FROM monitor.mysql_server_replication_lag_log WHERE hostname=? AND port=? ORDER BY time_start_us DESC LIMIT 1`)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		checkedAt := time.Now().Add(-5 * time.Second)
+		rows := sqlmock.NewRows([]string{"repl_lag", "error", "time_start_us"}).AddRow(3, nil, checkedAt.UnixMicro())
+		mock.ExpectQuery(query).WithArgs("replica1", 3306).WillReturnRows(rows)
+
+		c := NewClient(time.Second)
+		lag, err := c.GetServerReplicationLag(context.Background(), db, "replica1", 3306)
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if lag.CheckedAt.UnixMicro() != checkedAt.UnixMicro() {
+			t.Fatalf("expected check at %v, got %v", checkedAt, lag.CheckedAt)
+		}
+		value, err := lag.Value(time.Minute)
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if value != 3 {
+			t.Fatalf("expected lag of 3, got %v", value)
+		}
+		if _, err := lag.Value(time.Second); err == nil {
+			t.Fatal("expected stale check to error")
+		}
+	})
+
+	t.Run("not-running", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		rows := sqlmock.NewRows([]string{"repl_lag", "error", "time_start_us"}).AddRow(nil, nil, time.Now().UnixMicro())
+		mock.ExpectQuery(query).WithArgs("replica1", 3306).WillReturnRows(rows)
+
+		lag, err := NewClient(time.Second).GetServerReplicationLag(context.Background(), db, "replica1", 3306)
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if _, err := lag.Value(time.Minute); err == nil {
+			t.Fatal("expected replication not running to error")
+		}
+	})
+
+	t.Run("check-error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		rows := sqlmock.NewRows([]string{"repl_lag", "error", "time_start_us"}).AddRow(nil, "timeout on creating new connection", time.Now().UnixMicro())
+		mock.ExpectQuery(query).WithArgs("replica1", 3306).WillReturnRows(rows)
+
+		lag, err := NewClient(time.Second).GetServerReplicationLag(context.Background(), db, "replica1", 3306)
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if _, err := lag.Value(time.Minute); err == nil || !strings.Contains(err.Error(), "timeout on creating new connection") {
+			t.Fatalf("expected check error, got %v", err)
+		}
+	})
+
+	t.Run("not-monitored", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		mock.ExpectQuery(query).WithArgs("replica1", 3306).WillReturnRows(sqlmock.NewRows([]string{"repl_lag", "error", "time_start_us"}))
+
+		if _, err := NewClient(time.Second).GetServerReplicationLag(context.Background(), db, "replica1", 3306); err == nil {
+			t.Fatal("expected error on server not monitored")
+		}
+	})
+}
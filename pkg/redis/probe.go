@@ -0,0 +1,49 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/mysql"
+)
+
+// timeout is the deadline for connecting to a server, and for each command
+const timeout = 1 * time.Second
+
+// Probe is the minimal configuration required to connect to a Redis server
+type Probe struct {
+	Key         mysql.InstanceKey
+	User        string
+	Password    string
+	MetricType  string
+	CacheMillis int
+}
+
+func (probe *Probe) String() string {
+	return fmt.Sprintf("%s, user=%s", probe.Key.DisplayString(), probe.User)
+}
+
+// ProbeKey identifies the probed server within its cluster
+func (probe *Probe) ProbeKey() string {
+	return probe.Key.StringCode()
+}
+
+func (probe *Probe) ProbeHostname() string {
+	return probe.Key.Hostname
+}
+
+// connKey identifies the probe's connection to its server
+func (probe *Probe) connKey() serverConnKey {
+	return serverConnKey{address: instanceAddress(&probe.Key), user: probe.User, password: probe.Password}
+}
+
+// ReadMetric reads the server's throttle metric, see ReadThrottleMetric
+func (probe *Probe) ReadMetric(clusterName string) base.MetricResult {
+	return ReadThrottleMetric(probe, clusterName)
+}
@@ -0,0 +1,243 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/mysql"
+
+	"github.com/patrickmn/go-cache"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+var redisMetricCache = cache.New(cache.NoExpiration, 10*time.Millisecond)
+
+// serverConnKey identifies a connection: clusters probing a server with different credentials each have their own
+type serverConnKey struct {
+	address  string
+	user     string
+	password string
+}
+
+// serverConn is a single, reused connection to a server. Commands on it are serialized.
+type serverConn struct {
+	conn  *Conn
+	mutex sync.Mutex
+}
+
+// serverConns holds a connection per server address and credentials; both probed servers and their primaries
+var serverConns = make(map[serverConnKey](*serverConn))
+
+// replicaPrimaries holds the address of each probed replica's primary, as last read, by the replica's connection
+var replicaPrimaries = make(map[serverConnKey]string)
+var serverConnsMutex sync.Mutex
+
+func getServerConn(key serverConnKey) *serverConn {
+	serverConnsMutex.Lock()
+	defer serverConnsMutex.Unlock()
+
+	if server, found := serverConns[key]; found {
+		return server
+	}
+	serverConns[key] = &serverConn{}
+	return serverConns[key]
+}
+
+// setReplicaPrimary registers the primary of a probed replica, or that the probed server is not a replica
+func setReplicaPrimary(replicaKey serverConnKey, primaryAddress string) {
+	serverConnsMutex.Lock()
+	defer serverConnsMutex.Unlock()
+
+	if primaryAddress == "" {
+		delete(replicaPrimaries, replicaKey)
+	} else {
+		replicaPrimaries[replicaKey] = primaryAddress
+	}
+}
+
+// readServerReplicationInfo runs INFO replication on given server, (re)connecting as needed. The connection
+// is dropped on error, to be re-established on next read.
+func readServerReplicationInfo(key serverConnKey) (*ReplicationInfo, error) {
+	server := getServerConn(key)
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.conn == nil {
+		conn, err := Dial(key.address, key.user, key.password, timeout)
+		if err != nil {
+			return nil, err
+		}
+		server.conn = conn
+	}
+	info, err := readReplicationInfo(server.conn)
+	if err != nil {
+		server.conn.Close()
+		server.conn = nil
+	}
+	return info, err
+}
+
+// EvictConnections closes connections to servers not in given active connections, other than to primaries of
+// active replicas. Offset histories of primaries no longer replicated from by any active replica are dropped.
+func EvictConnections(activeConnKeys map[serverConnKey]bool) {
+	serverConnsMutex.Lock()
+	defer serverConnsMutex.Unlock()
+
+	inUse := make(map[serverConnKey]bool)
+	for key := range activeConnKeys {
+		inUse[key] = true
+	}
+	activePrimaries := make(map[string]bool)
+	for replicaKey, primaryAddress := range replicaPrimaries {
+		if !activeConnKeys[replicaKey] {
+			delete(replicaPrimaries, replicaKey)
+			continue
+		}
+		// primaries are read with their replicas' credentials
+		inUse[serverConnKey{address: primaryAddress, user: replicaKey.user, password: replicaKey.password}] = true
+		activePrimaries[primaryAddress] = true
+	}
+	for key, server := range serverConns {
+		if inUse[key] {
+			continue
+		}
+		delete(serverConns, key)
+		go func(server *serverConn) {
+			server.mutex.Lock()
+			defer server.mutex.Unlock()
+			if server.conn != nil {
+				server.conn.Close()
+				server.conn = nil
+			}
+		}(server)
+	}
+	evictOffsetHistories(activePrimaries)
+}
+
+func instanceAddress(key *mysql.InstanceKey) string {
+	return net.JoinHostPort(key.Hostname, strconv.Itoa(key.Port))
+}
+
+func getRedisMetricCacheKey(probe *Probe) string {
+	return fmt.Sprintf("%s:%s", probe.Key, probe.MetricType)
+}
+
+func cacheRedisThrottleMetric(probe *Probe, throttleMetric *RedisThrottleMetric) *RedisThrottleMetric {
+	if throttleMetric.Err != nil {
+		return throttleMetric
+	}
+	if probe.CacheMillis > 0 {
+		redisMetricCache.Set(getRedisMetricCacheKey(probe), throttleMetric, time.Duration(probe.CacheMillis)*time.Millisecond)
+	}
+	return throttleMetric
+}
+
+func getCachedRedisThrottleMetric(probe *Probe) *RedisThrottleMetric {
+	if probe.CacheMillis == 0 {
+		return nil
+	}
+	if metric, found := redisMetricCache.Get(getRedisMetricCacheKey(probe)); found {
+		throttleMetric, _ := metric.(*RedisThrottleMetric)
+		return throttleMetric
+	}
+	return nil
+}
+
+type RedisThrottleMetric struct {
+	ClusterName string
+	Key         mysql.InstanceKey
+	Value       float64
+	Err         error
+}
+
+func (metric *RedisThrottleMetric) GetClusterInstanceKey() mysql.ClusterInstanceKey {
+	return mysql.GetClusterInstanceKey(metric.ClusterName, &metric.Key)
+}
+
+func (metric *RedisThrottleMetric) Get() (float64, error) {
+	return metric.Value, metric.Err
+}
+
+// readReplicationLag reads INFO replication on the probed server and, if it is a replica, on its primary.
+// Lag in bytes is the difference between the primary's and the replica's offsets. Lag in seconds is the time
+// since the primary first had data beyond the replica's offset, as per the primary's offset history.
+// A primary has no lag. errNoLagYet is returned, along with lag in bytes, while lag in seconds is unknown.
+func readReplicationLag(probe *Probe, now func() time.Time) (lagBytes int64, lagSeconds float64, err error) {
+	replicaKey := probe.connKey()
+	info, err := readServerReplicationInfo(replicaKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !info.IsReplica() {
+		setReplicaPrimary(replicaKey, "")
+		return 0, 0, nil
+	}
+	setReplicaPrimary(replicaKey, info.MasterAddress())
+	if !info.MasterLinkUp {
+		return 0, 0, fmt.Errorf("replication link to %s is down on %s", info.MasterAddress(), probe.Key.DisplayString())
+	}
+	primaryAddress := info.MasterAddress()
+	primaryInfo, err := readServerReplicationInfo(serverConnKey{address: primaryAddress, user: probe.User, password: probe.Password})
+	if err != nil {
+		return 0, 0, fmt.Errorf("primary %s: %+v", primaryAddress, err)
+	}
+	if primaryInfo.IsReplica() {
+		// chained replication: the lag is relative to the immediate primary
+		primaryInfo.MasterReplOffset = primaryInfo.SlaveReplOffset
+	}
+	history := getOffsetHistory(primaryAddress)
+	history.record(primaryInfo.MasterReplOffset, now())
+
+	if lagBytes = primaryInfo.MasterReplOffset - info.SlaveReplOffset; lagBytes < 0 {
+		// primary read after replica; replica may have caught up in between
+		lagBytes = 0
+	}
+	lagSeconds, err = history.lagSeconds(info.SlaveReplOffset, now())
+	return lagBytes, lagSeconds, err
+}
+
+// ReadThrottleMetric returns replication lag for a given probe, in seconds or in bytes as per probe's MetricType
+func ReadThrottleMetric(probe *Probe, clusterName string) (throttleMetric *RedisThrottleMetric) {
+	if throttleMetric := getCachedRedisThrottleMetric(probe); throttleMetric != nil {
+		return throttleMetric
+		// On cached results we avoid taking latency metrics
+	}
+
+	throttleMetric = &RedisThrottleMetric{ClusterName: clusterName, Key: probe.Key}
+
+	started := time.Now()
+	defer func(metric *RedisThrottleMetric, started time.Time) {
+		go func() {
+			metrics.GetOrRegisterTimer("redis.probes.latency", nil).Update(time.Since(started))
+			metrics.GetOrRegisterCounter("redis.probes.total", nil).Inc(1)
+			if metric.Err != nil {
+				metrics.GetOrRegisterCounter("redis.probes.error", nil).Inc(1)
+			}
+		}()
+	}(throttleMetric, started)
+
+	lagBytes, lagSeconds, err := readReplicationLag(probe, time.Now)
+	if err == errNoLagYet && probe.MetricType == config.RedisMetricTypeLagBytes {
+		// lag in bytes does not depend on the primary's offset history
+		err = nil
+	}
+	if err != nil {
+		throttleMetric.Err = err
+		return throttleMetric
+	}
+	if probe.MetricType == config.RedisMetricTypeLagBytes {
+		throttleMetric.Value = float64(lagBytes)
+	} else {
+		throttleMetric.Value = lagSeconds
+	}
+	return cacheRedisThrottleMetric(probe, throttleMetric)
+}
@@ -0,0 +1,235 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/mysql"
+	"github.com/github/freno/pkg/store"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func newFakeServerProbe(t *testing.T, server *fakeServer, metricType string) *Probe {
+	key, err := mysql.ParseInstanceKey(server.address(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Probe{Key: *key, Password: server.password, MetricType: metricType}
+}
+
+func primaryInfo(offset int64) string {
+	return fmt.Sprintf("# Replication\r\nrole:master\r\nconnected_slaves:1\r\nmaster_repl_offset:%d\r\n", offset)
+}
+
+func replicaInfo(primary *fakeServer, linkStatus string, offset int64) string {
+	key, _ := mysql.ParseInstanceKey(primary.address(), 0)
+	return fmt.Sprintf("# Replication\r\nrole:slave\r\nmaster_host:%s\r\nmaster_port:%d\r\nmaster_link_status:%s\r\nslave_repl_offset:%d\r\n", key.Hostname, key.Port, linkStatus, offset)
+}
+
+func TestReadThrottleMetric(t *testing.T) {
+	primary := newFakeServer(t, "secret")
+	replica := newFakeServer(t, "secret")
+	primary.setInfo(primaryInfo(5000))
+	replica.setInfo(replicaInfo(primary, "up", 5000))
+
+	start := time.Now()
+	now := start
+	clock := func() time.Time { return now }
+	{
+		lagBytes, lagSeconds, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lagBytes, int64(0))
+		test.S(t).ExpectEquals(lagSeconds, 0.0)
+	}
+	{
+		now = start.Add(3 * time.Second)
+		primary.setInfo(primaryInfo(6000))
+		lagBytes, lagSeconds, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lagBytes, int64(1000))
+		// the primary's new data was only just seen
+		test.S(t).ExpectEquals(lagSeconds, 0.0)
+	}
+	{
+		now = start.Add(4 * time.Second)
+		replica.setInfo(replicaInfo(primary, "up", 5500))
+		lagBytes, lagSeconds, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lagBytes, int64(500))
+		test.S(t).ExpectEquals(lagSeconds, 1.0)
+	}
+	{
+		replica.setInfo(replicaInfo(primary, "up", 6000))
+		metric := ReadThrottleMetric(newFakeServerProbe(t, replica, config.RedisMetricTypeLagBytes), "redis0")
+		value, err := metric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.0)
+		test.S(t).ExpectEquals(metric.ClusterName, "redis0")
+	}
+	{
+		metric := ReadThrottleMetric(newFakeServerProbe(t, primary, ""), "redis0")
+		value, err := metric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.0)
+	}
+	{
+		replica.setInfo(replicaInfo(primary, "down", 6000))
+		metric := ReadThrottleMetric(newFakeServerProbe(t, replica, ""), "redis0")
+		_, err := metric.Get()
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		probe := newFakeServerProbe(t, replica, "")
+		probe.Password = "wrong"
+		metric := ReadThrottleMetric(probe, "redis0")
+		_, err := metric.Get()
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestReadThrottleMetricLaggingOnFirstProbe(t *testing.T) {
+	primary := newFakeServer(t, "")
+	replica := newFakeServer(t, "")
+	primary.setInfo(primaryInfo(5000))
+	replica.setInfo(replicaInfo(primary, "up", 4000))
+
+	start := time.Now()
+	now := start
+	clock := func() time.Time { return now }
+	{
+		// replica already behind as the primary's history begins: how long it has been behind is unknown
+		lagBytes, _, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectEquals(err, errNoLagYet)
+		test.S(t).ExpectEquals(lagBytes, int64(1000))
+	}
+	{
+		now = start.Add(3 * time.Second)
+		_, _, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectEquals(err, errNoLagYet)
+	}
+	{
+		_, err := newFakeServerProbe(t, replica, "").ReadMetric("redis0").Get()
+		test.S(t).ExpectEquals(err, errNoLagYet)
+
+		metric := newFakeServerProbe(t, replica, config.RedisMetricTypeLagBytes).ReadMetric("redis0")
+		value, err := metric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1000.0)
+	}
+	{
+		// the replica catches up with the history
+		now = start.Add(4 * time.Second)
+		replica.setInfo(replicaInfo(primary, "up", 5000))
+		_, lagSeconds, err := readReplicationLag(newFakeServerProbe(t, replica, ""), clock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lagSeconds, 0.0)
+	}
+}
+
+func TestAggregateLaggingOnFirstProbe(t *testing.T) {
+	primary := newFakeServer(t, "")
+	lagging := newFakeServer(t, "")
+	healthy := newFakeServer(t, "")
+	primary.setInfo(primaryInfo(5000))
+	lagging.setInfo(replicaInfo(primary, "up", 4000))
+	healthy.setInfo(replicaInfo(primary, "up", 5000))
+
+	clusterProbes := &store.ClusterProbes{ClusterName: "redis0", IgnoreHostsCount: 1}
+	probeMetrics := store.ProbeMetrics{}
+	for _, server := range []*fakeServer{lagging, healthy} {
+		probe := newFakeServerProbe(t, server, "")
+		clusterProbes.Probes = append(clusterProbes.Probes, probe)
+		probeMetrics[probe.ProbeKey()] = probe.ReadMetric("redis0")
+	}
+	// the replica of unknown lag is an error of its own, tolerated by IgnoreHostsCount
+	value, err := store.AggregateProbes(clusterProbes, probeMetrics, nil).Get()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 0.0)
+
+	clusterProbes.IgnoreHostsCount = 0
+	_, err = store.AggregateProbes(clusterProbes, probeMetrics, nil).Get()
+	test.S(t).ExpectEquals(err, errNoLagYet)
+}
+
+func TestEvictConnections(t *testing.T) {
+	server := newFakeServer(t, "")
+	server.setInfo(primaryInfo(100))
+	probe := newFakeServerProbe(t, server, "")
+
+	metric := ReadThrottleMetric(probe, "redis0")
+	test.S(t).ExpectNil(metric.Err)
+	_, found := serverConns[probe.connKey()]
+	test.S(t).ExpectTrue(found)
+
+	EvictConnections(map[serverConnKey]bool{probe.connKey(): true})
+	_, found = serverConns[probe.connKey()]
+	test.S(t).ExpectTrue(found)
+
+	EvictConnections(map[serverConnKey]bool{})
+	_, found = serverConns[probe.connKey()]
+	test.S(t).ExpectFalse(found)
+}
+
+func TestServerConnsByCredentials(t *testing.T) {
+	server := newFakeServer(t, "")
+	server.setInfo(primaryInfo(100))
+	probeA := newFakeServerProbe(t, server, "")
+	probeA.User = "a"
+	probeB := newFakeServerProbe(t, server, "")
+	probeB.User = "b"
+
+	// clusters probing the same server with different credentials do not share a connection
+	test.S(t).ExpectNil(ReadThrottleMetric(probeA, "redis0").Err)
+	test.S(t).ExpectNil(ReadThrottleMetric(probeB, "redis1").Err)
+	connA := serverConns[probeA.connKey()]
+	connB := serverConns[probeB.connKey()]
+	test.S(t).ExpectTrue(connA != nil)
+	test.S(t).ExpectTrue(connB != nil)
+	test.S(t).ExpectTrue(connA != connB)
+
+	EvictConnections(map[serverConnKey]bool{probeA.connKey(): true})
+	_, found := serverConns[probeA.connKey()]
+	test.S(t).ExpectTrue(found)
+	_, found = serverConns[probeB.connKey()]
+	test.S(t).ExpectFalse(found)
+	EvictConnections(map[serverConnKey]bool{})
+}
+
+func TestEvictPrimaryConnections(t *testing.T) {
+	primary := newFakeServer(t, "")
+	replica := newFakeServer(t, "")
+	primary.setInfo(primaryInfo(5000))
+	replica.setInfo(replicaInfo(primary, "up", 5000))
+	probe := newFakeServerProbe(t, replica, "")
+	primaryConnKey := serverConnKey{address: primary.address()}
+
+	test.S(t).ExpectNil(ReadThrottleMetric(probe, "redis0").Err)
+	_, found := serverConns[primaryConnKey]
+	test.S(t).ExpectTrue(found)
+	_, found = offsetHistories[primary.address()]
+	test.S(t).ExpectTrue(found)
+
+	// the primary of a probed replica is kept, along with its offset history
+	EvictConnections(map[serverConnKey]bool{probe.connKey(): true})
+	_, found = serverConns[primaryConnKey]
+	test.S(t).ExpectTrue(found)
+	_, found = offsetHistories[primary.address()]
+	test.S(t).ExpectTrue(found)
+
+	// and evicted once the replica is no longer probed
+	EvictConnections(map[serverConnKey]bool{})
+	_, found = serverConns[primaryConnKey]
+	test.S(t).ExpectFalse(found)
+	_, found = offsetHistories[primary.address()]
+	test.S(t).ExpectFalse(found)
+	_, found = replicaPrimaries[probe.connKey()]
+	test.S(t).ExpectFalse(found)
+}
@@ -0,0 +1,176 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// offsetHistoryRetention is the time span of primary offsets kept to compute lag in seconds
+const offsetHistoryRetention = 10 * time.Minute
+
+// ReplicationInfo is the relevant output of INFO replication
+type ReplicationInfo struct {
+	Role             string // "master" or "slave"
+	MasterHost       string
+	MasterPort       int
+	MasterLinkUp     bool
+	MasterReplOffset int64 // on a primary, its replication offset
+	SlaveReplOffset  int64 // on a replica, the offset it has processed
+}
+
+func (info *ReplicationInfo) IsReplica() bool {
+	return info.Role == "slave"
+}
+
+func (info *ReplicationInfo) MasterAddress() string {
+	return net.JoinHostPort(info.MasterHost, strconv.Itoa(info.MasterPort))
+}
+
+// parseInfo parses INFO output into a field -> value map
+func parseInfo(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if tokens := strings.SplitN(line, ":", 2); len(tokens) == 2 {
+			fields[tokens[0]] = tokens[1]
+		}
+	}
+	return fields
+}
+
+func parseReplicationInfo(text string) (info *ReplicationInfo, err error) {
+	fields := parseInfo(text)
+	info = &ReplicationInfo{Role: fields["role"]}
+	parseInt := func(name string) int64 {
+		if err != nil {
+			return 0
+		}
+		var value int64
+		if value, err = strconv.ParseInt(fields[name], 10, 64); err != nil {
+			err = fmt.Errorf("cannot parse %s: %q", name, fields[name])
+		}
+		return value
+	}
+	switch info.Role {
+	case "master":
+		info.MasterReplOffset = parseInt("master_repl_offset")
+	case "slave":
+		info.MasterHost = fields["master_host"]
+		info.MasterPort = int(parseInt("master_port"))
+		info.MasterLinkUp = fields["master_link_status"] == "up"
+		info.SlaveReplOffset = parseInt("slave_repl_offset")
+	default:
+		return nil, fmt.Errorf("unexpected role: %q", info.Role)
+	}
+	return info, err
+}
+
+// readReplicationInfo runs INFO replication
+func readReplicationInfo(conn *Conn) (*ReplicationInfo, error) {
+	reply, err := conn.Do("INFO", "replication")
+	if err != nil {
+		return nil, err
+	}
+	text, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected INFO reply: %+v", reply)
+	}
+	return parseReplicationInfo(text)
+}
+
+type offsetSample struct {
+	at     time.Time
+	offset int64
+}
+
+// errNoLagYet indicates lag in seconds is unknown: the replica was already behind when the primary's offset
+// history began, and the history is too young to tell how far behind. It is an error of that replica only,
+// which IgnoreHostsCount may tolerate.
+var errNoLagYet = errors.New("lag: replica is behind the primary's offset history, which is still building up")
+
+// offsetHistory records the times at which a primary's replication offset was seen to advance
+type offsetHistory struct {
+	samples   []offsetSample
+	startedAt time.Time
+	mutex     sync.Mutex
+}
+
+// record registers a primary's offset, as read at given time
+func (history *offsetHistory) record(offset int64, now time.Time) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	if n := len(history.samples); n > 0 && offset <= history.samples[n-1].offset {
+		if offset < history.samples[n-1].offset {
+			// offsets went back: primary replaced or restarted
+			history.samples = nil
+		} else {
+			return
+		}
+	}
+	if len(history.samples) == 0 {
+		history.startedAt = now
+	}
+	history.samples = append(history.samples, offsetSample{at: now, offset: offset})
+	for len(history.samples) > 1 && now.Sub(history.samples[0].at) > offsetHistoryRetention {
+		history.samples = history.samples[1:]
+	}
+}
+
+// lagSeconds returns the time elapsed since the primary first had data beyond given replica offset. That is
+// the age of the oldest data the replica is missing, to a resolution of the primary's sampling interval.
+// A replica behind the oldest sample is lagging by at least the sample's age; while the history is younger
+// than its retention, that bound says little, and errNoLagYet is returned.
+func (history *offsetHistory) lagSeconds(replicaOffset int64, now time.Time) (float64, error) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	if len(history.samples) > 0 && replicaOffset < history.samples[0].offset && now.Sub(history.startedAt) < offsetHistoryRetention {
+		return 0, errNoLagYet
+	}
+	for _, sample := range history.samples {
+		if sample.offset > replicaOffset {
+			return now.Sub(sample.at).Seconds(), nil
+		}
+	}
+	return 0, nil
+}
+
+var offsetHistories = make(map[string](*offsetHistory))
+var offsetHistoriesMutex sync.Mutex
+
+func getOffsetHistory(primaryAddress string) *offsetHistory {
+	offsetHistoriesMutex.Lock()
+	defer offsetHistoriesMutex.Unlock()
+
+	if history, found := offsetHistories[primaryAddress]; found {
+		return history
+	}
+	offsetHistories[primaryAddress] = &offsetHistory{}
+	return offsetHistories[primaryAddress]
+}
+
+// evictOffsetHistories drops the offset histories of primaries not in given active primaries
+func evictOffsetHistories(activePrimaries map[string]bool) {
+	offsetHistoriesMutex.Lock()
+	defer offsetHistoriesMutex.Unlock()
+
+	for address := range offsetHistories {
+		if !activePrimaries[address] {
+			delete(offsetHistories, address)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestParseReplicationInfo(t *testing.T) {
+	{
+		info, err := parseReplicationInfo("# Replication\r\nrole:master\r\nconnected_slaves:1\r\nslave0:ip=10.0.0.2,port=6379,state=online,offset=1000,lag=0\r\nmaster_repl_offset:1200\r\n")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectFalse(info.IsReplica())
+		test.S(t).ExpectEquals(info.MasterReplOffset, int64(1200))
+	}
+	{
+		info, err := parseReplicationInfo("# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6380\r\nmaster_link_status:up\r\nslave_repl_offset:1000\r\nmaster_repl_offset:1000\r\n")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(info.IsReplica())
+		test.S(t).ExpectTrue(info.MasterLinkUp)
+		test.S(t).ExpectEquals(info.MasterAddress(), "10.0.0.1:6380")
+		test.S(t).ExpectEquals(info.SlaveReplOffset, int64(1000))
+	}
+	{
+		info, err := parseReplicationInfo("role:slave\nmaster_host:10.0.0.1\nmaster_port:6379\nmaster_link_status:down\nslave_repl_offset:0\n")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectFalse(info.MasterLinkUp)
+	}
+	{
+		_, err := parseReplicationInfo("role:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\n")
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := parseReplicationInfo("role:sentinel\r\n")
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestOffsetHistory(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	history := &offsetHistory{}
+	history.record(100, at(0))
+	history.record(100, at(1))
+	history.record(200, at(2))
+	history.record(300, at(5))
+	test.S(t).ExpectEquals(len(history.samples), 3)
+
+	expectLag := func(replicaOffset int64, now time.Time, expected float64) {
+		lag, err := history.lagSeconds(replicaOffset, now)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(lag, expected)
+	}
+	expectLag(300, at(6), 0.0)
+	expectLag(250, at(6), 1.0)
+	expectLag(150, at(6), 4.0)
+	expectLag(100, at(6), 4.0)
+
+	// replica already behind when history began: lag unknown while history is young
+	_, err := history.lagSeconds(50, at(6))
+	test.S(t).ExpectEquals(err, errNoLagYet)
+
+	// samples beyond retention are trimmed; a replica behind the oldest sample lags by at least its age
+	history.record(400, at(5).Add(offsetHistoryRetention+time.Second))
+	test.S(t).ExpectEquals(len(history.samples), 1)
+	expectLag(50, at(5).Add(offsetHistoryRetention+2*time.Second), 1.0)
+
+	// offset going back means a new primary; history restarts
+	history.record(10, at(1000))
+	test.S(t).ExpectEquals(len(history.samples), 1)
+	test.S(t).ExpectEquals(history.startedAt, at(1000))
+	expectLag(10, at(1002), 0.0)
+	_, err = history.lagSeconds(0, at(1002))
+	test.S(t).ExpectEquals(err, errNoLagYet)
+}
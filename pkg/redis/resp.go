@@ -0,0 +1,143 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBulkLength limits the size of a bulk string reply
+const maxBulkLength = 16 * 1024 * 1024
+
+// ErrorReply is an error returned by the server, e.g. "NOAUTH Authentication required."
+type ErrorReply string
+
+func (e ErrorReply) Error() string {
+	return string(e)
+}
+
+// Conn is a minimal RESP (REdis Serialization Protocol) client connection. It is not safe for concurrent use.
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// Dial connects to a server, and authenticates if a password is given
+func Dial(address string, user string, password string, timeout time.Duration) (*Conn, error) {
+	netConn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{conn: netConn, reader: bufio.NewReader(netConn), timeout: timeout}
+	if password != "" {
+		args := []string{"AUTH", password}
+		if user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := conn.Do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (conn *Conn) Close() error {
+	return conn.conn.Close()
+}
+
+// Do sends a command and reads its reply. Replies are returned as string (simple and bulk strings),
+// int64 (integers), nil (null bulk strings and arrays) or []interface{} (arrays). Server errors are
+// returned as ErrorReply.
+func (conn *Conn) Do(args ...string) (interface{}, error) {
+	if err := conn.conn.SetDeadline(time.Now().Add(conn.timeout)); err != nil {
+		return nil, err
+	}
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(conn.conn, command.String()); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(conn.reader)
+	if err != nil {
+		return nil, err
+	}
+	if errorReply, ok := reply.(ErrorReply); ok {
+		return nil, errorReply
+	}
+	return reply, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("invalid RESP line: %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, fmt.Errorf("empty RESP reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return ErrorReply(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP bulk length: %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		if length > maxBulkLength {
+			return nil, fmt.Errorf("RESP bulk string too long: %d", length)
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESP array length: %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		array := make([]interface{}, length)
+		for i := range array {
+			if array[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	}
+	return nil, fmt.Errorf("unsupported RESP reply: %q", line)
+}
@@ -0,0 +1,173 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// fakeServer is an in-process server speaking enough RESP to answer AUTH, PING and INFO
+type fakeServer struct {
+	listener net.Listener
+	password string
+	info     string
+	mutex    sync.Mutex
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeServer{listener: listener, password: password}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (server *fakeServer) address() string {
+	return server.listener.Addr().String()
+}
+
+func (server *fakeServer) setInfo(info string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.info = info
+}
+
+func (server *fakeServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.handle(conn)
+	}
+}
+
+func (server *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := server.password == ""
+	for {
+		request, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		args, _ := request.([]interface{})
+		if len(args) == 0 {
+			fmt.Fprint(conn, "-ERR protocol error\r\n")
+			continue
+		}
+		command, _ := args[0].(string)
+		switch strings.ToUpper(command) {
+		case "AUTH":
+			if args[len(args)-1] == server.password {
+				authenticated = true
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid username-password pair\r\n")
+			}
+		case "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "INFO":
+			if !authenticated {
+				fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+				continue
+			}
+			server.mutex.Lock()
+			info := server.info
+			server.mutex.Unlock()
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", command)
+		}
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader("+OK\r\n")))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, "OK")
+	}
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader(":42\r\n")))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, int64(42))
+	}
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader("$5\r\na\r\nbc\r\n")))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, "a\r\nbc")
+	}
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader("$-1\r\n")))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectNil(reply)
+	}
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader("*2\r\n$3\r\nfoo\r\n:7\r\n")))
+		test.S(t).ExpectNil(err)
+		array := reply.([]interface{})
+		test.S(t).ExpectEquals(len(array), 2)
+		test.S(t).ExpectEquals(array[0], "foo")
+		test.S(t).ExpectEquals(array[1], int64(7))
+	}
+	{
+		reply, err := readReply(bufio.NewReader(strings.NewReader("-ERR oops\r\n")))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, ErrorReply("ERR oops"))
+	}
+	{
+		_, err := readReply(bufio.NewReader(strings.NewReader("+OK\n")))
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := readReply(bufio.NewReader(strings.NewReader("?what\r\n")))
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestConnDo(t *testing.T) {
+	server := newFakeServer(t, "secret")
+	server.setInfo("# Replication\r\nrole:master\r\n")
+	{
+		_, err := Dial(server.address(), "", "wrong", time.Second)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		conn, err := Dial(server.address(), "", "", time.Second)
+		test.S(t).ExpectNil(err)
+		defer conn.Close()
+
+		reply, err := conn.Do("PING")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, "PONG")
+
+		_, err = conn.Do("INFO", "replication")
+		test.S(t).ExpectNotNil(err)
+		_, isErrorReply := err.(ErrorReply)
+		test.S(t).ExpectTrue(isErrorReply)
+	}
+	{
+		conn, err := Dial(server.address(), "", "secret", time.Second)
+		test.S(t).ExpectNil(err)
+		defer conn.Close()
+
+		reply, err := conn.Do("INFO", "replication")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(reply, "# Replication\r\nrole:master\r\n")
+	}
+}
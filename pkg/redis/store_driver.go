@@ -0,0 +1,90 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package redis
+
+import (
+	"fmt"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/mysql"
+	"github.com/github/freno/pkg/store"
+)
+
+const StoreType = "redis"
+
+func init() {
+	store.Register(newStoreDriver())
+}
+
+// storeDriver is the "redis" store driver
+type storeDriver struct {
+	activeProbes *store.ActiveProbes
+}
+
+func newStoreDriver() *storeDriver {
+	return &storeDriver{
+		activeProbes: store.NewActiveProbes(),
+	}
+}
+
+func (driver *storeDriver) StoreType() string {
+	return StoreType
+}
+
+func (driver *storeDriver) Clusters() (clusters [](*store.Cluster)) {
+	for clusterName, clusterSettings := range config.Settings().Stores.Redis.Clusters {
+		clusterName := clusterName
+		clusterSettings := clusterSettings
+		clusters = append(clusters, &store.Cluster{
+			Name:                 clusterName,
+			ThrottleThreshold:    clusterSettings.ThrottleThreshold,
+			IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
+			IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
+			IgnoreHosts:          clusterSettings.IgnoreHosts,
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return discover(clusterName, clusterSettings)
+			}),
+		})
+	}
+	return clusters
+}
+
+// discover returns probes of a cluster's servers, as listed by HAProxy or static settings
+func discover(clusterName string, clusterSettings *config.RedisClusterConfigurationSettings) (probes []store.Prober, err error) {
+	user, password, err := clusterSettings.Credentials()
+	if err != nil {
+		return probes, fmt.Errorf("Unable to read credentials for Redis cluster %s: %+v", clusterName, err)
+	}
+	hosts, err := store.ReadHosts(clusterName, &store.HostsSettings{
+		HAProxySettings:     &clusterSettings.HAProxySettings,
+		StaticHostsSettings: &clusterSettings.StaticHostsSettings,
+		Port:                clusterSettings.Port,
+	})
+	if err != nil {
+		return probes, err
+	}
+	for _, host := range hosts {
+		probes = append(probes, &Probe{
+			Key:         mysql.InstanceKey{Hostname: host.Host, Port: host.Port},
+			User:        user,
+			Password:    password,
+			MetricType:  clusterSettings.MetricType,
+			CacheMillis: clusterSettings.CacheMillis,
+		})
+	}
+	return probes, nil
+}
+
+// UpdateClusterProbes closes connections to servers no longer probed, by any cluster's credentials
+func (driver *storeDriver) UpdateClusterProbes(clusterProbes *store.ClusterProbes) {
+	activeConnKeys := make(map[serverConnKey]bool)
+	for _, prober := range driver.activeProbes.Update(clusterProbes) {
+		if probe, ok := prober.(*Probe); ok {
+			activeConnKeys[probe.connKey()] = true
+		}
+	}
+	go EvictConnections(activeConnKeys)
+}
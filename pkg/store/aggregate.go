@@ -0,0 +1,100 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package store
+
+import (
+	"sort"
+
+	"github.com/github/freno/pkg/base"
+)
+
+// probeValue is a (non erroneous) value of a probe, along with the host it was read from
+type probeValue struct {
+	value float64
+	host  string
+}
+
+// ProbeMetrics are the latest metrics of a cluster's hosts, by probe key
+type ProbeMetrics map[string]base.MetricResult
+
+// AggregateProbes returns the worst metric of a cluster's probes. An erroneous metric is the aggregated result,
+// along with its host, unless tolerated by the cluster's IgnoreHostsCount. filter, if non-nil, adjusts each probe's metric first.
+func AggregateProbes(clusterProbes *ClusterProbes, probeMetrics ProbeMetrics, filter MetricFilter) (worstMetric base.MetricResult) {
+	ignoreHostsCount := clusterProbes.IgnoreHostsCount
+	// clusterProbes is known not to change. It can be *replaced*, but not changed.
+	// so it's safe to iterate it
+	probeValues := []probeValue{}
+	for _, probe := range clusterProbes.Probes {
+		metric := probeMetrics[probe.ProbeKey()]
+		if filter != nil {
+			var included bool
+			if metric, included = filter.FilterMetric(clusterProbes.ClusterName, probe, metric); !included {
+				continue
+			}
+		}
+		if metric == nil {
+			return base.NoMetricResultYet
+		}
+
+		value, err := metric.Get()
+		if err != nil {
+			if ignoreHostsCount > 0 {
+				// ok to skip this error
+				ignoreHostsCount = ignoreHostsCount - 1
+				continue
+			}
+			return base.NewHostErrorMetricResult(err, probe.ProbeKey())
+		}
+
+		// No error
+		probeValues = append(probeValues, probeValue{value: value, host: probe.ProbeKey()})
+	}
+	return aggregateProbeValues(probeValues, ignoreHostsCount, clusterProbes.IgnoreHostsThreshold)
+}
+
+// aggregateProbeValues returns the worst of given (non erroneous) probe values, possibly ignoring up to
+// ignoreHostsCount of the worst values
+func aggregateProbeValues(
+	probeValues []probeValue,
+	ignoreHostsCount int,
+	ignoreHostsThreshold float64,
+) (worstMetric base.MetricResult) {
+	if len(probeValues) == 0 {
+		return base.NoHostsMetricResult
+	}
+
+	// If we got here, that means no errors (or good-to-skip errors)
+	sort.SliceStable(probeValues, func(i, j int) bool {
+		return probeValues[i].value < probeValues[j].value
+	})
+	// probeValues sorted ascending (from best, ie smallest, to worst, ie largest)
+	for ignoreHostsCount > 0 {
+		goodToIgnore := func() bool {
+			// Note that these hosts don't have errors
+			numProbeValues := len(probeValues)
+			if numProbeValues <= 1 {
+				// We wish to retain at least one host
+				return false
+			}
+			if ignoreHostsThreshold <= 0 {
+				// No threshold conditional (or implicitly "any value exceeds the threshold")
+				return true
+			}
+			if worstValue := probeValues[numProbeValues-1].value; worstValue > ignoreHostsThreshold {
+				return true
+			}
+			return false
+		}()
+		if goodToIgnore {
+			probeValues = probeValues[0 : len(probeValues)-1]
+		}
+		// And, whether ignored or not, we are reducing our tokens
+		ignoreHostsCount = ignoreHostsCount - 1
+	}
+	worstValue := probeValues[len(probeValues)-1]
+	worstMetric = base.NewHostMetricResult(worstValue.value, worstValue.host)
+	return worstMetric
+}
@@ -0,0 +1,137 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/github/freno/pkg/base"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func newTestClusterProbes(keys ...string) *ClusterProbes {
+	clusterProbes := &ClusterProbes{ClusterName: "c0"}
+	for _, key := range keys {
+		clusterProbes.Probes = append(clusterProbes.Probes, &fakeProber{key: key})
+	}
+	return clusterProbes
+}
+
+// excludingFilter excludes a single host, and fails another
+type excludingFilter struct {
+	excludedKey string
+	failedKey   string
+}
+
+func (filter *excludingFilter) FilterMetric(clusterName string, probe Prober, metric base.MetricResult) (base.MetricResult, bool) {
+	switch probe.ProbeKey() {
+	case filter.excludedKey:
+		return nil, false
+	case filter.failedKey:
+		return base.NoSuchMetric, true
+	}
+	return metric, true
+}
+
+func TestAggregateProbes(t *testing.T) {
+	clusterProbes := newTestClusterProbes("h1", "h2", "h3")
+	probeMetrics := ProbeMetrics{
+		"h1": base.NewSimpleMetricResult(1.2),
+		"h2": base.NewSimpleMetricResult(1.7),
+		"h3": base.NoSuchMetric,
+	}
+	{
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+		_, err := worstMetric.Get()
+		test.S(t).ExpectEquals(err, base.NoSuchMetricError)
+		test.S(t).ExpectEquals(worstMetric.(base.HostMetricResult).GetHost(), "h3")
+	}
+	{
+		clusterProbes.IgnoreHostsCount = 1
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+		test.S(t).ExpectEquals(worstMetric.(base.HostMetricResult).GetHost(), "h2")
+	}
+	{
+		clusterProbes.IgnoreHostsCount = 2
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+	{
+		clusterProbes.IgnoreHostsThreshold = 2.0
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+	{
+		clusterProbes.Probes = append(clusterProbes.Probes, &fakeProber{key: "h4"})
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+		test.S(t).ExpectEquals(worstMetric, base.NoMetricResultYet)
+	}
+	{
+		worstMetric := AggregateProbes(newTestClusterProbes(), probeMetrics, nil)
+		test.S(t).ExpectEquals(worstMetric, base.NoHostsMetricResult)
+	}
+}
+
+func TestAggregateProbesTiedHosts(t *testing.T) {
+	clusterProbes := newTestClusterProbes("h1", "h2", "h3")
+	clusterProbes.IgnoreHostsCount = 1
+	clusterProbes.IgnoreHostsThreshold = 1.0
+	probeMetrics := ProbeMetrics{
+		"h1": base.NewSimpleMetricResult(1.7),
+		"h2": base.NewSimpleMetricResult(1.7),
+		"h3": base.NewSimpleMetricResult(0.3),
+	}
+	// h2 is ignored; the worst host is the tied host which remains
+	worstMetric := AggregateProbes(clusterProbes, probeMetrics, nil)
+	value, err := worstMetric.Get()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 1.7)
+	test.S(t).ExpectEquals(worstMetric.(base.HostMetricResult).GetHost(), "h1")
+}
+
+func TestAggregateProbesWithFilter(t *testing.T) {
+	clusterProbes := newTestClusterProbes("h1", "h2", "h3")
+	probeMetrics := ProbeMetrics{
+		"h1": base.NewSimpleMetricResult(1.2),
+		"h2": base.NewSimpleMetricResult(1.7),
+		"h3": base.NewSimpleMetricResult(0.3),
+	}
+	{
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, &excludingFilter{excludedKey: "h2"})
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.2)
+	}
+	{
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, &excludingFilter{excludedKey: "h2", failedKey: "h1"})
+		_, err := worstMetric.Get()
+		test.S(t).ExpectEquals(err, base.NoSuchMetricError)
+	}
+	{
+		clusterProbes.IgnoreHostsCount = 1
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, &excludingFilter{excludedKey: "h2", failedKey: "h1"})
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 0.3)
+	}
+	{
+		// A host yet to be probed may be excluded
+		clusterProbes.IgnoreHostsCount = 0
+		clusterProbes.Probes = append(clusterProbes.Probes, &fakeProber{key: "h4"})
+		worstMetric := AggregateProbes(clusterProbes, probeMetrics, &excludingFilter{excludedKey: "h4"})
+		value, err := worstMetric.Get()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(value, 1.7)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/github/freno/pkg/config"
+	"github.com/github/freno/pkg/haproxy"
+
+	"github.com/outbrain/golib/log"
+)
+
+// HostsSettings are the hosts settings of a cluster whose servers are listed by HAProxy or by static settings,
+// as is the case for the PostgreSQL and Redis stores
+type HostsSettings struct {
+	HAProxySettings     *config.HAProxyConfigurationSettings
+	StaticHostsSettings *config.StaticHostsConfigurationSettings
+	Port                int // the port of hosts listed without one
+}
+
+// ReadHosts lists a cluster's servers, as listed by HAProxy or, failing HAProxy settings, by static settings.
+// Invalid hosts are skipped.
+func ReadHosts(clusterName string, settings *HostsSettings) (hosts []config.HostPort, err error) {
+	var addresses []config.HostPort
+	if !settings.HAProxySettings.IsEmpty() {
+		haproxyHosts, err := haproxy.ReadHosts(settings.HAProxySettings)
+		if err != nil {
+			return hosts, err
+		}
+		for _, host := range haproxyHosts {
+			addresses = append(addresses, config.HostPort{Host: host, Port: settings.Port})
+		}
+	} else if !settings.StaticHostsSettings.IsEmpty() {
+		for _, host := range settings.StaticHostsSettings.Hosts {
+			address := config.HostPort{Host: host, Port: settings.Port}
+			if tokens := strings.SplitN(host, ":", 2); len(tokens) == 2 {
+				address.Host = tokens[0]
+				if address.Port, err = strconv.Atoi(tokens[1]); err != nil {
+					return hosts, fmt.Errorf("Invalid port: %s", tokens[1])
+				}
+			}
+			addresses = append(addresses, address)
+		}
+	} else {
+		return hosts, fmt.Errorf("Could not find any hosts definition for cluster %s", clusterName)
+	}
+	for _, address := range addresses {
+		if address.Host == "" || address.Host == "_" || address.Port <= 0 {
+			log.Debugf("read invalid host: [%+v] for cluster %+v", address, clusterName)
+			continue
+		}
+		hosts = append(hosts, address)
+	}
+	return hosts, nil
+}
+
+// ActiveProbes keeps the latest probes of each cluster of a store, so that a driver can release the resources,
+// e.g. connections, of hosts no cluster probes any longer
+type ActiveProbes struct {
+	clustersProbes map[string]([]Prober)
+	mutex          sync.Mutex
+}
+
+func NewActiveProbes() *ActiveProbes {
+	return &ActiveProbes{
+		clustersProbes: make(map[string]([]Prober)),
+	}
+}
+
+// Update records a cluster's newly discovered probes, and returns the probes of all clusters
+func (active *ActiveProbes) Update(clusterProbes *ClusterProbes) (probes []Prober) {
+	active.mutex.Lock()
+	defer active.mutex.Unlock()
+
+	active.clustersProbes[clusterProbes.ClusterName] = clusterProbes.Probes
+	for _, clusterProbes := range active.clustersProbes {
+		probes = append(probes, clusterProbes...)
+	}
+	return probes
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/github/freno/pkg/config"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestReadHosts(t *testing.T) {
+	{
+		hosts, err := ReadHosts("c0", &HostsSettings{
+			HAProxySettings:     &config.HAProxyConfigurationSettings{},
+			StaticHostsSettings: &config.StaticHostsConfigurationSettings{Hosts: []string{"db1", "db2:5433", "_", ":5432"}},
+			Port:                5432,
+		})
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(hosts), 2)
+		test.S(t).ExpectEquals(hosts[0], config.HostPort{Host: "db1", Port: 5432})
+		test.S(t).ExpectEquals(hosts[1], config.HostPort{Host: "db2", Port: 5433})
+	}
+	{
+		_, err := ReadHosts("c0", &HostsSettings{
+			HAProxySettings:     &config.HAProxyConfigurationSettings{},
+			StaticHostsSettings: &config.StaticHostsConfigurationSettings{Hosts: []string{"db1:port"}},
+			Port:                5432,
+		})
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		_, err := ReadHosts("c0", &HostsSettings{
+			HAProxySettings:     &config.HAProxyConfigurationSettings{},
+			StaticHostsSettings: &config.StaticHostsConfigurationSettings{},
+			Port:                5432,
+		})
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestActiveProbes(t *testing.T) {
+	active := NewActiveProbes()
+	probeKeys := func(probes []Prober) map[string]bool {
+		keys := make(map[string]bool)
+		for _, probe := range probes {
+			keys[probe.ProbeKey()] = true
+		}
+		return keys
+	}
+	probes := active.Update(&ClusterProbes{ClusterName: "c0", Probes: []Prober{&fakeProber{key: "a"}, &fakeProber{key: "b"}}})
+	test.S(t).ExpectEquals(len(probeKeys(probes)), 2)
+
+	probes = active.Update(&ClusterProbes{ClusterName: "c1", Probes: []Prober{&fakeProber{key: "c"}}})
+	test.S(t).ExpectEquals(len(probeKeys(probes)), 3)
+
+	// a cluster's probes replace its previous ones
+	probes = active.Update(&ClusterProbes{ClusterName: "c0", Probes: []Prober{&fakeProber{key: "b"}}})
+	keys := probeKeys(probes)
+	test.S(t).ExpectEquals(len(keys), 2)
+	test.S(t).ExpectFalse(keys["a"])
+	test.S(t).ExpectTrue(keys["b"])
+	test.S(t).ExpectTrue(keys["c"])
+}
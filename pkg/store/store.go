@@ -0,0 +1,184 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+// Package store defines store drivers: the store types freno throttles on, such as "mysql". A driver reads its
+// own config section, which a new store registers via config.RegisterStoreSettings, discovers its clusters' hosts
+// and probes them. The throttler collects and aggregates metrics of all registered drivers alike.
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/github/freno/pkg/base"
+)
+
+// Prober reads the metric of a single host of a cluster
+type Prober interface {
+	// ProbeKey uniquely identifies the probed host within its cluster, e.g. "host:port" or a URL. Aggregated
+	// metrics indicate their worst host by this key.
+	ProbeKey() string
+	// ProbeHostname is the name of the probed host, by which it may be skipped. Empty if the host cannot be skipped.
+	ProbeHostname() string
+	// ReadMetric reads the host's current metric. It may return a cached result.
+	ReadMetric(clusterName string) base.MetricResult
+}
+
+// Discoverer lists the hosts of a cluster
+type Discoverer interface {
+	// Discover returns a prober per host of the cluster, e.g. as read from HAProxy or from static settings
+	Discover() ([]Prober, error)
+}
+
+// DiscovererFunc is an adapter to allow the use of ordinary functions as discoverers
+type DiscovererFunc func() ([]Prober, error)
+
+func (f DiscovererFunc) Discover() ([]Prober, error) {
+	return f()
+}
+
+// Cluster is a configured cluster of a store, along with settings common to all store types
+type Cluster struct {
+	Name                 string
+	ThrottleThreshold    float64
+	IgnoreHostsCount     int      // Number of hosts that can be skipped/ignored even on error or on exceeding theesholds
+	IgnoreHostsThreshold float64  // Threshold beyond which IgnoreHostsCount applies
+	IgnoreHosts          []string // Substrings of probe keys to indicate hosts to be ignored/skipped
+	Discoverer           Discoverer
+}
+
+// ClusterProbes are the probes of a cluster's discovered hosts
+type ClusterProbes struct {
+	ClusterName          string
+	IgnoreHostsCount     int
+	IgnoreHostsThreshold float64
+	Probes               []Prober
+}
+
+// Driver is a store type
+type Driver interface {
+	// StoreType is the store type's name, as in /check/<app>/<store type>/<store name>
+	StoreType() string
+	// Clusters returns the store's configured clusters, as read from the driver's config section
+	Clusters() []*Cluster
+}
+
+// MetricFilter is optionally implemented by drivers to adjust hosts' metrics ahead of aggregation, e.g. to exclude
+// hosts failing health checks. metric is nil when the host has not been probed yet. It returns false to exclude
+// the host from aggregation.
+type MetricFilter interface {
+	FilterMetric(clusterName string, probe Prober, metric base.MetricResult) (base.MetricResult, bool)
+}
+
+// ProbesUpdater is optionally implemented by drivers to be notified of a cluster's newly discovered probes,
+// e.g. so as to close connections to hosts no longer probed. It is called synchronously by the throttler, and
+// must not block.
+type ProbesUpdater interface {
+	UpdateClusterProbes(clusterProbes *ClusterProbes)
+}
+
+// Operator is optionally implemented by drivers running periodic work of their own, e.g. health checks or
+// heartbeat writes. Operate runs for the lifetime of the process, and is expected to only act while isLeader().
+type Operator interface {
+	Operate(isLeader func() bool)
+}
+
+var drivers = make(map[string]Driver)
+var driversMutex sync.Mutex
+
+// Register makes a store driver available by its store type. It panics if called twice with the same store
+// type. Drivers register themselves in their package's init().
+func Register(driver Driver) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+
+	storeType := driver.StoreType()
+	if _, found := drivers[storeType]; found {
+		panic(fmt.Sprintf("store: Register called twice for store type %s", storeType))
+	}
+	drivers[storeType] = driver
+}
+
+// Drivers returns the registered store drivers, sorted by store type
+func Drivers() (result []Driver) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+
+	for _, driver := range drivers {
+		result = append(result, driver)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StoreType() < result[j].StoreType()
+	})
+	return result
+}
+
+// GetDriver returns the registered driver of given store type
+func GetDriver(storeType string) (driver Driver, found bool) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+
+	driver, found = drivers[storeType]
+	return driver, found
+}
+
+// RefreshRequest asks for an early rediscovery of the hosts of a cluster. Empty fields stand for all store types
+// or all clusters.
+type RefreshRequest struct {
+	StoreType   string
+	ClusterName string
+}
+
+// Matches returns true when the request covers given cluster
+func (request RefreshRequest) Matches(storeType string, clusterName string) bool {
+	if request.StoreType != "" && request.StoreType != storeType {
+		return false
+	}
+	return request.ClusterName == "" || request.ClusterName == clusterName
+}
+
+var refreshSignal = make(chan bool, 1)
+var pendingRefreshRequests = make(map[RefreshRequest]bool)
+var pendingRefreshRequestsMutex sync.Mutex
+
+func requestRefresh(request RefreshRequest) {
+	pendingRefreshRequestsMutex.Lock()
+	pendingRefreshRequests[request] = true
+	pendingRefreshRequestsMutex.Unlock()
+
+	select {
+	case refreshSignal <- true:
+	default:
+	}
+}
+
+// RequestRefresh asks for an early rediscovery of all clusters' hosts, e.g. when a hosts source reports a change.
+// Requests made while one is pending are merged.
+func RequestRefresh() {
+	requestRefresh(RefreshRequest{})
+}
+
+// RequestClusterRefresh asks for an early rediscovery of a single cluster's hosts
+func RequestClusterRefresh(storeType string, clusterName string) {
+	requestRefresh(RefreshRequest{StoreType: storeType, ClusterName: clusterName})
+}
+
+// RefreshRequests signals pending refresh requests, to be read via PendingRefreshRequests
+func RefreshRequests() <-chan bool {
+	return refreshSignal
+}
+
+// PendingRefreshRequests returns and clears the pending refresh requests
+func PendingRefreshRequests() (requests []RefreshRequest) {
+	pendingRefreshRequestsMutex.Lock()
+	defer pendingRefreshRequestsMutex.Unlock()
+
+	for request := range pendingRefreshRequests {
+		requests = append(requests, request)
+	}
+	pendingRefreshRequests = make(map[RefreshRequest]bool)
+	return requests
+}
@@ -0,0 +1,111 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/github/freno/pkg/base"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// fakeProber is a host whose metric is set by the test
+type fakeProber struct {
+	key    string
+	metric base.MetricResult
+}
+
+func (probe *fakeProber) ProbeKey() string {
+	return probe.key
+}
+
+func (probe *fakeProber) ProbeHostname() string {
+	return probe.key
+}
+
+func (probe *fakeProber) ReadMetric(clusterName string) base.MetricResult {
+	return probe.metric
+}
+
+type fakeDriver struct {
+	storeType string
+}
+
+func (driver *fakeDriver) StoreType() string {
+	return driver.storeType
+}
+
+func (driver *fakeDriver) Clusters() []*Cluster {
+	return []*Cluster{
+		{
+			Name: "c0",
+			Discoverer: DiscovererFunc(func() ([]Prober, error) {
+				return []Prober{&fakeProber{key: "h0"}}, nil
+			}),
+		},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(&fakeDriver{storeType: "fake-b"})
+	Register(&fakeDriver{storeType: "fake-a"})
+	{
+		driver, found := GetDriver("fake-a")
+		test.S(t).ExpectTrue(found)
+		test.S(t).ExpectEquals(driver.StoreType(), "fake-a")
+
+		probes, err := driver.Clusters()[0].Discoverer.Discover()
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(len(probes), 1)
+	}
+	{
+		_, found := GetDriver("no-such-store")
+		test.S(t).ExpectFalse(found)
+	}
+	{
+		drivers := Drivers()
+		test.S(t).ExpectEquals(len(drivers), 2)
+		test.S(t).ExpectEquals(drivers[0].StoreType(), "fake-a")
+		test.S(t).ExpectEquals(drivers[1].StoreType(), "fake-b")
+	}
+	{
+		err := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%+v", r)
+				}
+			}()
+			Register(&fakeDriver{storeType: "fake-a"})
+			return nil
+		}()
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestRequestRefresh(t *testing.T) {
+	RequestClusterRefresh("mysql", "c0")
+	RequestClusterRefresh("mysql", "c0")
+	RequestRefresh()
+	<-RefreshRequests()
+	select {
+	case <-RefreshRequests():
+		t.Errorf("expected refresh requests to be merged")
+	default:
+	}
+	requests := PendingRefreshRequests()
+	test.S(t).ExpectEquals(len(requests), 2)
+	test.S(t).ExpectEquals(len(PendingRefreshRequests()), 0)
+}
+
+func TestRefreshRequestMatches(t *testing.T) {
+	test.S(t).ExpectTrue(RefreshRequest{}.Matches("mysql", "c0"))
+	test.S(t).ExpectTrue(RefreshRequest{StoreType: "mysql"}.Matches("mysql", "c0"))
+	test.S(t).ExpectTrue(RefreshRequest{StoreType: "mysql", ClusterName: "c0"}.Matches("mysql", "c0"))
+	test.S(t).ExpectFalse(RefreshRequest{StoreType: "mysql", ClusterName: "c0"}.Matches("mysql", "c1"))
+	test.S(t).ExpectFalse(RefreshRequest{StoreType: "redis", ClusterName: "c0"}.Matches("mysql", "c0"))
+}
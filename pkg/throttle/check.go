@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/mysql"
 	metrics "github.com/rcrowley/go-metrics"
 )
 
@@ -20,6 +21,7 @@ type CheckFlags struct {
 	OverrideThreshold float64
 	LowPriority       bool
 	OKIfNotExists     bool
+	Explain           bool
 }
 
 var StandardCheckFlags = &CheckFlags{}
@@ -40,38 +42,61 @@ func (check *ThrottlerCheck) checkAppMetricResult(appName string, storeType stri
 	// Handle deprioritized app logic
 	denyApp := false
 	metricName := fmt.Sprintf("%s/%s", storeType, storeName)
+	var explanation *CheckExplanation
+	if flags.Explain {
+		explanation = NewCheckExplanation(metricName)
+	}
 	if flags.LowPriority {
 		if _, exists := check.throttler.nonLowPriorityAppRequestsThrottled.Get(metricName); exists {
 			// a non-deprioritized app, ie a "normal" app, has recently been throttled.
 			// This is now a deprioritized app. Deny access to this request.
 			denyApp = true
+			if explanation != nil {
+				explanation.LowPriorityDenied = true
+			}
 		}
 	}
 	//
-	metricResult, threshold := check.throttler.AppRequestMetricResult(appName, storeName, metricResultFunc, denyApp)
+	metricResult, threshold := check.throttler.explainedAppRequestMetricResult(appName, storeName, metricResultFunc, denyApp, explanation)
 	if flags.OverrideThreshold > 0 {
 		threshold = flags.OverrideThreshold
 	}
 	value, err := metricResult.Get()
+	if explanation != nil {
+		explanation.Threshold = threshold
+		explanation.ThresholdOverridden = (flags.OverrideThreshold > 0)
+		if hostMetricResult, ok := metricResult.(base.HostMetricResult); ok {
+			explanation.WorstHost = hostMetricResult.GetHost()
+		}
+	}
 	if appName == "" {
-		return NewCheckResult(http.StatusExpectationFailed, value, threshold, fmt.Errorf("no app indicated"))
+		return NewCheckResult(http.StatusExpectationFailed, value, threshold, fmt.Errorf("no app indicated")).withExplanation(explanation, "no app indicated")
 	}
 
 	statusCode := http.StatusInternalServerError // 500
+	reason := ""
 
 	if err == base.AppDeniedError {
 		// app specifically not allowed to get metrics
 		statusCode = http.StatusExpectationFailed // 417
+		if denyApp {
+			reason = "low priority app denied: a normal priority app was recently throttled on this metric"
+		} else {
+			reason = "app is throttled"
+		}
 	} else if err == base.NoSuchMetricError {
 		// not collected yet, or metric does not exist
 		statusCode = http.StatusNotFound // 404
+		reason = "no such metric"
 	} else if err != nil {
 		// any error
 		statusCode = http.StatusInternalServerError // 500
+		reason = fmt.Sprintf("metric error: %s", err.Error())
 	} else if value > threshold {
 		// casual throttling
 		statusCode = http.StatusTooManyRequests // 429
 		err = base.ThresholdExceededError
+		reason = fmt.Sprintf("value %f exceeds threshold %f", value, threshold)
 
 		if !flags.LowPriority && !flags.ReadCheck && appName != frenoAppName {
 			// low priority requests will henceforth be denied
@@ -87,25 +112,34 @@ func (check *ThrottlerCheck) checkAppMetricResult(appName string, storeType stri
 
 		statusCode = http.StatusTooManyRequests // 429
 		err = base.ThresholdExceededError
+		if explanation != nil {
+			explanation.ShareDomainUnhealthy = true
+			if metricHealth := check.throttler.getShareDomainMetricHealth(metricName); metricHealth != nil {
+				explanation.ShareDomainSource = metricHealth.SourceDomain
+				explanation.ShareDomainSecondsSinceHealthy = metricHealth.SecondsSinceLastHealthy
+			}
+			reason = fmt.Sprintf("metric is unhealthy in shared domain %s", explanation.ShareDomainSource)
+		}
 	} else {
 		// all good!
 		statusCode = http.StatusOK // 200
+		reason = "ok"
 	}
-	return NewCheckResult(statusCode, value, threshold, err)
+	return NewCheckResult(statusCode, value, threshold, err).withExplanation(explanation, reason)
 }
 
 // CheckAppStoreMetric
 func (check *ThrottlerCheck) Check(appName string, storeType string, storeName string, remoteAddr string, flags *CheckFlags) (checkResult *CheckResult) {
 	var metricResultFunc base.MetricResultFunc
-	switch storeType {
-	case "mysql":
-		{
-			metricResultFunc = func() (metricResult base.MetricResult, threshold float64) {
-				return check.throttler.getMySQLClusterMetrics(storeName)
-			}
+	if check.throttler.isKnownStoreType(storeType) {
+		metricResultFunc = func() (metricResult base.MetricResult, threshold float64) {
+			return check.throttler.getStoreClusterMetrics(storeType, storeName)
 		}
 	}
 	if metricResultFunc == nil {
+		if flags.Explain {
+			return NewErrorCheckResult(http.StatusNotFound, base.NoSuchMetricError).withExplanation(NewCheckExplanation(fmt.Sprintf("%s/%s", storeType, storeName)), "unknown store type")
+		}
 		return NoSuchMetricCheckResult
 	}
 
@@ -190,6 +224,11 @@ func (check *ThrottlerCheck) MetricsHealth() map[string](*base.MetricHealth) {
 	return check.throttler.metricsHealthSnapshot()
 }
 
+// ConnectionPoolsStats is a convenience access method into the stats of connection pools to probed MySQL servers
+func (check *ThrottlerCheck) ConnectionPoolsStats() map[string]mysql.ConnectionPoolStats {
+	return mysql.ConnectionPoolsStats()
+}
+
 func (check *ThrottlerCheck) SelfChecks() {
 	selfCheckTick := time.Tick(selfCheckInterval)
 	go func() {
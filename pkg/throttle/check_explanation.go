@@ -0,0 +1,37 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package throttle
+
+// AppThrottleDecision describes the evaluation of a single throttled-apps entry during a check
+type AppThrottleDecision struct {
+	Key       string  // the throttled-apps entry, either "<app>" or "<app>/<store>"
+	Scope     string  // "global" or "store"
+	Ratio     float64 // the entry's throttle ratio
+	Draw      float64 // the random draw compared against the ratio
+	Throttled bool    // true when Draw < Ratio, i.e. the entry denied the request
+}
+
+// CheckExplanation is a trace of the decisions made while evaluating a check. It is only
+// computed on demand, e.g. via `?explain=true`, and is returned as part of the CheckResult.
+type CheckExplanation struct {
+	MetricName                     string
+	AppThrottles                   []*AppThrottleDecision `json:",omitempty"`
+	LowPriorityDenied              bool                   // request was low priority and a normal priority app was recently throttled on this metric
+	WorstHost                      string                 `json:",omitempty"` // the host which produced the aggregated (worst) value or error
+	Threshold                      float64                // the threshold which applied to this check
+	ThresholdOverridden            bool                   // true when Threshold was given by the request, e.g. via /check-read
+	ShareDomainUnhealthy           bool
+	ShareDomainSource              string `json:",omitempty"` // the domain which reported the metric as unhealthy
+	ShareDomainSecondsSinceHealthy int64  `json:",omitempty"`
+	Reason                         string
+}
+
+func NewCheckExplanation(metricName string) *CheckExplanation {
+	return &CheckExplanation{
+		MetricName:   metricName,
+		AppThrottles: [](*AppThrottleDecision){},
+	}
+}
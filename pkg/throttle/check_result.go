@@ -12,6 +12,8 @@ type CheckResult struct {
 	Threshold  float64 `json:"Threshold"`
 	Error      error   `json:"-"`
 	Message    string  `json:"Message"`
+
+	Explanation *CheckExplanation `json:"Explanation,omitempty"`
 }
 
 func NewCheckResult(statusCode int, value float64, threshold float64, err error) *CheckResult {
@@ -27,6 +29,15 @@ func NewCheckResult(statusCode int, value float64, threshold float64, err error)
 	return result
 }
 
+// withExplanation attaches given explanation, if non-nil, along with the reason for the check's outcome
+func (result *CheckResult) withExplanation(explanation *CheckExplanation, reason string) *CheckResult {
+	if explanation != nil {
+		explanation.Reason = reason
+		result.Explanation = explanation
+	}
+	return result
+}
+
 func NewErrorCheckResult(statusCode int, err error) *CheckResult {
 	return NewCheckResult(statusCode, 0, 0, err)
 }
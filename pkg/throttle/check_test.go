@@ -0,0 +1,97 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package throttle
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func TestCheckExplainThresholdExceeded(t *testing.T) {
+	check := NewThrottlerCheck(NewThrottler())
+	metricResultFunc := func() (base.MetricResult, float64) {
+		return base.NewHostMetricResult(2.5, "10.0.0.2:3306"), 1.0
+	}
+	{
+		checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{ReadCheck: true})
+		test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusTooManyRequests)
+		test.S(t).ExpectTrue(checkResult.Explanation == nil)
+	}
+	{
+		checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{ReadCheck: true, Explain: true})
+		test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusTooManyRequests)
+		test.S(t).ExpectNotNil(checkResult.Explanation)
+		test.S(t).ExpectEquals(checkResult.Explanation.MetricName, "mysql/c0")
+		test.S(t).ExpectEquals(checkResult.Explanation.WorstHost, "10.0.0.2:3306")
+		test.S(t).ExpectEquals(checkResult.Explanation.Threshold, 1.0)
+		test.S(t).ExpectFalse(checkResult.Explanation.ThresholdOverridden)
+		test.S(t).ExpectEquals(len(checkResult.Explanation.AppThrottles), 0)
+	}
+	{
+		checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{ReadCheck: true, OverrideThreshold: 3.0, Explain: true})
+		test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusOK)
+		test.S(t).ExpectEquals(checkResult.Explanation.Threshold, 3.0)
+		test.S(t).ExpectTrue(checkResult.Explanation.ThresholdOverridden)
+	}
+}
+
+func TestCheckExplainHostError(t *testing.T) {
+	check := NewThrottlerCheck(NewThrottler())
+	metricResultFunc := func() (base.MetricResult, float64) {
+		return base.NewHostErrorMetricResult(fmt.Errorf("connection refused"), "10.0.0.3:3306"), 1.0
+	}
+	checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{Explain: true})
+	test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusInternalServerError)
+	test.S(t).ExpectEquals(checkResult.Explanation.WorstHost, "10.0.0.3:3306")
+}
+
+func TestCheckExplainAppThrottled(t *testing.T) {
+	throttler := NewThrottler()
+	check := NewThrottlerCheck(throttler)
+	metricResultFunc := func() (base.MetricResult, float64) {
+		return base.NewSimpleMetricResult(0.5), 1.0
+	}
+	throttler.ThrottleApp("test-app/c0", time.Now().Add(time.Hour), 1)
+	{
+		checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{Explain: true})
+		test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusExpectationFailed)
+		test.S(t).ExpectEquals(len(checkResult.Explanation.AppThrottles), 1)
+		decision := checkResult.Explanation.AppThrottles[0]
+		test.S(t).ExpectEquals(decision.Key, "test-app/c0")
+		test.S(t).ExpectEquals(decision.Scope, "store")
+		test.S(t).ExpectTrue(decision.Throttled)
+	}
+	throttler.UnthrottleApp("test-app/c0")
+	throttler.ThrottleApp("test-app", time.Now().Add(time.Hour), 0)
+	{
+		checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{Explain: true})
+		test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusOK)
+		test.S(t).ExpectEquals(len(checkResult.Explanation.AppThrottles), 1)
+		decision := checkResult.Explanation.AppThrottles[0]
+		test.S(t).ExpectEquals(decision.Key, "test-app")
+		test.S(t).ExpectEquals(decision.Scope, "global")
+		test.S(t).ExpectFalse(decision.Throttled)
+	}
+}
+
+func TestCheckExplainLowPriority(t *testing.T) {
+	throttler := NewThrottler()
+	check := NewThrottlerCheck(throttler)
+	metricResultFunc := func() (base.MetricResult, float64) {
+		return base.NewSimpleMetricResult(0.5), 1.0
+	}
+	throttler.nonLowPriorityAppRequestsThrottled.SetDefault("mysql/c0", true)
+
+	checkResult := check.checkAppMetricResult("test-app", "mysql", "c0", metricResultFunc, &CheckFlags{LowPriority: true, Explain: true})
+	test.S(t).ExpectEquals(checkResult.StatusCode, http.StatusExpectationFailed)
+	test.S(t).ExpectTrue(checkResult.Explanation.LowPriorityDenied)
+}
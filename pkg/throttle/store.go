@@ -0,0 +1,237 @@
+package throttle
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/store"
+
+	// Store drivers register themselves on import
+	_ "github.com/github/freno/pkg/httpstore"
+	_ "github.com/github/freno/pkg/mysql"
+	_ "github.com/github/freno/pkg/postgres"
+	_ "github.com/github/freno/pkg/prometheus"
+	_ "github.com/github/freno/pkg/redis"
+
+	"github.com/outbrain/golib/log"
+	"github.com/patrickmn/go-cache"
+)
+
+// storeInventory is the state of a single store type. It is owned by the Operate goroutine; except for
+// clusterThresholds, which is read by checks.
+type storeInventory struct {
+	driver            store.Driver
+	clustersProbes    map[string](*clusterProbes)
+	probeMetrics      map[string]store.ProbeMetrics // cluster name -> probe key -> latest metric
+	clusterThresholds *cache.Cache
+}
+
+// clusterProbes are a cluster's probes, along with a guard per probe against concurrent reads
+type clusterProbes struct {
+	*store.ClusterProbes
+	queriesInProgress []int64
+}
+
+// storeProbeMetric is a host's metric, as read by collectStoreMetrics()
+type storeProbeMetric struct {
+	storeType   string
+	clusterName string
+	probeKey    string
+	metric      base.MetricResult
+}
+
+// storeClusterProbes are a cluster's probes, as discovered by refreshStoreInventories()
+type storeClusterProbes struct {
+	storeType     string
+	clusterProbes *store.ClusterProbes
+}
+
+func newStoreInventories(drivers []store.Driver) map[string](*storeInventory) {
+	stores := make(map[string](*storeInventory))
+	for _, driver := range drivers {
+		stores[driver.StoreType()] = &storeInventory{
+			driver:            driver,
+			clustersProbes:    make(map[string](*clusterProbes)),
+			probeMetrics:      make(map[string]store.ProbeMetrics),
+			clusterThresholds: cache.New(cache.NoExpiration, 0),
+		}
+	}
+	return stores
+}
+
+// operateStores runs the periodic work of drivers which have any
+func (throttler *Throttler) operateStores() {
+	for _, inventory := range throttler.stores {
+		if operator, ok := inventory.driver.(store.Operator); ok {
+			go operator.Operate(func() bool { return throttler.isLeader })
+		}
+	}
+}
+
+func (throttler *Throttler) collectStoreMetrics() error {
+	if !throttler.isLeader {
+		return nil
+	}
+	// synchronously, get lists of probes
+	for storeType, inventory := range throttler.stores {
+		storeType := storeType
+		for clusterName, probes := range inventory.clustersProbes {
+			clusterName := clusterName
+			probes := probes
+			go func() {
+				// probes is known not to change. It can be *replaced*, but not changed.
+				// so it's safe to iterate it
+				for i, probe := range probes.Probes {
+					queryInProgress := &probes.queriesInProgress[i]
+					probe := probe
+					go func() {
+						// Avoid querying the same server twice at the same time. If previous read is still there,
+						// we avoid re-reading it.
+						if !atomic.CompareAndSwapInt64(queryInProgress, 0, 1) {
+							return
+						}
+						defer atomic.StoreInt64(queryInProgress, 0)
+						throttler.storeProbeMetricChan <- &storeProbeMetric{
+							storeType:   storeType,
+							clusterName: clusterName,
+							probeKey:    probe.ProbeKey(),
+							metric:      probe.ReadMetric(clusterName),
+						}
+					}()
+				}
+			}()
+		}
+	}
+	return nil
+}
+
+// isIgnoredProbe returns true when a probe's host is configured to be ignored, or has been skipped
+func (throttler *Throttler) isIgnoredProbe(cluster *store.Cluster, probe store.Prober) bool {
+	for _, ignore := range cluster.IgnoreHosts {
+		if strings.Contains(probe.ProbeKey(), ignore) {
+			log.Debugf("probe ignored: %+v", probe.ProbeKey())
+			return true
+		}
+	}
+	if hostname := probe.ProbeHostname(); hostname != "" {
+		if _, skipped := throttler.skippedHosts.Get(hostname); skipped {
+			log.Debugf("host skipped: %+v", hostname)
+			return true
+		}
+	}
+	return false
+}
+
+// refreshStoreInventories will re-structure the inventories of all stores based on reading config settings,
+// and potentially re-querying dynamic data such as HAProxy list of hosts
+func (throttler *Throttler) refreshStoreInventories() error {
+	return throttler.refreshRequestedStoreInventories([]store.RefreshRequest{{}})
+}
+
+// refreshRequestedStoreInventories re-structures the inventories of the clusters covered by given requests
+func (throttler *Throttler) refreshRequestedStoreInventories(requests []store.RefreshRequest) error {
+	if !throttler.isLeader {
+		return nil
+	}
+	isRequested := func(storeType string, clusterName string) bool {
+		for _, request := range requests {
+			if request.Matches(storeType, clusterName) {
+				return true
+			}
+		}
+		return false
+	}
+	for storeType, inventory := range throttler.stores {
+		log.Debugf("refreshing %s inventory", storeType)
+		for _, cluster := range inventory.driver.Clusters() {
+			if !isRequested(storeType, cluster.Name) {
+				continue
+			}
+			storeType := storeType
+			cluster := cluster
+			inventory.clusterThresholds.Set(cluster.Name, cluster.ThrottleThreshold, cache.DefaultExpiration)
+			go func() error {
+				probes, err := cluster.Discoverer.Discover()
+				if err != nil {
+					return log.Errorf("Unable to discover hosts of %s cluster %s: %+v", storeType, cluster.Name, err)
+				}
+				clusterProbes := &store.ClusterProbes{
+					ClusterName:          cluster.Name,
+					IgnoreHostsCount:     cluster.IgnoreHostsCount,
+					IgnoreHostsThreshold: cluster.IgnoreHostsThreshold,
+				}
+				for _, probe := range probes {
+					if !throttler.isIgnoredProbe(cluster, probe) {
+						clusterProbes.Probes = append(clusterProbes.Probes, probe)
+					}
+				}
+				throttler.storeClusterProbesChan <- &storeClusterProbes{storeType: storeType, clusterProbes: clusterProbes}
+				return nil
+			}()
+		}
+	}
+	return nil
+}
+
+// synchronous update of inventory
+func (throttler *Throttler) updateStoreClusterProbes(discovered *storeClusterProbes) error {
+	log.Debugf("updating %s ClusterProbes: %s", discovered.storeType, discovered.clusterProbes.ClusterName)
+	inventory := throttler.stores[discovered.storeType]
+	inventory.clustersProbes[discovered.clusterProbes.ClusterName] = &clusterProbes{
+		ClusterProbes:     discovered.clusterProbes,
+		queriesInProgress: make([]int64, len(discovered.clusterProbes.Probes)),
+	}
+	if updater, ok := inventory.driver.(store.ProbesUpdater); ok {
+		updater.UpdateClusterProbes(discovered.clusterProbes)
+	}
+	return nil
+}
+
+// synchronous update of a host's metric
+func (throttler *Throttler) updateStoreProbeMetric(probeMetric *storeProbeMetric) error {
+	inventory := throttler.stores[probeMetric.storeType]
+	probeMetrics, ok := inventory.probeMetrics[probeMetric.clusterName]
+	if !ok {
+		probeMetrics = make(store.ProbeMetrics)
+		inventory.probeMetrics[probeMetric.clusterName] = probeMetrics
+	}
+	probeMetrics[probeMetric.probeKey] = probeMetric.metric
+	return nil
+}
+
+// synchronous aggregation of collected data
+func (throttler *Throttler) aggregateStoreMetrics() error {
+	if !throttler.isLeader {
+		return nil
+	}
+	for storeType, inventory := range throttler.stores {
+		filter, _ := inventory.driver.(store.MetricFilter)
+		for clusterName, probes := range inventory.clustersProbes {
+			metricName := fmt.Sprintf("%s/%s", storeType, clusterName)
+			aggregatedMetric := store.AggregateProbes(probes.ClusterProbes, inventory.probeMetrics[clusterName], filter)
+			throttler.setAggregatedMetric(metricName, aggregatedMetric)
+		}
+	}
+	return nil
+}
+
+func (throttler *Throttler) isKnownStoreType(storeType string) bool {
+	_, found := throttler.stores[storeType]
+	return found
+}
+
+func (throttler *Throttler) getStoreClusterMetrics(storeType string, clusterName string) (base.MetricResult, float64) {
+	inventory, found := throttler.stores[storeType]
+	if !found {
+		return base.NoSuchMetric, 0
+	}
+	if thresholdVal, found := inventory.clusterThresholds.Get(clusterName); found {
+		threshold, _ := thresholdVal.(float64)
+		metricName := fmt.Sprintf("%s/%s", storeType, clusterName)
+		return throttler.getNamedMetric(metricName), threshold
+	}
+
+	return base.NoSuchMetric, 0
+}
@@ -0,0 +1,155 @@
+/*
+   Copyright 2017 GitHub Inc.
+	 See https://github.com/github/freno/blob/master/LICENSE
+*/
+
+package throttle
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/github/freno/pkg/base"
+	"github.com/github/freno/pkg/store"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+type testProber struct {
+	hostname string
+	value    float64
+}
+
+func (probe *testProber) ProbeKey() string {
+	return fmt.Sprintf("%s:1234", probe.hostname)
+}
+
+func (probe *testProber) ProbeHostname() string {
+	return probe.hostname
+}
+
+func (probe *testProber) ReadMetric(clusterName string) base.MetricResult {
+	return base.NewHostMetricResult(probe.value, probe.ProbeKey())
+}
+
+type testDriver struct {
+	probes  []store.Prober
+	updated []*store.ClusterProbes
+}
+
+func (driver *testDriver) StoreType() string {
+	return "test"
+}
+
+func (driver *testDriver) Clusters() []*store.Cluster {
+	return []*store.Cluster{
+		{
+			Name:              "c0",
+			ThrottleThreshold: 2.0,
+			IgnoreHosts:       []string{"ignored"},
+			Discoverer: store.DiscovererFunc(func() ([]store.Prober, error) {
+				return driver.probes, nil
+			}),
+		},
+	}
+}
+
+func (driver *testDriver) UpdateClusterProbes(clusterProbes *store.ClusterProbes) {
+	driver.updated = append(driver.updated, clusterProbes)
+}
+
+func newTestStoreThrottler(driver store.Driver) *Throttler {
+	throttler := NewThrottler()
+	throttler.isLeader = true
+	throttler.stores = newStoreInventories([]store.Driver{driver})
+	return throttler
+}
+
+func waitForStoreClusterMetric(throttler *Throttler, storeType string, clusterName string) (base.MetricResult, float64) {
+	for i := 0; i < 100; i++ {
+		metricResult, threshold := throttler.getStoreClusterMetrics(storeType, clusterName)
+		if metricResult != base.NoSuchMetric {
+			return metricResult, threshold
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return throttler.getStoreClusterMetrics(storeType, clusterName)
+}
+
+func TestStoreInventoryRoundTrip(t *testing.T) {
+	driver := &testDriver{
+		probes: []store.Prober{
+			&testProber{hostname: "host1", value: 0.5},
+			&testProber{hostname: "host2", value: 1.5},
+			&testProber{hostname: "ignored-host3", value: 7.0},
+			&testProber{hostname: "host4", value: 9.0},
+		},
+	}
+	throttler := newTestStoreThrottler(driver)
+	throttler.SkipHost("host4", time.Now().Add(time.Hour))
+
+	test.S(t).ExpectTrue(throttler.isKnownStoreType("test"))
+	test.S(t).ExpectFalse(throttler.isKnownStoreType("mysql"))
+
+	throttler.refreshStoreInventories()
+	discovered := <-throttler.storeClusterProbesChan
+	test.S(t).ExpectEquals(discovered.storeType, "test")
+	test.S(t).ExpectEquals(discovered.clusterProbes.ClusterName, "c0")
+	test.S(t).ExpectEquals(len(discovered.clusterProbes.Probes), 2)
+
+	throttler.updateStoreClusterProbes(discovered)
+	test.S(t).ExpectEquals(len(driver.updated), 1)
+
+	throttler.aggregateStoreMetrics()
+	metricResult, threshold := waitForStoreClusterMetric(throttler, "test", "c0")
+	test.S(t).ExpectEquals(metricResult, base.NoMetricResultYet)
+	test.S(t).ExpectEquals(threshold, 2.0)
+
+	throttler.collectStoreMetrics()
+	for range discovered.clusterProbes.Probes {
+		throttler.updateStoreProbeMetric(<-throttler.storeProbeMetricChan)
+	}
+	throttler.aggregateStoreMetrics()
+	for i := 0; i < 100; i++ {
+		if metricResult, _ = throttler.getStoreClusterMetrics("test", "c0"); metricResult != base.NoMetricResultYet {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	value, err := metricResult.Get()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(value, 1.5)
+	test.S(t).ExpectEquals(metricResult.(base.HostMetricResult).GetHost(), "host2:1234")
+}
+
+func TestStoreInventoryUnknownCluster(t *testing.T) {
+	throttler := newTestStoreThrottler(&testDriver{})
+	{
+		metricResult, threshold := throttler.getStoreClusterMetrics("test", "no-such-cluster")
+		test.S(t).ExpectEquals(metricResult, base.NoSuchMetric)
+		test.S(t).ExpectEquals(threshold, 0.0)
+	}
+	{
+		metricResult, _ := throttler.getStoreClusterMetrics("no-such-store", "c0")
+		test.S(t).ExpectEquals(metricResult, base.NoSuchMetric)
+	}
+}
+
+func TestRefreshRequestedStoreInventories(t *testing.T) {
+	throttler := newTestStoreThrottler(&testDriver{probes: []store.Prober{&testProber{hostname: "host1"}}})
+	{
+		throttler.refreshRequestedStoreInventories([]store.RefreshRequest{{StoreType: "test", ClusterName: "c1"}, {StoreType: "mysql"}})
+		select {
+		case discovered := <-throttler.storeClusterProbesChan:
+			t.Errorf("unexpected refresh of %s", discovered.clusterProbes.ClusterName)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	{
+		throttler.refreshRequestedStoreInventories([]store.RefreshRequest{{StoreType: "test", ClusterName: "c0"}})
+		discovered := <-throttler.storeClusterProbesChan
+		test.S(t).ExpectEquals(discovered.clusterProbes.ClusterName, "c0")
+		test.S(t).ExpectEquals(len(discovered.clusterProbes.Probes), 1)
+	}
+}
@@ -8,15 +8,11 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/github/freno/pkg/base"
 	"github.com/github/freno/pkg/config"
-	"github.com/github/freno/pkg/haproxy"
-	"github.com/github/freno/pkg/mysql"
-	"github.com/github/freno/pkg/proxysql"
-	"github.com/github/freno/pkg/vitess"
+	"github.com/github/freno/pkg/store"
 
 	"github.com/outbrain/golib/log"
 	"github.com/patrickmn/go-cache"
@@ -26,10 +22,9 @@ import (
 )
 
 const leaderCheckInterval = 1 * time.Second
-const mysqlCollectInterval = 50 * time.Millisecond
-const mysqlRefreshInterval = 10 * time.Second
-const mysqlAggreateInterval = 25 * time.Millisecond
-const mysqlHttpCheckInterval = 5 * time.Second
+const storeCollectInterval = 50 * time.Millisecond
+const storeRefreshInterval = 10 * time.Second
+const storeAggregateInterval = 25 * time.Millisecond
 const sharedDomainCollectInterval = 1 * time.Second
 
 const aggregatedMetricsExpiration = 5 * time.Second
@@ -53,14 +48,10 @@ type Throttler struct {
 	isLeaderFunc             func() bool
 	sharedDomainServicesFunc func() (map[string]string, error)
 
-	mysqlThrottleMetricChan chan *mysql.MySQLThrottleMetric
-	mysqlHttpCheckChan      chan *mysql.MySQLHttpCheck
-	mysqlInventoryChan      chan *mysql.MySQLInventory
-	mysqlClusterProbesChan  chan *mysql.ClusterProbes
+	storeProbeMetricChan   chan *storeProbeMetric
+	storeClusterProbesChan chan *storeClusterProbes
+	stores                 map[string](*storeInventory)
 
-	mysqlInventory *mysql.MySQLInventory
-
-	mysqlClusterThresholds  *cache.Cache
 	aggregatedMetrics       *cache.Cache
 	throttledApps           *cache.Cache
 	skippedHosts            *cache.Cache
@@ -71,8 +62,6 @@ type Throttler struct {
 	memcacheClient *memcache.Client
 	memcachePath   string
 
-	proxysqlClient *proxysql.Client
-
 	throttledAppsMutex sync.Mutex
 	skippedHostsMutex  sync.Mutex
 
@@ -84,16 +73,12 @@ func NewThrottler() *Throttler {
 	throttler := &Throttler{
 		isLeader: false,
 
-		mysqlThrottleMetricChan: make(chan *mysql.MySQLThrottleMetric),
-		mysqlHttpCheckChan:      make(chan *mysql.MySQLHttpCheck),
-
-		mysqlInventoryChan:     make(chan *mysql.MySQLInventory, 1),
-		mysqlClusterProbesChan: make(chan *mysql.ClusterProbes),
-		mysqlInventory:         mysql.NewMySQLInventory(),
+		storeProbeMetricChan:   make(chan *storeProbeMetric),
+		storeClusterProbesChan: make(chan *storeClusterProbes),
+		stores:                 newStoreInventories(store.Drivers()),
 
 		throttledApps:           cache.New(cache.NoExpiration, 10*time.Second),
 		skippedHosts:            cache.New(cache.NoExpiration, 10*time.Second),
-		mysqlClusterThresholds:  cache.New(cache.NoExpiration, 0),
 		aggregatedMetrics:       cache.New(aggregatedMetricsExpiration, aggregatedMetricsCleanup),
 		recentApps:              cache.New(recentAppsExpiration, time.Minute),
 		metricsHealth:           cache.New(cache.NoExpiration, 0),
@@ -109,10 +94,6 @@ func NewThrottler() *Throttler {
 	}
 	throttler.memcachePath = config.Settings().MemcachePath
 
-	if throttler.hasProxySQLStores() {
-		throttler.proxysqlClient = proxysql.NewClient(mysqlRefreshInterval)
-	}
-
 	return throttler
 }
 
@@ -130,16 +111,16 @@ func (throttler *Throttler) ThrottledAppsSnapshot() map[string]cache.Item {
 
 func (throttler *Throttler) Operate() {
 	leaderCheckTick := time.Tick(leaderCheckInterval)
-	mysqlCollectTick := time.Tick(mysqlCollectInterval)
-	mysqlRefreshTick := time.Tick(mysqlRefreshInterval)
-	mysqlAggregateTick := time.Tick(mysqlAggreateInterval)
-	mysqlHttpCheckTick := time.Tick(mysqlHttpCheckInterval)
+	storeCollectTick := time.Tick(storeCollectInterval)
+	storeRefreshTick := time.Tick(storeRefreshInterval)
+	storeAggregateTick := time.Tick(storeAggregateInterval)
 	throttledAppsTick := time.Tick(throttledAppsSnapshotInterval)
 	sharedDomainTick := time.Tick(sharedDomainCollectInterval)
 	skippedHostsTick := time.Tick(skippedHostsSnapshotInterval)
 
 	// initial read of inventory:
-	go throttler.refreshMySQLInventory()
+	go throttler.refreshStoreInventories()
+	throttler.operateStores()
 
 	for {
 		select {
@@ -148,42 +129,38 @@ func (throttler *Throttler) Operate() {
 				// sparse
 				throttler.isLeader = throttler.isLeaderFunc()
 			}
-		case <-mysqlCollectTick:
+		case <-storeCollectTick:
 			{
 				// frequent
-				throttler.collectMySQLMetrics()
+				throttler.collectStoreMetrics()
 			}
-		case <-mysqlHttpCheckTick:
+		case probeMetric := <-throttler.storeProbeMetricChan:
 			{
-				throttler.collectMySQLHttpChecks()
+				// incoming host metric, frequent, as result of collectStoreMetrics()
+				throttler.updateStoreProbeMetric(probeMetric)
 			}
-		case metric := <-throttler.mysqlThrottleMetricChan:
+		case <-storeRefreshTick:
 			{
-				// incoming MySQL metric, frequent, as result of collectMySQLMetrics()
-				throttler.mysqlInventory.InstanceKeyMetrics[metric.GetClusterInstanceKey()] = metric
+				// sparse
+				go throttler.refreshStoreInventories()
 			}
-		case httpCheckResult := <-throttler.mysqlHttpCheckChan:
+		case <-store.RefreshRequests():
 			{
-				// incoming MySQL metric, frequent, as result of collectMySQLMetrics()
-				throttler.mysqlInventory.ClusterInstanceHttpChecks[httpCheckResult.HashKey()] = httpCheckResult.CheckResult
-			}
-		case <-mysqlRefreshTick:
-			{
-				// sparse
-				go throttler.refreshMySQLInventory()
+				// sparse, as requested by hosts sources reporting a change
+				go throttler.refreshRequestedStoreInventories(store.PendingRefreshRequests())
 			}
 		case <-sharedDomainTick:
 			{
 				go throttler.collectShareDomainMetricHealth()
 			}
-		case probes := <-throttler.mysqlClusterProbesChan:
+		case clusterProbes := <-throttler.storeClusterProbesChan:
 			{
-				// incoming structural update, sparse, as result of refreshMySQLInventory()
-				throttler.updateMySQLClusterProbes(probes)
+				// incoming structural update, sparse, as result of refreshStoreInventories()
+				throttler.updateStoreClusterProbes(clusterProbes)
 			}
-		case <-mysqlAggregateTick:
+		case <-storeAggregateTick:
 			{
-				throttler.aggregateMySQLMetrics()
+				throttler.aggregateStoreMetrics()
 			}
 		case <-throttledAppsTick:
 			{
@@ -201,263 +178,22 @@ func (throttler *Throttler) Operate() {
 	}
 }
 
-func (throttler *Throttler) hasProxySQLStores() bool {
-	for _, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
-		if !clusterSettings.ProxySQLSettings.IsEmpty() {
-			return true
-		}
-	}
-	return false
-}
-
-func (throttler *Throttler) collectMySQLMetrics() error {
-	if !throttler.isLeader {
-		return nil
-	}
-	// synchronously, get lists of probes
-	for clusterName, probes := range throttler.mysqlInventory.ClustersProbes {
-		clusterName := clusterName
-		probes := probes
-		go func() {
-			// probes is known not to change. It can be *replaced*, but not changed.
-			// so it's safe to iterate it
-			for _, probe := range *probes {
-				probe := probe
-				go func() {
-					// Avoid querying the same server twice at the same time. If previous read is still there,
-					// we avoid re-reading it.
-					if !atomic.CompareAndSwapInt64(&probe.QueryInProgress, 0, 1) {
-						return
-					}
-					defer atomic.StoreInt64(&probe.QueryInProgress, 0)
-					throttleMetrics := mysql.ReadThrottleMetric(probe, clusterName)
-					throttler.mysqlThrottleMetricChan <- throttleMetrics
-				}()
-			}
-		}()
-	}
-	return nil
-}
-
-func (throttler *Throttler) collectMySQLHttpChecks() error {
-	if !throttler.isLeader {
-		return nil
-	}
-	// synchronously, get lists of probes
-	for clusterName, probes := range throttler.mysqlInventory.ClustersProbes {
-		clusterName := clusterName
-		probes := probes
+// setAggregatedMetric stores an aggregated metric, and shares it via memcache, if configured
+func (throttler *Throttler) setAggregatedMetric(metricName string, aggregatedMetric base.MetricResult) {
+	go throttler.aggregatedMetrics.Set(metricName, aggregatedMetric, cache.DefaultExpiration)
+	if throttler.memcacheClient != nil {
 		go func() {
-			// probes is known not to change. It can be *replaced*, but not changed.
-			// so it's safe to iterate it
-			for _, probe := range *probes {
-				probe := probe
-				go func() {
-					// Avoid querying the same server twice at the same time. If previous read is still there,
-					// we avoid re-reading it.
-					if !atomic.CompareAndSwapInt64(&probe.HttpCheckInProgress, 0, 1) {
-						return
-					}
-					defer atomic.StoreInt64(&probe.HttpCheckInProgress, 0)
-					httpCheckResult := mysql.CheckHttp(clusterName, probe)
-					throttler.mysqlHttpCheckChan <- httpCheckResult
-				}()
-			}
-		}()
-	}
-	return nil
-}
-
-// refreshMySQLInventory will re-structure the inventory based on reading config settings, and potentially
-// re-querying dynamic data such as HAProxy list of hosts
-func (throttler *Throttler) refreshMySQLInventory() error {
-	if !throttler.isLeader {
-		return nil
-	}
-	log.Debugf("refreshing MySQL inventory")
-
-	addInstanceKey := func(key *mysql.InstanceKey, clusterName string, clusterSettings *config.MySQLClusterConfigurationSettings, probes *mysql.Probes) {
-		for _, ignore := range clusterSettings.IgnoreHosts {
-			if strings.Contains(key.StringCode(), ignore) {
-				log.Debugf("instance key ignored: %+v", key)
-				return
-			}
-		}
-		if _, skipped := throttler.skippedHosts.Get(key.Hostname); skipped {
-			log.Debugf("host skipped: %+v", key.Hostname)
-			return
-		}
-		if !key.IsValid() {
-			log.Debugf("read invalid instance key: [%+v] for cluster %+v", key, clusterName)
-			return
-		}
-		log.Debugf("read instance key: %+v", key)
-
-		probe := &mysql.Probe{
-			Key:           *key,
-			User:          clusterSettings.User,
-			Password:      clusterSettings.Password,
-			MetricQuery:   clusterSettings.MetricQuery,
-			CacheMillis:   clusterSettings.CacheMillis,
-			HttpCheckPath: clusterSettings.HttpCheckPath,
-			HttpCheckPort: clusterSettings.HttpCheckPort,
-		}
-		(*probes)[*key] = probe
-	}
-
-	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
-		clusterName := clusterName
-		clusterSettings := clusterSettings
-		// config may dynamically change, but internal structure (config.Settings().Stores.MySQL.Clusters in our case)
-		// is immutable and can only be _replaced_. Hence, it's safe to read in a goroutine:
-		go func() error {
-			throttler.mysqlClusterThresholds.Set(clusterName, clusterSettings.ThrottleThreshold, cache.DefaultExpiration)
-			if !clusterSettings.HAProxySettings.IsEmpty() {
-				poolName := clusterSettings.HAProxySettings.PoolName
-				totalHosts := []string{}
-				addresses, _ := clusterSettings.HAProxySettings.GetProxyAddresses()
-				for _, u := range addresses {
-					log.Debugf("getting haproxy data from %s", u.String())
-					csv, err := haproxy.Read(u)
-					if err != nil {
-						return log.Errorf("Unable to get HAproxy data from %s: %+v", u.String(), err)
-					}
-					if backendHosts, err := haproxy.ParseCsvHosts(csv, poolName); err == nil {
-						hosts := haproxy.FilterThrotllerHosts(backendHosts)
-						totalHosts = append(totalHosts, hosts...)
-						log.Debugf("Read %+v hosts from haproxy %s/#%s", len(hosts), u.String(), poolName)
-					} else {
-						log.Errorf("Unable to get HAproxy hosts from %s/#%s: %+v", u.String(), poolName, err)
-					}
-				}
-				if len(totalHosts) == 0 {
-					return log.Errorf("Unable to get any HAproxy hosts for pool: %+v", poolName)
-				}
-
-				clusterProbes := &mysql.ClusterProbes{
-					ClusterName:          clusterName,
-					IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
-					IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
-					InstanceProbes:       mysql.NewProbes(),
-				}
-				for _, host := range totalHosts {
-					key := mysql.InstanceKey{Hostname: host, Port: clusterSettings.Port}
-					addInstanceKey(&key, clusterName, clusterSettings, clusterProbes.InstanceProbes)
-				}
-				throttler.mysqlClusterProbesChan <- clusterProbes
-				return nil
-			}
-
-			if !clusterSettings.ProxySQLSettings.IsEmpty() {
-				db, addr, err := throttler.proxysqlClient.GetDB(clusterSettings.ProxySQLSettings)
-				if err != nil {
-					log.Debugf("Unable to connect to ProxySQL: %v", err)
-					return err
-				}
-
-				dsn := clusterSettings.ProxySQLSettings.AddressToDSN(addr)
-				log.Debugf("getting ProxySQL data from %s, hostgroup id: %d (%s)", dsn, clusterSettings.ProxySQLSettings.HostgroupID, clusterName)
-				servers, err := throttler.proxysqlClient.GetServers(db, clusterSettings.ProxySQLSettings)
-				if err != nil {
-					throttler.proxysqlClient.CloseDB(addr)
-					return log.Errorf("Unable to get hosts from ProxySQL %s: %+v", dsn, err)
-				}
-				log.Debugf("Read %+v hosts from ProxySQL %s, hostgroup id: %d (%s)", len(servers), dsn, clusterSettings.ProxySQLSettings.HostgroupID, clusterName)
-				clusterProbes := &mysql.ClusterProbes{
-					ClusterName:          clusterName,
-					IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
-					IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
-					InstanceProbes:       mysql.NewProbes(),
-				}
-				for _, server := range servers {
-					key := mysql.InstanceKey{Hostname: server.Host, Port: int(server.Port)}
-					addInstanceKey(&key, clusterName, clusterSettings, clusterProbes.InstanceProbes)
-				}
-				throttler.mysqlClusterProbesChan <- clusterProbes
-				return nil
-			}
-
-			if !clusterSettings.VitessSettings.IsEmpty() {
-				log.Debugf("getting vitess data from %s", clusterSettings.VitessSettings.API)
-				keyspace := clusterSettings.VitessSettings.Keyspace
-				shard := clusterSettings.VitessSettings.Shard
-				tablets, err := vitess.ParseTablets(clusterSettings.VitessSettings)
-				if err != nil {
-					return log.Errorf("Unable to get vitess hosts from %s, %s/%s: %+v", clusterSettings.VitessSettings.API, keyspace, shard, err)
-				}
-				log.Debugf("Read %+v hosts from vitess %s, %s/%s, cells=%s", len(tablets), clusterSettings.VitessSettings.API,
-					keyspace, shard, strings.Join(vitess.ParseCells(clusterSettings.VitessSettings), ","),
-				)
-				clusterProbes := &mysql.ClusterProbes{
-					ClusterName:          clusterName,
-					IgnoreHostsCount:     clusterSettings.IgnoreHostsCount,
-					IgnoreHostsThreshold: clusterSettings.IgnoreHostsThreshold,
-					InstanceProbes:       mysql.NewProbes(),
-				}
-				for _, tablet := range tablets {
-					key := mysql.InstanceKey{Hostname: tablet.MysqlHostname, Port: int(tablet.MysqlPort)}
-					addInstanceKey(&key, clusterName, clusterSettings, clusterProbes.InstanceProbes)
-				}
-				throttler.mysqlClusterProbesChan <- clusterProbes
-				return nil
-			}
-
-			if !clusterSettings.StaticHostsSettings.IsEmpty() {
-				clusterProbes := &mysql.ClusterProbes{
-					ClusterName:    clusterName,
-					InstanceProbes: mysql.NewProbes(),
-				}
-				for _, host := range clusterSettings.StaticHostsSettings.Hosts {
-					key, err := mysql.ParseInstanceKey(host, clusterSettings.Port)
-					if err != nil {
-						return log.Errore(err)
-					}
-					addInstanceKey(key, clusterName, clusterSettings, clusterProbes.InstanceProbes)
-				}
-				throttler.mysqlClusterProbesChan <- clusterProbes
-				return nil
+			memcacheKey := fmt.Sprintf("%s/%s", throttler.memcachePath, metricName)
+			value, err := aggregatedMetric.Get()
+			if err != nil {
+				throttler.memcacheClient.Delete(memcacheKey)
+			} else {
+				epochMillis := time.Now().UnixNano() / 1000000
+				entryVal := fmt.Sprintf("%d:%.6f", epochMillis, value)
+				throttler.memcacheClient.Set(&memcache.Item{Key: memcacheKey, Value: []byte(entryVal), Expiration: 1})
 			}
-			return log.Errorf("Could not find any hosts definition for cluster %s", clusterName)
 		}()
 	}
-	return nil
-}
-
-// synchronous update of inventory
-func (throttler *Throttler) updateMySQLClusterProbes(clusterProbes *mysql.ClusterProbes) error {
-	log.Debugf("updating MySQLClusterProbes: %s", clusterProbes.ClusterName)
-	throttler.mysqlInventory.ClustersProbes[clusterProbes.ClusterName] = clusterProbes.InstanceProbes
-	throttler.mysqlInventory.IgnoreHostsCount[clusterProbes.ClusterName] = clusterProbes.IgnoreHostsCount
-	throttler.mysqlInventory.IgnoreHostsThreshold[clusterProbes.ClusterName] = clusterProbes.IgnoreHostsThreshold
-	return nil
-}
-
-// synchronous aggregation of collected data
-func (throttler *Throttler) aggregateMySQLMetrics() error {
-	if !throttler.isLeader {
-		return nil
-	}
-	for clusterName, probes := range throttler.mysqlInventory.ClustersProbes {
-		metricName := fmt.Sprintf("mysql/%s", clusterName)
-		ignoreHostsCount := throttler.mysqlInventory.IgnoreHostsCount[clusterName]
-		ignoreHostsThreshold := throttler.mysqlInventory.IgnoreHostsThreshold[clusterName]
-		aggregatedMetric := aggregateMySQLProbes(probes, clusterName, throttler.mysqlInventory.InstanceKeyMetrics, throttler.mysqlInventory.ClusterInstanceHttpChecks, ignoreHostsCount, config.Settings().Stores.MySQL.IgnoreDialTcpErrors, ignoreHostsThreshold)
-		go throttler.aggregatedMetrics.Set(metricName, aggregatedMetric, cache.DefaultExpiration)
-		if throttler.memcacheClient != nil {
-			go func() {
-				memcacheKey := fmt.Sprintf("%s/%s", throttler.memcachePath, metricName)
-				value, err := aggregatedMetric.Get()
-				if err != nil {
-					throttler.memcacheClient.Delete(memcacheKey)
-				} else {
-					epochMillis := time.Now().UnixNano() / 1000000
-					entryVal := fmt.Sprintf("%d:%.6f", epochMillis, value)
-					throttler.memcacheClient.Set(&memcache.Item{Key: memcacheKey, Value: []byte(entryVal), Expiration: 1})
-				}
-			}()
-		}
-	}
-	return nil
 }
 
 func (throttler *Throttler) pushStatusToExpVar() {
@@ -479,16 +215,6 @@ func (throttler *Throttler) getNamedMetric(metricName string) base.MetricResult
 	return base.NoSuchMetric
 }
 
-func (throttler *Throttler) getMySQLClusterMetrics(clusterName string) (base.MetricResult, float64) {
-	if thresholdVal, found := throttler.mysqlClusterThresholds.Get(clusterName); found {
-		threshold, _ := thresholdVal.(float64)
-		metricName := fmt.Sprintf("mysql/%s", clusterName)
-		return throttler.getNamedMetric(metricName), threshold
-	}
-
-	return base.NoSuchMetric, 0
-}
-
 func (throttler *Throttler) aggregatedMetricsSnapshot() map[string]base.MetricResult {
 	snapshot := make(map[string]base.MetricResult)
 	for key, value := range throttler.aggregatedMetrics.Items() {
@@ -546,6 +272,13 @@ func (throttler *Throttler) UnthrottleApp(appName string) {
 }
 
 func (throttler *Throttler) IsAppThrottled(appName, storeName string) bool {
+	_, throttled := throttler.appThrottleDecisions(appName, storeName)
+	return throttled
+}
+
+// appThrottleDecisions evaluates the store-scoped and global throttled-apps entries for the given app.
+// It returns the evaluated entries, and whether any of them throttles the app.
+func (throttler *Throttler) appThrottleDecisions(appName, storeName string) (decisions [](*AppThrottleDecision), throttled bool) {
 	appWithStore := fmt.Sprintf("%s/%s", appName, storeName)
 	keys := []string{appWithStore, appName}
 	// check if app is throttled for this store or globally
@@ -556,13 +289,24 @@ func (throttler *Throttler) IsAppThrottled(appName, storeName string) bool {
 				// throttling cleanup hasn't purged yet, but it is expired
 				continue
 			}
+			decision := &AppThrottleDecision{
+				Key:   key,
+				Scope: "global",
+				Ratio: appThrottle.Ratio,
+				Draw:  rand.Float64(),
+			}
+			if key == appWithStore {
+				decision.Scope = "store"
+			}
+			decisions = append(decisions, decision)
 			// handle ratio
-			if rand.Float64() < appThrottle.Ratio {
-				return true
+			if decision.Draw < appThrottle.Ratio {
+				decision.Throttled = true
+				return decisions, true
 			}
 		}
 	}
-	return false
+	return decisions, false
 }
 
 func (throttler *Throttler) ThrottledAppsMap() (result map[string](*base.AppThrottle)) {
@@ -652,10 +396,20 @@ func (throttler *Throttler) metricsHealthSnapshot() base.MetricHealthMap {
 }
 
 func (throttler *Throttler) AppRequestMetricResult(appName string, storeName string, metricResultFunc base.MetricResultFunc, denyApp bool) (metricResult base.MetricResult, threshold float64) {
+	return throttler.explainedAppRequestMetricResult(appName, storeName, metricResultFunc, denyApp, nil)
+}
+
+// explainedAppRequestMetricResult is AppRequestMetricResult, which also records its decisions onto given
+// explanation, if non-nil
+func (throttler *Throttler) explainedAppRequestMetricResult(appName string, storeName string, metricResultFunc base.MetricResultFunc, denyApp bool, explanation *CheckExplanation) (metricResult base.MetricResult, threshold float64) {
 	if denyApp {
 		return base.AppDeniedMetric, 0
 	}
-	if throttler.IsAppThrottled(appName, storeName) {
+	decisions, throttled := throttler.appThrottleDecisions(appName, storeName)
+	if explanation != nil {
+		explanation.AppThrottles = append(explanation.AppThrottles, decisions...)
+	}
+	if throttled {
 		return base.AppDeniedMetric, 0
 	}
 	return metricResultFunc()
@@ -673,7 +427,8 @@ func (throttler *Throttler) collectShareDomainMetricHealth() error {
 		return nil
 	}
 	aggregatedMetricHealth := make(base.MetricHealthMap)
-	for _, service := range services {
+	for domain, service := range services {
+		domain := domain
 		err := func() error {
 			uri := fmt.Sprintf("http://%s/metrics-health", service)
 
@@ -692,6 +447,9 @@ func (throttler *Throttler) collectShareDomainMetricHealth() error {
 				return err
 			}
 			log.Debugf("share domain url: %+v", uri)
+			for _, metricHealth := range m {
+				metricHealth.SourceDomain = domain
+			}
 			aggregatedMetricHealth.Aggregate(m)
 			return nil
 		}()
@@ -704,9 +462,17 @@ func (throttler *Throttler) collectShareDomainMetricHealth() error {
 }
 
 func (throttler *Throttler) getShareDomainSecondsSinceHealth(metricName string) int64 {
-	if object, found := throttler.shareDomainMetricHealth.Get(metricName); found {
-		metricHealth := object.(*base.MetricHealth)
+	if metricHealth := throttler.getShareDomainMetricHealth(metricName); metricHealth != nil {
 		return metricHealth.SecondsSinceLastHealthy
 	}
 	return 0
 }
+
+// getShareDomainMetricHealth returns the worst known health of given metric across shared domain services,
+// or nil if unknown
+func (throttler *Throttler) getShareDomainMetricHealth(metricName string) *base.MetricHealth {
+	if object, found := throttler.shareDomainMetricHealth.Get(metricName); found {
+		return object.(*base.MetricHealth)
+	}
+	return nil
+}